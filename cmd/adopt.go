@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var adoptRepo, adoptVersion, adoptRegex, adoptScan, adoptFormat string
+var adoptArgs []string
+var adoptDryRun bool
+
+// adopt sub command
+var adoptCmd = &cobra.Command{
+	Use:   "adopt [path]",
+	Short: "bring a binary installed by hand under binman management",
+	Args:  cobra.MaximumNArgs(1),
+	Example: `binman adopt /usr/local/bin/kubectl --repo kubernetes/kubectl
+binman adopt ~/bin/jq --repo jqlang/jq --version-regex 'jq-([0-9.]+)'
+binman adopt --scan ~/bin`,
+	Long: `Run a binary to find its version, match the version to an upstream tag and store the binary as that version of the release.
+The version is recorded in the db and the binary is replaced with a link, so later syncs update it. Releases missing from the config are added.
+With --scan every executable in a directory is matched to a configured release by name and the matching tag is reported. Nothing is changed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if adoptFormat != "table" && adoptFormat != "json" {
+			fmt.Printf("Error: Unknown format %s. Use table or json\n", adoptFormat)
+			os.Exit(1)
+		}
+
+		if (adoptScan == "") == (len(args) == 0) {
+			fmt.Println("Error: supply either a path or --scan <dir>")
+			os.Exit(1)
+		}
+
+		if adoptScan == "" {
+			validateRepo(adoptRepo)
+		}
+
+		log.ConfigureLog(jsonLog, debug)
+
+		o := binman.AdoptOptions{
+			Repo:         adoptRepo,
+			Version:      adoptVersion,
+			VersionArgs:  adoptArgs,
+			VersionRegex: adoptRegex,
+			DryRun:       adoptDryRun,
+		}
+
+		var results []binman.AdoptResult
+
+		if adoptScan != "" {
+			var err error
+			if results, err = binman.AdoptScan(adoptScan, o, config); err != nil {
+				log.Fatalf("Failed to scan %s - %s", adoptScan, err)
+			}
+		} else {
+			res, err := binman.Adopt(args[0], o, "", config)
+			if err != nil {
+				log.Fatalf("Failed to adopt %s - %s", args[0], err)
+			}
+			results = append(results, *res)
+		}
+
+		switch adoptFormat {
+		case "json":
+			b, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				log.Fatalf("Unable to marshal results - %s", err)
+			}
+			fmt.Println(string(b))
+		default:
+			binman.OutputAdoptResults(results)
+		}
+	},
+}
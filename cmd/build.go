@@ -30,7 +30,7 @@ var buildOciCmd = &cobra.Command{
 
 		log.Infof("Building OCI Image and publishing to %s", targetImageName)
 
-		if err = binman.BuildOciImage(config, repo, targetImageName, baseImage, imagePath); err != nil {
+		if err = binman.BuildOciImage(config, repo, targetImageName, baseImage, imagePath, selector()); err != nil {
 			log.Fatalf("Failed to build image %s", err)
 		}
 
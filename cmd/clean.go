@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
@@ -10,24 +11,44 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var cleanDryRun, scan bool
+var cleanDryRun, scan, cleanOrphans bool
 var threshold int
+var cleanFormat string
 
 // Config sub command
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "clean old versions previously synced by binman",
-	Long:  `clean old versions previously synced by binman`,
+	Long: `clean old versions previously synced by binman.
+Versions are kept according to the retention set in the config, --threshold is used for releases with no keep configured.
+Links in binpath to versions that are gone are removed too. Version directories missing from the db are removed with --orphans or --scan.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if threshold == 0 {
 			fmt.Println("Error: Please use a non-zero value for threshold")
 			os.Exit(1)
 		}
 
+		if cleanFormat != "table" && cleanFormat != "json" {
+			fmt.Printf("Error: Unknown format %s. Use table or json\n", cleanFormat)
+			os.Exit(1)
+		}
+
 		// Set the logging options
 		log.ConfigureLog(jsonLog, debug)
 
-		err := binman.Clean(cleanDryRun, scan, threshold, "", config)
+		report, err := binman.Clean(cleanDryRun, scan, cleanOrphans, threshold, "", config, selector())
+
+		switch cleanFormat {
+		case "json":
+			b, jsonErr := json.MarshalIndent(report, "", "  ")
+			if jsonErr != nil {
+				log.Fatalf("Unable to marshal clean report - %s", jsonErr)
+			}
+			fmt.Println(string(b))
+		default:
+			binman.OutputCleanReport(report)
+		}
+
 		if err != nil {
 			log.Fatalf("Failed to run clean %s", err)
 		}
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 
 	binman "github.com/rjbrown57/binman/pkg"
 	"github.com/spf13/cobra"
@@ -55,6 +57,59 @@ var configAddCmd = &cobra.Command{
 	},
 }
 
+var importFrom, importFormat string
+var importMap map[string]string
+var importDryRun bool
+
+// Config import sub command
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Add the tools of an aqua, eget, asdf or mise manifest to your binman config",
+	Args:  cobra.ExactArgs(1),
+	Example: `binman config import --from aqua aqua.yaml
+binman config import --from asdf .tool-versions --map nodejs=nodejs/node --dry-run
+binman config import --from mise mise.toml --format yaml --dry-run`,
+	Long: `Translate the tools of a manifest written for another installer into binman releases and add them to your binman config.
+Package and plugin names are mapped to repos, pinned versions, asset patterns and link names are carried over and anything that could not be translated is reported.
+Comments in the config are preserved.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !slices.Contains(binman.ImportFormats, importFrom) {
+			fmt.Printf("Error: Unknown format %s. Use one of %v\n", importFrom, binman.ImportFormats)
+			os.Exit(1)
+		}
+
+		if importFormat != "table" && importFormat != "json" && importFormat != "yaml" {
+			fmt.Printf("Error: Unknown output format %s. Use table, json or yaml\n", importFormat)
+			os.Exit(1)
+		}
+
+		log.ConfigureLog(jsonLog, debug)
+
+		rep, err := binman.Import(importFrom, args[0], binman.ImportOptions{Map: importMap, DryRun: importDryRun}, config)
+		if err != nil {
+			log.Fatalf("Failed to import %s - %s", args[0], err)
+		}
+
+		switch importFormat {
+		case "json":
+			b, err := json.MarshalIndent(rep, "", "  ")
+			if err != nil {
+				log.Fatalf("Unable to marshal import report - %s", err)
+			}
+			fmt.Println(string(b))
+		case "yaml":
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(map[string][]binman.BinmanRelease{"releases": rep.Releases()}); err != nil {
+				log.Fatalf("Unable to marshal releases - %s", err)
+			}
+			enc.Close()
+		default:
+			binman.OutputImportReport(rep)
+		}
+	},
+}
+
 // Config get sub command
 var configGetCmd = &cobra.Command{
 	Use:   "get",
@@ -106,12 +161,12 @@ func Add(c *binman.BMConfig, repos []string) error {
 
 		switch r.SourceIdentifier {
 		case "github.com":
-			r.Version, err = gh.CheckRepo(gh.GetGHCLient(constants.DefaultGHBaseURL, c.Config.SourceMap["github.com"].Tokenvar), r.Repo)
+			r.Version, err = gh.CheckRepo(gh.GetGHClientWithToken(constants.DefaultGHBaseURL, c.Config.SourceMap["github.com"].Token()), r.Repo)
 			if err != nil {
 				return err
 			}
 		case "gitlab.com":
-			r.Version, err = gl.GLGetLatestTag(gl.GetGLClient(constants.DefaultGLBaseURL, c.Config.SourceMap["gitlab.com"].Tokenvar), r.Repo)
+			r.Version, err = gl.GLGetLatestTag(gl.GetGLClientWithToken(constants.DefaultGLBaseURL, c.Config.SourceMap["gitlab.com"].Token(), c.Config.SourceMap["gitlab.com"].Tokenvar == "CI_JOB_TOKEN"), r.Repo)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var doctorFix bool
+
+// doctor sub command
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Short:   "check that links, the db and synced versions agree",
+	Example: "binman doctor --fix",
+	Long: `Check for broken links, db versions missing from disk, versions on disk missing from the db, unfinished version directories and a binpath missing from $PATH.
+With --fix broken links are pointed at the newest valid version, orphaned versions are pruned and the db is rebuilt from disk.
+doctor exits with status 1 if any error is left unfixed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.ConfigureLog(jsonLog, debug)
+
+		findings, err := binman.Doctor(doctorFix, "", config, selector())
+
+		binman.OutputFindings(findings)
+
+		if err != nil {
+			log.Fatalf("Doctor failed %s", err)
+		}
+
+		for _, f := range findings {
+			if f.Severity == binman.DoctorError && !f.Fixed {
+				os.Exit(1)
+			}
+		}
+	},
+}
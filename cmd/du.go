@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var duSort, duFormat, duLevel string
+var duThreshold int
+var duOrphans bool
+
+// du sub command
+var duCmd = &cobra.Command{
+	Use:     "du",
+	Short:   "show the disk space used by synced releases",
+	Example: "binman du --level version --sort name",
+	Long: `Show the disk space used by the release store per source, org, repo and version.
+Linked versions, archives kept because cleanup is off and the space binman clean would free are reported.
+Reclaimable space uses the configured retention, with --threshold for releases that have no keep set.
+Version directories missing from the db are only counted with --orphans, matching binman clean --orphans.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if duFormat != "table" && duFormat != "json" {
+			fmt.Printf("Error: Unknown format %s. Use table or json\n", duFormat)
+			os.Exit(1)
+		}
+
+		levels := []string{binman.DuLevelSource, binman.DuLevelOrg, binman.DuLevelRepo, binman.DuLevelVersion}
+		if !slices.Contains(levels, duLevel) {
+			fmt.Printf("Error: Unknown level %s. Use one of %v\n", duLevel, levels)
+			os.Exit(1)
+		}
+
+		log.ConfigureLog(jsonLog, debug)
+
+		report, err := binman.DiskUsage(duThreshold, duOrphans, duSort, "", config, selector())
+		if err != nil {
+			log.Fatalf("Failed to get disk usage %s", err)
+		}
+
+		switch duFormat {
+		case "json":
+			b, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				log.Fatalf("Unable to marshal disk usage - %s", err)
+			}
+			fmt.Println(string(b))
+		default:
+			binman.OutputDiskUsage(report, duLevel)
+		}
+	},
+}
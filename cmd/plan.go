@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rjbrown57/binman/internal"
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var planOut, planFormat string
+
+// plan sub command
+var planCmd = &cobra.Command{
+	Use:     "plan",
+	Short:   "show what a sync would change without downloading anything",
+	Example: "binman plan --out binman.plan",
+	Long: `Resolve the upstream version and asset of each release and report what a sync would do.
+The plan can be written to a file with --out and executed later with binman apply.
+plan exits with status 3 when a release could not be resolved and 2 when updates are pending.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.ConfigureLog(jsonLog, debug)
+
+		if planFormat != "table" && planFormat != "json" {
+			log.Fatalf("Unknown format %s. Use table or json", planFormat)
+		}
+
+		bm := binman.NewBMPlan(config).Select(selector())
+		bm.CollectData()
+		bm.BMClose()
+
+		p := bm.Plan()
+
+		if planOut != "" {
+			if err := p.Write(planOut); err != nil {
+				log.Fatalf("Unable to write plan to %s - %s", planOut, err)
+			}
+		}
+
+		switch planFormat {
+		case "json":
+			b, err := json.MarshalIndent(p, "", "  ")
+			if err != nil {
+				log.Fatalf("Unable to marshal plan - %s", err)
+			}
+			fmt.Println(string(b))
+		default:
+			internal.OutputPlan(p)
+		}
+
+		switch {
+		case p.Errors() > 0:
+			os.Exit(3)
+		case p.Pending() > 0:
+			os.Exit(2)
+		}
+	},
+}
+
+// apply sub command
+var applyCmd = &cobra.Command{
+	Use:     "apply <planfile>",
+	Short:   "sync exactly the changes in a plan",
+	Args:    cobra.ExactArgs(1),
+	Example: "binman apply binman.plan",
+	Long: `Sync the releases with updates pending in a plan written by binman plan.
+The planned version and asset are used, the sources are not queried again.
+The config the plan was made with is used unless -c is set.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.ConfigureLog(jsonLog, debug)
+
+		p, err := binman.ReadPlan(args[0])
+		if err != nil {
+			log.Fatalf("Unable to read plan %s - %s", args[0], err)
+		}
+
+		cfg := config
+		if cfg == "" {
+			cfg = p.Config
+		}
+
+		bm := binman.NewBMSync(cfg, table)
+		if err := bm.ApplyPlan(p); err != nil {
+			bm.BMClose()
+			log.Fatalf("Unable to apply plan %s - %s", args[0], err)
+		}
+
+		if err := internal.Main(bm); err != nil {
+			log.Fatalf("Binman apply failed %s", err)
+		}
+	},
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var removeKeepFiles, removeDryRun bool
+
+// remove sub command
+var removeCmd = &cobra.Command{
+	Use:     "remove <repo>",
+	Short:   "remove a release from binman",
+	Args:    cobra.ExactArgs(1),
+	Example: "binman remove rjbrown57/binman",
+	Long: `Remove a release from the binman config, delete its stored versions and links from disk, and delete its versions from the db.
+Comments in the config are preserved.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateRepo(args[0])
+		log.ConfigureLog(jsonLog, debug)
+
+		if err := binman.Remove(args[0], removeKeepFiles, removeDryRun, "", config); err != nil {
+			log.Fatalf("Failed to remove %s - %s", args[0], err)
+		}
+	},
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	binman "github.com/rjbrown57/binman/pkg"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+var rollbackRelease bool
+
+// rollback sub command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <repo> [version]",
+	Short: "switch a release back to a previously synced version",
+	Args:  cobra.RangeArgs(1, 2),
+	Example: `binman rollback rjbrown57/binman
+binman rollback rjbrown57/binman v0.10.0
+binman rollback --release rjbrown57/binman`,
+	Long: `Link a release to the version synced before the active one, or to a specific stored version.
+Syncs will leave the release at that version until it is released with --release, which links the newest stored version again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		validateRepo(args[0])
+		log.ConfigureLog(jsonLog, debug)
+
+		var rollbackVersion string
+		if len(args) == 2 {
+			rollbackVersion = args[1]
+		}
+
+		if rollbackRelease && rollbackVersion != "" {
+			log.Fatalf("A version can not be set with --release")
+		}
+
+		if err := binman.Rollback(args[0], rollbackVersion, rollbackRelease, "", config); err != nil {
+			log.Fatalf("Failed to roll back %s - %s", args[0], err)
+		}
+	},
+}
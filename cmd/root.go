@@ -14,6 +14,7 @@ import (
 var imagePath, baseImage, config, path, repo, targetImageName, version string
 var jsonLog, table bool
 var debug int
+var only, skip, labels []string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -24,7 +25,7 @@ var rootCmd = &cobra.Command{
 
 		// Set the logging options
 		log.ConfigureLog(jsonLog, debug)
-		if err := internal.Main(binman.NewBMSync(config, table)); err != nil {
+		if err := internal.Main(binman.NewBMSync(config, table).Select(selector())); err != nil {
 			log.Fatalf("Binman run failed %s", err)
 		}
 	},
@@ -46,12 +47,31 @@ func validateRepo(repo string) {
 	}
 }
 
+// selector returns the releases requested with --only, --skip and --label
+func selector() binman.ReleaseSelector {
+	return binman.ReleaseSelector{Only: only, Skip: skip, Labels: labels}
+}
+
+// addSelectorFlags adds the release selection flags to cmd
+func addSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&only, "only", nil, "only act on these releases. E.G rjbrown57/binman,gitlab.com/gitlab-org/cli")
+	cmd.Flags().StringSliceVar(&skip, "skip", nil, "skip these releases")
+	cmd.Flags().StringSliceVar(&labels, "label", nil, "only act on releases with one of these labels")
+}
+
 func addSubcommands() {
 	// add edit/get to config
 	configCmd.AddCommand(configEditCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configAddCmd)
 
+	configImportCmd.Flags().StringVar(&importFrom, "from", "", "format of the manifest. aqua, eget, asdf or mise")
+	configImportCmd.Flags().StringToStringVar(&importMap, "map", nil, "map a plugin or package name to a repo. e.g nodejs=nodejs/node")
+	configImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "show what would be imported without changing the config")
+	configImportCmd.Flags().StringVar(&importFormat, "format", "table", "output format. table, json or yaml. yaml prints the releases that are added")
+	configImportCmd.MarkFlagRequired("from")
+	configCmd.AddCommand(configImportCmd)
+
 	// add config to root
 	rootCmd.AddCommand(configCmd)
 
@@ -59,20 +79,65 @@ func addSubcommands() {
 	rootCmd.AddCommand(watchCmd)
 
 	// add status to root
+	addSelectorFlags(statusCmd)
 	rootCmd.AddCommand(statusCmd)
 
 	// add clean to root
 	cleanCmd.Flags().BoolVarP(&cleanDryRun, "dryrun", "r", false, "enable dry run for clean")
 	cleanCmd.Flags().IntVarP(&threshold, "threshold", "n", 3, "Non-zero amount of releases to retain")
 	cleanCmd.Flags().BoolVarP(&scan, "scan", "s", false, "force update of DB pre clean")
+	cleanCmd.Flags().BoolVar(&cleanOrphans, "orphans", false, "remove version directories missing from the db")
+	cleanCmd.Flags().StringVar(&cleanFormat, "format", "table", "report format. table or json")
+	addSelectorFlags(cleanCmd)
 	rootCmd.AddCommand(cleanCmd)
 
+	// add du to root
+	duCmd.Flags().StringVar(&duSort, "sort", binman.DuSortSize, "sort by size or name")
+	duCmd.Flags().StringVar(&duFormat, "format", "table", "output format. table or json")
+	duCmd.Flags().StringVar(&duLevel, "level", binman.DuLevelRepo, "level to report at. source, org, repo or version")
+	duCmd.Flags().IntVarP(&duThreshold, "threshold", "n", 3, "versions clean would retain for releases with no keep configured")
+	duCmd.Flags().BoolVar(&duOrphans, "orphans", false, "count version directories missing from the db as reclaimable")
+	addSelectorFlags(duCmd)
+	rootCmd.AddCommand(duCmd)
+
+	// add rollback to root
+	rollbackCmd.Flags().BoolVar(&rollbackRelease, "release", false, "release a rolled back repo so syncs update it again")
+	rootCmd.AddCommand(rollbackCmd)
+
+	// add remove to root
+	removeCmd.Flags().BoolVar(&removeKeepFiles, "keep-files", false, "leave stored versions and links on disk")
+	removeCmd.Flags().BoolVar(&removeDryRun, "dry-run", false, "show what would be removed without changing anything")
+	rootCmd.AddCommand(removeCmd)
+
+	// add doctor to root
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "repair the problems found")
+	addSelectorFlags(doctorCmd)
+	rootCmd.AddCommand(doctorCmd)
+
+	// add adopt to root
+	adoptCmd.Flags().StringVar(&adoptRepo, "repo", "", "release the binary belongs to. e.g org/project")
+	adoptCmd.Flags().StringVar(&adoptScan, "scan", "", "suggest a release and version for every executable in a directory")
+	adoptCmd.Flags().StringVar(&adoptVersion, "version", "", "version of the binary. Skips running it")
+	adoptCmd.Flags().StringSliceVar(&adoptArgs, "version-args", binman.DefaultVersionArgs, "arguments tried in order to make the binary print its version")
+	adoptCmd.Flags().StringVar(&adoptRegex, "version-regex", binman.DefaultAdoptRegex, "regex the version is extracted with. The first capture group is used if present")
+	adoptCmd.Flags().BoolVar(&adoptDryRun, "dry-run", false, "show what would be adopted without changing anything")
+	adoptCmd.Flags().StringVar(&adoptFormat, "format", "table", "output format. table or json")
+	rootCmd.AddCommand(adoptCmd)
+
+	// add plan/apply to root
+	planCmd.Flags().StringVarP(&planOut, "out", "o", "", "write the plan to a file for binman apply")
+	planCmd.Flags().StringVar(&planFormat, "format", "table", "output format. table or json")
+	addSelectorFlags(planCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+
 	// add build to root
 	buildOciCmd.Flags().StringVar(&baseImage, "base", "alpine:latest", "Base image to append synced binaries to")
 	buildOciCmd.Flags().StringVar(&repo, "repo", "", "a specific repo to build OCI image for. E.G rjbrown57/binman:v0.10.1. The version string is optional and if omitted the latest version will be used. Leave empty to build a toolbox image of all synced releases")
 	buildOciCmd.Flags().StringVar(&targetImageName, "publishPath", "", "target to publish OCI image to. Should be a valid docker image name. If version is left empty it will be generated")
 	buildOciCmd.Flags().StringVar(&imagePath, "imageBinPath", "/usr/local/bin/", "Where binaries should be located within the image")
 	buildOciCmd.MarkFlagRequired("publishPath")
+	addSelectorFlags(buildOciCmd)
 	buildCmd.AddCommand(buildOciCmd)
 
 	rootCmd.AddCommand(buildCmd)
@@ -93,6 +158,7 @@ func addSubcommands() {
 func init() {
 
 	addSubcommands()
+	addSelectorFlags(rootCmd)
 
 	rootCmd.PersistentFlags().StringVarP(&config, "config", "c", "", "path to config file. Can be set with ${BINMAN_CONFIG} env var")
 	rootCmd.PersistentFlags().CountVarP(&debug, "debug", "d", "enable debug logging. Set multiple times to increase log level")
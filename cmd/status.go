@@ -15,6 +15,8 @@ var statusCmd = &cobra.Command{
 
 		// Set the logging options
 		log.ConfigureLog(jsonLog, debug)
-		binman.OutputDbStatus()
+		if err := binman.OutputDbStatus(selector(), config); err != nil {
+			log.Fatalf("Failed to get status %s", err)
+		}
 	},
 }
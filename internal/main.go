@@ -67,6 +67,9 @@ func Main(bm *BMConfig) error {
 			continue
 		case *ExcludeError:
 			continue
+		case *DeferredError:
+			output["Deferred"] = append(output["Deferred"], msg)
+			continue
 		default:
 			// Todo create an error here and use errors.Is
 			output["Error"] = append(output["Error"], msg)
@@ -106,6 +109,8 @@ func StartWatch(bm *BMConfig) {
 	go func() {
 		for {
 
+			// Msgs from the previous iteration have been processed, releases deferred then are retried now
+			bm.Msgs = nil
 			bm.CollectData()
 
 			// Process results
@@ -117,12 +122,14 @@ func StartWatch(bm *BMConfig) {
 					continue
 				}
 
-				if msg.Err.Error() == "Noupdate" {
+				switch msg.Err.(type) {
+				case *NoUpdateError:
 					log.Infof("%s - %s is up to date", msg.Rel.Repo, msg.Rel.Version)
-					continue
+				case *DeferredError:
+					log.Infof("%s. Deferring to next iteration", msg.Err)
+				default:
+					log.Infof("Issue syncing %s - %s", msg.Rel.Repo, msg.Err)
 				}
-
-				log.Infof("Issue syncing %s - %s", msg.Rel.Repo, msg.Err)
 			}
 
 			log.Infof("Binman watch iteration complete")
@@ -147,3 +154,33 @@ func StartWatch(bm *BMConfig) {
 	sig := <-sigs
 	log.Infof("Terimnating binman on %s", sig)
 }
+
+// OutputPlan prints the planned changes of each release
+func OutputPlan(p *Plan) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	planTable := table.New("Repo", "Installed", "Version", "Status", "Asset")
+	planTable.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, e := range p.Releases {
+		planTable.AddRow(e.Repo, e.Installed, e.Version, e.Status, e.Asset)
+	}
+
+	planTable.Print()
+
+	for _, e := range p.Releases {
+		switch e.Status {
+		case PlanUpdate:
+			fmt.Printf("\n%s %s -> %s\n  url: %s\n", e.Repo, e.Installed, e.Version, e.URL)
+			for _, a := range e.Actions {
+				fmt.Printf("  - %s\n", a)
+			}
+		case PlanError, PlanDeferred:
+			fmt.Printf("\n%s : %s\n", e.Repo, e.Error)
+		}
+	}
+
+	fmt.Printf("\n%d of %d releases have updates pending\n", p.Pending(), len(p.Releases))
+}
@@ -7,8 +7,6 @@ import (
 	"sync"
 
 	db "github.com/rjbrown57/binman/pkg/db"
-	"github.com/rjbrown57/binman/pkg/gh"
-	"github.com/rjbrown57/binman/pkg/gl"
 	log "github.com/rjbrown57/binman/pkg/logging"
 )
 
@@ -42,6 +40,9 @@ func (r *BinmanRelease) runActions() error {
 			case *NoUpdateError:
 				log.Debugf("%s(%s) is up to date", r.Repo, r.Version)
 				return err
+			case *DeferredError:
+				log.Debugf("%s", err)
+				return err
 			default:
 				log.Debugf("Unable to complete action %s : %v", reflect.TypeOf(task), err)
 				return err
@@ -59,21 +60,25 @@ func (r *BinmanRelease) setPreActions(releasePath string, binPath string) []Acti
 
 	actions = append(actions, r.AddReleaseExcludeAction())
 
-	switch r.source.Apitype {
-	case "gitlab":
-		glClient := gl.GetGLClient(r.source.URL, r.source.Tokenvar)
-		actions = append(actions, r.AddGetGLReleaseAction(glClient))
-	case "github":
-		ghClient := gh.GetGHCLient(r.source.URL, r.source.Tokenvar)
-		// TODO checking limits over and over is not optimal
-		gh.ShowLimits(ghClient)
-		if err := gh.CheckLimits(ghClient); err != nil {
-			log.Fatalf("Unable to check limits against GH api")
-		}
+	// Rolled back releases are held until released, so the source is not queried
+	if r.dbChan != nil && r.dwg != nil {
+		actions = append(actions, r.AddRollbackCheckAction())
+	}
 
-		actions = append(actions, r.AddGetGHReleaseAction(ghClient))
-	case "binman":
-		actions = append(actions, r.AddGetBinmanReleaseAction())
+	switch {
+	// Applied plans already know the version and asset
+	case r.applyEntry != nil:
+		actions = append(actions, r.AddApplyPlanAction())
+	// Releases with a versionurl are independent of the source api
+	case r.VersionUrl != "":
+		actions = append(actions, r.AddGetVersionUrlAction())
+	// Releases with several sources try each in order until one answers
+	case len(r.fallbacks) > 1:
+		actions = append(actions, r.AddGetFallbackReleaseAction())
+	default:
+		if action := r.getReleaseAction(); action != nil {
+			actions = append(actions, action)
+		}
 	}
 
 	// If we have a nil DbChan + downloadChan then we will only populate
@@ -96,10 +101,19 @@ func (r *BinmanRelease) setPreActions(releasePath string, binPath string) []Acti
 
 	// If PostOnly is true, we don't need to select an asset
 	if !r.PostOnly {
-		actions = append(actions,
-			// The SetUrlAction finds the approriate asset to download
-			r.AddSetUrlAction(),
-		)
+		// The SetUrlAction finds the approriate asset to download
+		if r.applyEntry == nil {
+			actions = append(actions, r.AddSetUrlAction())
+		}
+
+		if len(r.rewrites) > 0 {
+			actions = append(actions, r.AddRewriteUrlAction())
+		}
+	}
+
+	// Plans stop once we know what would be downloaded
+	if r.planning {
+		return append(actions, r.AddPlanAction())
 	}
 
 	// Add remaining preDownload actions
@@ -114,6 +128,31 @@ func (r *BinmanRelease) setPreActions(releasePath string, binPath string) []Acti
 
 }
 
+// getReleaseAction returns the action querying the current source of the release
+func (r *BinmanRelease) getReleaseAction() Action {
+
+	switch r.source.Apitype {
+	case "gitlab":
+		return r.AddGetGLReleaseAction(r.source.glClient(r.apiCache()))
+	case "github":
+		// Clients are shared per source, rate limits are tracked from response headers by the client
+		// and responses are cached in the db so unchanged releases are answered with a 304
+		return r.AddGetGHReleaseAction(r.source.ghClient(r.apiCache()))
+	case "gitea":
+		return r.AddGetGiteaReleaseAction(r.source.giteaClient(r.apiCache()))
+	case "http-index":
+		return r.AddGetHTTPIndexReleaseAction(r.source.indexClient(r.apiCache()))
+	case "oci":
+		return r.AddGetOCIReleaseAction(r.source.ociPuller(r.platform()))
+	case "file":
+		return r.AddGetFileReleaseAction()
+	case "binman":
+		return r.AddGetBinmanReleaseAction()
+	}
+
+	return nil
+}
+
 type SetPostActions struct {
 	r *BinmanRelease
 }
@@ -135,7 +174,12 @@ func (r *BinmanRelease) setPostActions() []Action {
 	var actions []Action
 
 	if !r.PostOnly {
-		actions = append(actions, r.AddDownloadAction())
+		// oci releases are pulled from the registry rather than downloaded by url
+		if r.source != nil && r.source.Apitype == "oci" {
+			actions = append(actions, r.AddOCIPullAction(r.source.ociPuller(r.platform())))
+		} else {
+			actions = append(actions, r.AddDownloadAction())
+		}
 
 		// If we are set to download only stop all postCommands
 		if r.DownloadOnly {
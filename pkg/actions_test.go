@@ -1,13 +1,18 @@
 package binman
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/v50/github"
 	"github.com/rjbrown57/binman/pkg/constants"
 	binmandb "github.com/rjbrown57/binman/pkg/db"
 	"github.com/rjbrown57/binman/pkg/downloader"
+	"github.com/rjbrown57/binman/pkg/ratelimit"
 )
 
 func TestRunActions(t *testing.T) {
@@ -77,6 +82,16 @@ func TestSetPreActions(t *testing.T) {
 		downloadChan: dlChan,
 	}
 
+	relVersionUrl := BinmanRelease{
+		Repo:         "kubernetes/kubernetes",
+		QueryType:    "release",
+		ExternalUrl:  "https://dl.k8s.io/release/{{.version}}/bin/{{.os}}/{{.arch}}/kubectl",
+		VersionUrl:   "https://dl.k8s.io/release/stable.txt",
+		source:       &githubSource,
+		dbChan:       dbChan,
+		downloadChan: dlChan,
+	}
+
 	relGLBasic := BinmanRelease{
 		Repo:         "rjbrown57/binman",
 		QueryType:    "release",
@@ -116,6 +131,11 @@ func TestSetPreActions(t *testing.T) {
 			relExternalUrl.setPreActions("/tmp/", "/tmp/"),
 			[]string{"*binman.ReleaseExcludeAction", "*binman.GetGHReleaseAction", "*binman.ReleaseStatusAction", "*binman.SetUrlAction", "*binman.SetArtifactPathAction", "*binman.SetPostActions"},
 		},
+		{
+			"relVersionUrl",
+			relVersionUrl.setPreActions("/tmp/", "/tmp/"),
+			[]string{"*binman.ReleaseExcludeAction", "*binman.GetVersionUrlAction", "*binman.ReleaseStatusAction", "*binman.SetUrlAction", "*binman.SetArtifactPathAction", "*binman.SetPostActions"},
+		},
 		{
 			"relGLBasic",
 			relGLBasic.setPreActions("/tmp/", "/tmp"),
@@ -161,6 +181,12 @@ func TestSetPostActions(t *testing.T) {
 		filepath: "extractbinman.zip",
 	}
 
+	relOCI := BinmanRelease{
+		Repo:      "org/tool",
+		ImagePath: "/usr/local/bin/tool",
+		source:    &Source{Name: "ghcr.io", Apitype: "oci"},
+	}
+
 	var tests = []struct {
 		name            string
 		ReturnedActions []Action
@@ -191,6 +217,11 @@ func TestSetPostActions(t *testing.T) {
 			relWithZip.setPostActions(),
 			[]string{"*binman.DownloadAction", "*binman.ExtractAction", "*binman.FindTargetAction", "*binman.MakeExecuteableAction", "*binman.WriteRelNotesAction", "*binman.SetOsActions"},
 		},
+		{
+			"oci",
+			relOCI.setPostActions(),
+			[]string{"*binman.OCIPullAction", "*binman.FindTargetAction", "*binman.MakeExecuteableAction", "*binman.WriteRelNotesAction", "*binman.SetOsActions"},
+		},
 	}
 
 	for _, test := range tests {
@@ -293,3 +324,31 @@ func TestSetFinalActions(t *testing.T) {
 		}
 	}
 }
+
+func TestDeferOnRateLimit(t *testing.T) {
+	rel := BinmanRelease{Repo: "rjbrown57/binman"}
+
+	reset := time.Now().Add(time.Hour)
+
+	var tests = []struct {
+		err      error
+		deferred bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://api.github.com", Err: &ratelimit.LimitedError{Host: "api.github.com", Reset: reset}}, true},
+		{&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}}, true},
+		{errors.New("some other error"), false},
+	}
+
+	for _, test := range tests {
+		err := rel.deferOnRateLimit(test.err)
+
+		var deferred *DeferredError
+		if errors.As(err, &deferred) != test.deferred {
+			t.Fatalf("%v: expected deferred %t got %v", test.err, test.deferred, err)
+		}
+
+		if test.deferred && !deferred.Until.Equal(reset) {
+			t.Fatalf("Expected deferral until %s got %s", reset, deferred.Until)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package binman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/versionurl"
+	"github.com/rodaine/table"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultAdoptRegex matches the first version like string a binary prints. e.g 1.2.3, v0.4 or 2.0.0-rc1
+const DefaultAdoptRegex = `v?\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.]+)?`
+
+// DefaultVersionArgs are tried in order until a binary prints something matching the version regex
+var DefaultVersionArgs = []string{"--version", "version", "-version"}
+
+// versionTimeout is how long a binary is given to print its version
+const versionTimeout = 5 * time.Second
+
+// scanConcurrency limits how many binaries AdoptScan runs at once
+const scanConcurrency = 4
+
+// AdoptOptions configures how Adopt finds the version of a binary
+type AdoptOptions struct {
+	Repo         string   // release the binary belongs to. e.g org/project or gitlab.com/org/project
+	Version      string   // use this version instead of running the binary
+	VersionArgs  []string // arguments that make the binary print its version. Each is split on whitespace
+	VersionRegex string   // extracts the version from the output. The first capture group is used if present
+	DryRun       bool
+}
+
+// AdoptResult describes a binary that was, or could be, adopted
+type AdoptResult struct {
+	Path     string `json:"path"`
+	Repo     string `json:"repo,omitempty"`
+	Detected string `json:"detected,omitempty"` // version the binary reported
+	Tag      string `json:"tag,omitempty"`      // upstream tag the version matched
+	Artifact string `json:"artifact,omitempty"` // where the binary is stored
+	Link     string `json:"link,omitempty"`     // binman link the original path now points at
+	Added    bool   `json:"added,omitempty"`    // the release was added to the config
+	Note     string `json:"note,omitempty"`
+}
+
+// Adopt brings a binary that was installed by hand under binman management. The version the binary reports is matched
+// to an upstream tag of o.Repo, the binary is copied into the release path as that version and recorded in the db. The
+// original is replaced with a link to the binman link so later syncs update it. Releases missing from the config are added
+func Adopt(path string, o AdoptOptions, dbPath, config string) (*AdoptResult, error) {
+
+	c := NewBMConfig(config).SetConfig(false)
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := c.adoptable(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s - %w", path, err)
+	}
+
+	rel, configured := c.adoptRelease(o.Repo)
+
+	res := &AdoptResult{Path: path, Repo: rel.Repo, Detected: o.Version}
+
+	if res.Detected == "" {
+		if res.Detected, err = detectVersion(src, o.VersionArgs, o.VersionRegex); err != nil {
+			return res, err
+		}
+	}
+
+	found, err := c.matchTag(rel, res.Detected)
+	if err != nil {
+		return res, err
+	}
+	res.Tag = found.Version
+
+	linkName := rel.LinkName
+	if linkName == "" {
+		linkName = rel.project
+	}
+
+	found.setpublishPath(found.ReleasePath, found.Version)
+	found.ArtifactPath = filepath.Join(found.PublishPath, rel.project)
+	found.linkPath = filepath.Join(found.BinPath, linkName)
+	found.assetName = filepath.Base(src)
+
+	res.Artifact = found.ArtifactPath
+	res.Link = found.linkPath
+	res.Added = !configured
+
+	if _, err := os.Stat(found.PublishPath); err == nil {
+		return res, fmt.Errorf("%s(%s) is already stored at %s", rel.Repo, found.Version, found.PublishPath)
+	}
+
+	// The original path may be the binman link itself, otherwise anything else already at the link is left alone
+	if found.linkPath != path {
+		if info, err := os.Lstat(found.linkPath); err == nil && info.Mode()&os.ModeSymlink == 0 {
+			return res, fmt.Errorf("%s already exists and is not a link", found.linkPath)
+		}
+	}
+
+	if o.DryRun {
+		log.Infof("%s will be stored as %s(%s) at %s", path, rel.Repo, found.Version, found.ArtifactPath)
+		return res, nil
+	}
+
+	if err := found.store(src, dbPath, config); err != nil {
+		return res, err
+	}
+
+	if err := replaceWithLink(path, found.ArtifactPath, found.linkPath); err != nil {
+		return res, err
+	}
+
+	if !configured {
+		if err := c.addReleasesToConfig(BinmanRelease{Repo: o.Repo}); err != nil {
+			return res, err
+		}
+	}
+
+	log.Infof("%s adopted as %s(%s)", path, rel.Repo, found.Version)
+	return res, nil
+}
+
+// AdoptScan suggests a release and version for every executable in dir. Executables are matched to configured releases by
+// their link name, and the version of each match is looked up upstream. Nothing is changed
+func AdoptScan(dir string, o AdoptOptions, config string) ([]AdoptResult, error) {
+
+	c := NewBMConfig(config).SetConfig(false)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AdoptResult, len(entries))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, scanConcurrency)
+
+	for index, e := range entries {
+		results[index].Path = filepath.Join(dir, e.Name())
+
+		src, err := c.adoptable(results[index].Path)
+		if err != nil {
+			results[index].Note = err.Error()
+			continue
+		}
+
+		wg.Add(1)
+		go func(res *AdoptResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			c.suggest(res, src, o)
+		}(&results[index])
+	}
+
+	wg.Wait()
+
+	// Directories and other files that are not executables are not worth reporting
+	var suggestions []AdoptResult
+	for _, res := range results {
+		if res.Note != errNotExecutable {
+			suggestions = append(suggestions, res)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// suggest fills res with the release and tag the binary at src would be adopted as. Binaries are matched to a release
+// by name first, only a binary that matches exactly one release is run to find its version
+func (config *BMConfig) suggest(res *AdoptResult, src string, o AdoptOptions) {
+
+	name := filepath.Base(res.Path)
+
+	var matches []BinmanRelease
+	for _, rel := range config.Releases {
+		linkName := rel.LinkName
+		if linkName == "" {
+			linkName = rel.project
+		}
+		if linkName == name {
+			matches = append(matches, rel)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		res.Note = "no configured release matches. Use binman adopt --repo"
+		return
+	case 1:
+	default:
+		res.Note = fmt.Sprintf("%d configured releases match", len(matches))
+		return
+	}
+
+	res.Repo = matches[0].Repo
+
+	detected, err := detectVersion(src, o.VersionArgs, o.VersionRegex)
+	if err != nil {
+		res.Note = err.Error()
+		return
+	}
+	res.Detected = detected
+
+	found, err := config.matchTag(matches[0], detected)
+	if err != nil {
+		res.Note = err.Error()
+		return
+	}
+	res.Tag = found.Version
+}
+
+// errNotExecutable is the reason adoptable gives for files that are not executables
+const errNotExecutable = "not an executable"
+
+// adoptable checks that path is an executable that binman does not already manage and returns the file to adopt.
+// A link to an executable outside the release path is followed
+func (config *BMConfig) adoptable(path string) (string, error) {
+
+	src, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	releasePath, err := filepath.Abs(config.Config.ReleasePath)
+	if err != nil {
+		return "", err
+	}
+	if p, err := filepath.EvalSymlinks(releasePath); err == nil {
+		releasePath = p
+	}
+
+	if strings.HasPrefix(src, filepath.Join(releasePath, "repos")+string(filepath.Separator)) {
+		return "", errors.New("already managed by binman")
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return "", errors.New(errNotExecutable)
+	}
+
+	return src, nil
+}
+
+// adoptRelease returns the configured release for repo. Unconfigured releases are populated with the config defaults
+func (config *BMConfig) adoptRelease(repo string) (BinmanRelease, bool) {
+
+	if rel, err := config.findRelease(repo); err == nil {
+		return rel, true
+	}
+
+	c := &BMConfig{Config: config.Config, Defaults: config.Defaults, Releases: []BinmanRelease{{Repo: repo}}}
+	c.populateReleases()
+
+	return c.Releases[0], false
+}
+
+// detectVersion runs the binary at path with each of args until its output matches rx. The extracted version is returned
+func detectVersion(path string, args []string, rx string) (string, error) {
+
+	if len(args) == 0 {
+		args = DefaultVersionArgs
+	}
+
+	if rx == "" {
+		rx = DefaultAdoptRegex
+	}
+
+	for _, arg := range args {
+		ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+
+		// #nosec G204 -- running the binary is the point, the user has asked for it to be adopted
+		cmd := exec.CommandContext(ctx, path, strings.Fields(arg)...)
+		cmd.WaitDelay = time.Second
+		out, err := cmd.CombinedOutput()
+		cancel()
+
+		if err != nil {
+			log.Debugf("%s %s failed - %s", path, arg, err)
+			continue
+		}
+
+		if v, err := versionurl.Extract(out, "", rx); err == nil {
+			log.Debugf("%s %s reported %s", path, arg, v)
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to find a version with %v", args)
+}
+
+// tagCandidates returns the tags a detected version could be published under. Binaries rarely print the v prefix of
+// their tag, and some print a suffix the tag does not have
+func tagCandidates(version string) []string {
+
+	trimmed := strings.TrimPrefix(version, "v")
+	candidates := []string{version, "v" + trimmed, trimmed}
+
+	if core, _, found := strings.Cut(trimmed, "-"); found {
+		candidates = append(candidates, "v"+core, core)
+	}
+
+	var unique []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if !seen[c] {
+			seen[c] = true
+			unique = append(unique, c)
+		}
+	}
+
+	return unique
+}
+
+// matchTag queries the source of rel for each tag version could be published under and returns the release of the first found
+func (config *BMConfig) matchTag(rel BinmanRelease, version string) (BinmanRelease, error) {
+
+	var errs []error
+
+	for _, tag := range tagCandidates(version) {
+		r := rel
+		r.Version = tag
+		r.QueryType = "releasebytag"
+
+		// With no db or downloader each release only queries its source
+		q := &BMConfig{Config: config.Config, Releases: []BinmanRelease{r}}
+		q.CollectData()
+
+		if len(q.Msgs) == 1 && q.Msgs[0].Err == nil {
+			return q.Msgs[0].Rel, nil
+		}
+
+		for _, msg := range q.Msgs {
+			log.Debugf("%s(%s) not found - %s", rel.Repo, tag, msg.Err)
+			errs = append(errs, msg.Err)
+		}
+	}
+
+	return rel, fmt.Errorf("no tag of %s matches %s - %w", rel.Repo, version, errors.Join(errs...))
+}
+
+// store copies the binary at src to the artifact path of r and records the version in the db
+func (r *BinmanRelease) store(src, dbPath, config string) error {
+
+	if checkNewDb(dbPath) {
+		var dwg sync.WaitGroup
+		if err := populateDB(db.DbConfig{Path: dbPath, Dwg: &dwg, DbChan: make(chan db.DbMsg)}, config); err != nil {
+			return err
+		}
+	}
+
+	if err := CreateDirectory(r.PublishPath); err != nil {
+		return err
+	}
+
+	if err := CopyFile(src, r.ArtifactPath); err != nil {
+		return err
+	}
+
+	if err := MakeExecuteable(r.ArtifactPath); err != nil {
+		return err
+	}
+
+	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: false})
+	defer bdb.Close()
+
+	return db.WriteData(false, fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, r.Version), dataToBytes(r.getDataMap()), bdb)
+}
+
+// replaceWithLink points linkPath at artifact and replaces path with a link to linkPath. The replacement is renamed into
+// place so path is never missing
+func replaceWithLink(path, artifact, linkPath string) error {
+
+	target := linkPath
+	if path == linkPath {
+		target = artifact
+	} else if err := createLink(artifact, linkPath); err != nil {
+		return err
+	}
+
+	tmp := path + ".binman-adopt"
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// OutputAdoptResults prints a table of adopted or suggested binaries
+func OutputAdoptResults(results []AdoptResult) {
+
+	headerFmt := color.New(color.FgBlue, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Path", "Version", "Repo", "Tag", "Note")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, res := range results {
+		note := res.Note
+		if note == "" && res.Link != "" {
+			note = fmt.Sprintf("linked to %s", res.Link)
+		}
+		tbl.AddRow(res.Path, res.Detected, res.Repo, res.Tag, note)
+	}
+
+	tbl.Print()
+}
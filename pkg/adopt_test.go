@@ -0,0 +1,262 @@
+package binman
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/templating"
+	bolt "go.etcd.io/bbolt"
+)
+
+const adoptConfig = `
+config:
+  releasepath: {{ .releasePath }}
+  sources:
+   - name: usb
+     apitype: file
+     url: file://{{ .sourcePath }}
+releases:
+{{- range .releases }}
+  - repo: {{ . }}
+{{- end }}
+`
+
+// adoptTestSetup creates a file source with versions of org1/tool and a config using it
+func adoptTestSetup(t *testing.T, releases ...string) (string, string) {
+
+	sourcePath := t.TempDir()
+	releasePath := t.TempDir()
+
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		dir := filepath.Join(sourcePath, "org1", "tool", version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		asset := fmt.Sprintf("tool_%s_%s_%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+		writeTestTarGz(t, filepath.Join(dir, asset), "tool", "#!/bin/sh\necho "+version+"\n")
+	}
+
+	cf := filepath.Join(releasePath, "config")
+	cfg := templating.TemplateString(adoptConfig, map[string]any{"releasePath": releasePath, "sourcePath": sourcePath, "releases": releases})
+	if err := WriteStringtoFile(cf, cfg); err != nil {
+		t.Fatalf("Unable to write test config")
+	}
+
+	return releasePath, cf
+}
+
+// writeTestBinary writes an executable shell script to path
+func writeTestBinary(t *testing.T, path string, script string) {
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("Unable to write %s", path)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+
+	releasePath, cf := adoptTestSetup(t)
+	dbPath := filepath.Join(releasePath, "binman.db")
+
+	binDir := t.TempDir()
+	bin := filepath.Join(binDir, "tool")
+	writeTestBinary(t, bin, `echo "tool version 1.0.0 (abc123)"`)
+
+	o := AdoptOptions{Repo: "usb/org1/tool", DryRun: true}
+
+	res, err := Adopt(bin, o, dbPath, cf)
+	if err != nil {
+		t.Fatalf("Dry run failed - %s", err)
+	}
+
+	if info, err := os.Lstat(bin); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("Dry run replaced %s", bin)
+	}
+
+	if _, err := os.Stat(res.Artifact); err == nil {
+		t.Fatalf("Dry run stored %s", res.Artifact)
+	}
+
+	o.DryRun = false
+
+	res, err = Adopt(bin, o, dbPath, cf)
+	if err != nil {
+		t.Fatalf("Adopt failed - %s", err)
+	}
+
+	if res.Detected != "1.0.0" || res.Tag != "v1.0.0" || !res.Added {
+		t.Fatalf("Expected 1.0.0 adopted as v1.0.0 and added to the config got %+v", res)
+	}
+
+	want := filepath.Join(releasePath, "repos", "usb", "org1", "tool", "v1.0.0", "tool")
+	if res.Artifact != want {
+		t.Fatalf("Expected artifact %s got %s", want, res.Artifact)
+	}
+
+	if target, err := os.Readlink(bin); err != nil || target != res.Link {
+		t.Fatalf("Expected %s to link to %s got %s", bin, res.Link, target)
+	}
+
+	if target, err := os.Readlink(res.Link); err != nil || target != want {
+		t.Fatalf("Expected %s to link to %s got %s", res.Link, want, target)
+	}
+
+	out, err := exec.Command(bin).CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "1.0.0") {
+		t.Fatalf("Expected adopted binary to run got %s %v", out, err)
+	}
+
+	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	b, err := db.GetData("usb/org1/tool/v1.0.0/data", bdb)
+	bdb.Close()
+	if err != nil {
+		t.Fatalf("Expected v1.0.0 in the db - %s", err)
+	}
+
+	data := bytesToData(b)
+	if data["artifactPath"] != want || data["linkPath"] != res.Link {
+		t.Fatalf("Unexpected db data %+v", data)
+	}
+
+	c := NewBMConfig(cf).SetConfig(false)
+	if _, err := c.findRelease("usb/org1/tool"); err != nil {
+		t.Fatalf("Expected usb/org1/tool to be added to the config")
+	}
+
+	// The original is now a link into the release path
+	if _, err := Adopt(bin, o, dbPath, cf); err == nil {
+		t.Fatalf("Expected a second adopt of %s to fail", bin)
+	}
+}
+
+func TestAdoptNoTag(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+
+	releasePath, cf := adoptTestSetup(t, "usb/org1/tool")
+
+	bin := filepath.Join(t.TempDir(), "tool")
+	writeTestBinary(t, bin, `echo "tool 0.9.0"`)
+
+	res, err := Adopt(bin, AdoptOptions{Repo: "usb/org1/tool"}, filepath.Join(releasePath, "binman.db"), cf)
+	if err == nil {
+		t.Fatalf("Expected no tag to match 0.9.0 got %+v", res)
+	}
+
+	if info, err := os.Lstat(bin); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf("Expected %s to be left in place", bin)
+	}
+}
+
+func TestAdoptScan(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+
+	_, cf := adoptTestSetup(t, "usb/org1/tool")
+
+	dir := t.TempDir()
+	writeTestBinary(t, filepath.Join(dir, "tool"), `echo "v1.1.0"`)
+	// other matches no release, so it must never be run
+	ran := filepath.Join(dir, "ran")
+	writeTestBinary(t, filepath.Join(dir, "other"), fmt.Sprintf(`touch %s; echo "other 2.0"`, ran))
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a binary"), 0644); err != nil {
+		t.Fatalf("Unable to write README")
+	}
+
+	results, err := AdoptScan(dir, AdoptOptions{}, cf)
+	if err != nil {
+		t.Fatalf("Scan failed - %s", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 executables got %+v", results)
+	}
+
+	for _, res := range results {
+		switch filepath.Base(res.Path) {
+		case "tool":
+			if res.Repo != "org1/tool" || res.Tag != "v1.1.0" {
+				t.Fatalf("Expected tool to match org1/tool(v1.1.0) got %+v", res)
+			}
+		case "other":
+			if res.Repo != "" || res.Detected != "" || res.Note == "" {
+				t.Fatalf("Expected other to have no match got %+v", res)
+			}
+		}
+	}
+
+	if _, err := os.Stat(ran); err == nil {
+		t.Fatalf("Expected a binary with no matching release not to be run")
+	}
+}
+
+func TestDetectVersion(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test binaries are shell scripts")
+	}
+
+	bin := filepath.Join(t.TempDir(), "tool")
+	writeTestBinary(t, bin, `[ "$1" = "version" ] || exit 1
+echo "tool-1.7.1 built with go1.22.0"`)
+
+	var tests = []struct {
+		args []string
+		rx   string
+		want string
+		err  bool
+	}{
+		{nil, "", "1.7.1", false},
+		{[]string{"--version"}, "", "", true},
+		{[]string{"version"}, `go(\d+\.\d+\.\d+)`, "1.22.0", false},
+		{[]string{"version"}, `nomatch`, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := detectVersion(bin, test.args, test.rx)
+		if (err != nil) != test.err || got != test.want {
+			t.Fatalf("detectVersion(%v, %s) expected %s(err %v) got %s(%v)", test.args, test.rx, test.want, test.err, got, err)
+		}
+	}
+}
+
+func TestTagCandidates(t *testing.T) {
+
+	var tests = []struct {
+		version string
+		want    []string
+	}{
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}},
+		{"v1.2.3", []string{"v1.2.3", "1.2.3"}},
+		{"1.2.3-linux", []string{"1.2.3-linux", "v1.2.3-linux", "v1.2.3", "1.2.3"}},
+	}
+
+	for _, test := range tests {
+		if got := tagCandidates(test.version); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("tagCandidates(%s) expected %v got %v", test.version, test.want, got)
+		}
+	}
+}
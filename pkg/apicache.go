@@ -0,0 +1,74 @@
+package binman
+
+import (
+	"fmt"
+	"sync"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+)
+
+// apiCacheBucket is the top level db bucket for cached api responses. It sits beside the source buckets and holds no versions
+const apiCacheBucket = "_httpcache"
+
+// apiCacheStore persists api responses in the binman db so conditional requests can be sent on the next sync
+type apiCacheStore struct {
+	source string
+	dbChan chan db.DbMsg
+	dwg    *sync.WaitGroup
+}
+
+// apiCache returns the api response cache for a release, or nil if the release has no db
+func (r *BinmanRelease) apiCache() *apiCacheStore {
+	if r.dbChan == nil || r.dwg == nil || r.source == nil {
+		return nil
+	}
+
+	return &apiCacheStore{source: r.source.Name, dbChan: r.dbChan, dwg: r.dwg}
+}
+
+// id identifies the db backing the cache, clients are only shared between releases using the same db
+func (c *apiCacheStore) id() string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%p", c.dbChan)
+}
+
+func (c *apiCacheStore) dbKey(key string) string {
+	return fmt.Sprintf("%s/%s/%s/entry", apiCacheBucket, c.source, key)
+}
+
+func (c *apiCacheStore) Get(key string) ([]byte, error) {
+
+	c.dwg.Add(1)
+
+	var rwg sync.WaitGroup
+
+	dbMsg := db.DbMsg{
+		Operation:  "read",
+		Key:        c.dbKey(key),
+		ReturnChan: make(chan db.DBResponse, 1),
+		ReturnWg:   &rwg,
+	}
+
+	d := dbMsg.Send(c.dbChan)
+	return d.Data, d.Err
+}
+
+func (c *apiCacheStore) Set(key string, data []byte) error {
+
+	c.dwg.Add(1)
+
+	var rwg sync.WaitGroup
+
+	dbMsg := db.DbMsg{
+		Operation:  "write",
+		Key:        c.dbKey(key),
+		ReturnChan: make(chan db.DBResponse, 1),
+		ReturnWg:   &rwg,
+		Data:       data,
+	}
+
+	d := dbMsg.Send(c.dbChan)
+	return d.Err
+}
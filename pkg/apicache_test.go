@@ -0,0 +1,78 @@
+package binman
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestApiCacheStore(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	d, err := os.MkdirTemp(os.TempDir(), "binmanapicache")
+	if err != nil {
+		t.Fatalf("Unable to create test dir")
+	}
+	defer os.RemoveAll(d)
+
+	var dwg sync.WaitGroup
+
+	dbOptions := db.DbConfig{
+		Path:      fmt.Sprintf("%s/binman.db", d),
+		Dwg:       &dwg,
+		DbChan:    make(chan db.DbMsg),
+		Overwrite: true,
+	}
+
+	go db.RunDB(dbOptions)
+
+	rel := BinmanRelease{
+		source: &Source{Name: "github.com", Apitype: "github"},
+		dbChan: dbOptions.DbChan,
+		dwg:    &dwg,
+	}
+
+	cache := rel.apiCache()
+
+	if _, err := cache.Get("missing"); err == nil {
+		t.Fatalf("Expected error reading missing key")
+	}
+
+	for _, data := range []string{"first", "second"} {
+		if err := cache.Set("key", []byte(data)); err != nil {
+			t.Fatalf("Unexpected error writing cache %s", err)
+		}
+
+		got, err := cache.Get("key")
+		if err != nil || string(got) != data {
+			t.Fatalf("Expected %s got %s, %v", data, got, err)
+		}
+	}
+
+	dwg.Wait()
+	close(dbOptions.DbChan)
+
+	// The cache bucket must not be mistaken for a source when scanning versions
+	testDb := db.GetDB(dbOptions.Path)
+	defer testDb.Close()
+
+	if err := testDb.View(func(tx *bolt.Tx) error {
+		buckets, err := getVersionBuckets(tx)
+		if len(buckets) != 0 {
+			return fmt.Errorf("expected no version buckets got %d", len(buckets))
+		}
+		return err
+	}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if (&BinmanRelease{}).apiCache() != nil {
+		t.Fatalf("Expected no cache without a db")
+	}
+}
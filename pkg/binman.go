@@ -43,6 +43,9 @@ func goSyncRepo(rel BinmanRelease, c chan<- BinmanMsg, wg *sync.WaitGroup) {
 			case *ExcludeError:
 				c <- BinmanMsg{Rel: rel, Err: err}
 				return
+			case *DeferredError:
+				c <- BinmanMsg{Rel: rel, Err: err}
+				return
 			default:
 				c <- BinmanMsg{Rel: rel, Err: err}
 				if rel.cleanupOnFailure {
@@ -77,6 +80,7 @@ type BMConfig struct {
 	msgChan      chan BinmanMsg
 	downloadChan chan downloader.DlMsg
 	wg           sync.WaitGroup
+	planning     bool
 }
 
 // For running the default sync
@@ -138,6 +142,11 @@ func (config *BMConfig) BMClose() {
 		close(config.dbOptions.DbChan)
 	}
 
+	if config.OutputOptions.ProgressChan != nil {
+		log.Tracef("Closing progress chan")
+		close(config.OutputOptions.ProgressChan)
+	}
+
 	if config.OutputOptions.SpinChan != nil {
 		log.Tracef("Closing spin chan")
 		close(config.OutputOptions.SpinChan)
@@ -247,7 +256,13 @@ func (config *BMConfig) CollectData() {
 
 	var wg sync.WaitGroup
 
-	for _, rel := range config.Releases {
+	prefetched := config.prefetchReleases()
+
+	for index, rel := range config.Releases {
+		rel.relData = nil
+		if ghd, ok := prefetched[index]; ok {
+			rel.relData = ghd
+		}
 		wg.Add(1)
 		go goSyncRepo(rel, c, &wg)
 	}
@@ -309,6 +324,21 @@ func (config *BMConfig) populateReleases() {
 			config.Releases[index].dwg = config.dbOptions.Dwg
 
 			config.Releases[index].downloadChan = config.downloadChan
+			config.Releases[index].rewrites = config.Config.Rewrites
+			config.Releases[index].planning = config.planning
+
+			if !validVersionScheme(config.Releases[index].VersionScheme) {
+				log.Fatalf("%s has unknown versionscheme %s. Use one of %v", config.Releases[index].Repo, config.Releases[index].VersionScheme, versionSchemes)
+			}
+
+			if err := validAssetFilters(config.Releases[index].AssetFilters); err != nil {
+				log.Fatalf("%s has an invalid assetfilter - %s", config.Releases[index].Repo, err)
+			}
+
+			// Releases that do not pick a source of their own use the default source list
+			if !config.Releases[index].hasSource(config.Config.SourceMap) {
+				config.Releases[index].Sources = config.Defaults.Sources
+			}
 
 			// set sources
 			config.Releases[index].SetSource(config.Config.SourceMap)
@@ -342,8 +372,18 @@ func (config *BMConfig) populateReleases() {
 			}
 
 			// If the user has not supplied an external url check against our map of known external urls
-			if config.Releases[index].ExternalUrl == "" && config.Releases[index].source.Apitype != "binman" {
-				config.Releases[index].knownUrlCheck()
+			// binman, http-index and file sources find their own assets so known urls do not apply
+			if config.Releases[index].ExternalUrl == "" {
+				switch config.Releases[index].source.Apitype {
+				case "binman", "http-index", "file":
+				default:
+					config.Releases[index].knownUrlCheck()
+				}
+			}
+
+			// versionurl only supplies a version, the asset must come from url
+			if config.Releases[index].VersionUrl != "" && config.Releases[index].ExternalUrl == "" {
+				log.Fatalf("%s sets versionurl but not url", config.Releases[index].Repo)
 			}
 
 			// enable UpxShrink
@@ -413,6 +453,8 @@ func (config *BMConfig) SetDefaults() {
 
 	log.Debugf("set Sources = %+v", config.Config.Sources)
 
+	config.setRewrites()
+
 	// If user does not supply a ReleasePath var we will use HOMEDIR/binMan
 	if config.Config.ReleasePath == "" {
 		hDir, err := os.UserHomeDir()
@@ -435,7 +477,7 @@ func (config *BMConfig) SetDefaults() {
 		config.Config.NumWorkers = len(config.Releases)
 	}
 
-	if config.Config.TokenVar == "" && config.Config.SourceMap["github.com"].Tokenvar == "" {
+	if config.Config.TokenVar == "" && !config.Config.SourceMap["github.com"].hasCredentials() {
 		log.Debugf("config.tokenvar is not set. Using anonymous authentication. Please be aware you can quickly be rate limited by github. Instructions here https://docs.github.com/en/authentication/keeping-your-account-and-data-secure/creating-a-personal-access-token")
 		config.Config.SourceMap["github.com"].Tokenvar = "none"
 		config.Config.TokenVar = "none"
@@ -486,9 +528,34 @@ func setDefaultSources(config *BMConfig) {
 	for index, source := range config.Config.Sources {
 
 		switch source.Apitype {
-		case "gitlab", "github", "binman":
+		case "gitlab", "github", "binman", "oci":
+		case "gitea", "http-index", "file":
+			// There is no public default instance so the url must always be set
+			if source.URL == "" {
+				log.Fatalf("Source %s with apitype %s must set url", source.Name, source.Apitype)
+			}
 		default:
-			log.Fatalf("Source %s apitype %s must equal github/gitlab or binman", source.Name, source.Apitype)
+			log.Fatalf("Source %s apitype %s must equal github/gitlab/gitea/http-index/oci/file or binman", source.Name, source.Apitype)
+		}
+
+		// Transport options are checked up front so a bad cafile or client cert fails the run instead of every query
+		if source.hasTransportOptions() {
+			if _, err := source.newTransport(); err != nil {
+				log.Fatalf("Source %s has invalid transport options - %s", source.Name, err)
+			}
+			if source.InsecureSkipVerify {
+				log.Warnf("TLS certificate verification is disabled for source %s", source.Name)
+			}
+		}
+
+		// Github app auth requires all of appid/installationid/privatekeyfile
+		if source.AppID != 0 || source.InstallationID != 0 || source.PrivateKeyFile != "" {
+			if source.Apitype != "github" {
+				log.Fatalf("Source %s sets github app options but apitype is %s", source.Name, source.Apitype)
+			}
+			if source.AppID == 0 || source.InstallationID == 0 || source.PrivateKeyFile == "" {
+				log.Fatalf("Source %s must set all of appid, installationid and privatekeyfile for github app auth", source.Name)
+			}
 		}
 
 		// assign to sourceMap
@@ -501,8 +568,8 @@ func setDefaultSources(config *BMConfig) {
 				config.Config.Sources[index].URL = constants.DefaultGHBaseURL
 			}
 
-			// Compatability for existing githubtoken setting
-			if source.Tokenvar == "" && config.Config.TokenVar != "" {
+			// Compatability for existing githubtoken setting. Sources with their own credentials, or tokenvar: none, keep them
+			if !source.hasCredentials() && source.Tokenvar == "" && config.Config.TokenVar != "" {
 				config.Config.Sources[index].Tokenvar = config.Config.TokenVar
 			}
 		case "gitlab.com":
@@ -531,16 +598,19 @@ type OutputOptions struct {
 	Table   bool
 	Spinner bool
 
-	SpinChan chan string
-	Swg      sync.WaitGroup
+	SpinChan     chan string
+	ProgressChan chan downloader.Progress // Download progress, rendered alongside the spinner
+	Swg          sync.WaitGroup
 }
 
 // Set table for Table based output after since, if a spinner is needed by the operation set Spinner
+// Operations with a spinner will also render download progress
 func NewOutputOptions(table, spinner bool) *OutputOptions {
 	o := OutputOptions{Table: table, Spinner: spinner}
 	if o.Spinner {
 		o.SpinChan = make(chan string)
-		go getSpinner(log.IsDebug(), o.SpinChan, &o.Swg)
+		o.ProgressChan = make(chan downloader.Progress)
+		go getSpinner(log.IsDebug(), o.SpinChan, o.ProgressChan, &o.Swg)
 	}
 	return &o
 }
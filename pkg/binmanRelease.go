@@ -3,17 +3,21 @@ package binman
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rjbrown57/binman/pkg/constants"
 	db "github.com/rjbrown57/binman/pkg/db"
 	"github.com/rjbrown57/binman/pkg/downloader"
+	"github.com/rjbrown57/binman/pkg/filesource"
 	log "github.com/rjbrown57/binman/pkg/logging"
 	"github.com/rjbrown57/binman/pkg/templating"
 )
@@ -40,6 +44,17 @@ func (e *ExcludeError) Error() string {
 	return fmt.Sprintf("%s was excluded from consideration because: %s", e.RepoName, e.Criteria)
 }
 
+// DeferredError is returned when a release could not be queried because the source api rate limit is exhausted.
+// The release will be picked up again on the next sync
+type DeferredError struct {
+	RepoName string
+	Until    time.Time
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("%s was deferred, api rate limit is exhausted until %s", e.RepoName, e.Until.Format(time.DateTime))
+}
+
 // BinmanRelease contains info on specifc releases to hunt for
 type BinmanRelease struct {
 	Os               string        `yaml:"os,omitempty"`
@@ -52,14 +67,23 @@ type BinmanRelease struct {
 	ExternalUrl      string        `yaml:"url,omitempty"`             // User provided external url to use with versions grabbed from GH. Note you must also set ReleaseFileName
 	ExtractFileName  string        `yaml:"extractfilename,omitempty"` // The file within the release you want
 	ReleaseFileName  string        `yaml:"releasefilename,omitempty"` // Specifc Release filename to look for. This is useful if a project publishes a binary and not a tarball.
+	AssetFilters     []string      `yaml:"assetfilters,omitempty"`    // Regexes the asset name must match when binman picks the asset. Prefix with ! to exclude matches
 	Repo             string        `yaml:"repo"`                      // The specific repo name in github. e.g achore/syft
 	LinkName         string        `yaml:"linkname,omitempty"`        // Set what the final link will be. Defaults to project name.
 	Version          string        `yaml:"version,omitempty"`         // Pull a specific version
+	VersionUrl       string        `yaml:"versionurl,omitempty"`      // Url publishing the latest version. Used with url so no source api query is needed
+	VersionPath      string        `yaml:"versionpath,omitempty"`     // JSONPath to the version if versionurl returns json. e.g $.tag_name
+	VersionRegex     string        `yaml:"versionregex,omitempty"`    // Regex to extract the version from versionurl. The first capture group is used if present
+	VersionScheme    string        `yaml:"versionscheme,omitempty"`   // How stored versions are ordered. semver, calver, lexical or created
+	ImagePath        string        `yaml:"imagepath,omitempty"`       // File to copy out of an oci image. e.g /usr/local/bin/tool. If unset the release is pulled as an artifact
 	PostCommands     []PostCommand `yaml:"postcommands,omitempty"`
 	QueryType        string        `yaml:"querytype,omitempty"`
 	ReleasePath      string        `yaml:"releasepath,omitempty"`
 	BinPath          string        `yaml:"binpath,omitempty"`
 	SourceIdentifier string        `yaml:"source,omitempty"`      // Allow setting of source individually
+	Sources          []string      `yaml:"sources,omitempty"`     // Sources to try in priority order. e.g [binman-internal, github.com]
+	Labels           []string      `yaml:"labels,omitempty"`      // Group releases so they can be selected together. e.g [k8s, security]
+	Retention        *Retention    `yaml:"retention,omitempty"`   // Versions binman clean keeps. Overrides the global retention
 	PublishPath      string        `yaml:"publishpath,omitempty"` // Path Release will be set up at. Typically only set by set commands or library use.
 	ArtifactPath     string        `yaml:"-"`                     // Will be set by BinmanRelease.setPaths. This is the source path for the link aka the executable binary
 	ExcludeOs        []string      `yaml:"excludeos,omitempty"`   // Allows excluding certain OS's because we know that we'll never have releases for this OS
@@ -69,13 +93,17 @@ type BinmanRelease struct {
 	relData          any // Data gathered from source
 	relNotes         string
 	source           *Source
-	assetName        string // the target assetName
-	cleanupOnFailure bool   // mark true if we need to clean up on failure
-	dlUrl            string // the final donwload url
-	filepath         string // the target filepath for download
-	org              string // Will be provided by constuctor
-	project          string // Will be provided by constuctor
-	linkPath         string // Will be set by BinmanRelease.setPaths
+	fallbacks        []*Source // Sources from Sources in priority order. Set by SetSource
+	assetName        string    // the target assetName
+	cleanupOnFailure bool      // mark true if we need to clean up on failure
+	dlUrl            string    // the final donwload url
+	dlAccept         string    // Accept header required by dlUrl, if any
+	rewrites         []Rewrite // Download url rewrite rules from config
+	rewrite          *Rewrite  // The rule that rewrote dlUrl, if any
+	filepath         string    // the target filepath for download
+	org              string    // Will be provided by constuctor
+	project          string    // Will be provided by constuctor
+	linkPath         string    // Will be set by BinmanRelease.setPaths
 	actions          []Action
 	versions         []string // Used during clean operations
 	output           *OutputOptions
@@ -83,6 +111,10 @@ type BinmanRelease struct {
 	watchExposeMetrics bool
 	watchSync          bool
 
+	planning      bool       // stop once the asset is selected. Set by plan
+	applyEntry    *PlanEntry // use the planned version and asset instead of querying the source. Set by apply
+	preRewriteUrl string     // dlUrl before it was rewritten
+
 	dwg          *sync.WaitGroup       // Wait Group for db operations
 	dbChan       chan db.DbMsg         // Channel to send to DB
 	downloadChan chan downloader.DlMsg // Channel to request file download
@@ -144,6 +176,47 @@ func (r *BinmanRelease) SetSource(sourceMap map[string]*Source) {
 	if sourceMap["default"].Apitype == "binman" {
 		r.source = sourceMap["default"]
 	}
+
+	r.setFallbacks(sourceMap)
+}
+
+// setFallbacks resolves Sources to the ordered list of sources to try. The first listed source replaces the source set by SetSource
+func (r *BinmanRelease) setFallbacks(sourceMap map[string]*Source) {
+
+	r.fallbacks = nil
+
+	for _, name := range r.Sources {
+		source, exists := sourceMap[name]
+		if !exists {
+			log.Warnf("source %s listed for %s is not configured, skipping", name, r.Repo)
+			continue
+		}
+		r.fallbacks = append(r.fallbacks, source)
+	}
+
+	if len(r.fallbacks) == 0 {
+		return
+	}
+
+	r.source = r.fallbacks[0]
+
+	// Versions are stored under the first upstream source so every source in the list shares them
+	// binman sources are downstream so they keep the upstream identifier
+	for _, source := range r.fallbacks {
+		if source.Apitype != "binman" {
+			r.SourceIdentifier = source.Name
+			break
+		}
+	}
+}
+
+// hasSource returns true if the release selects a source by prefix, source or sources
+func (r *BinmanRelease) hasSource(sourceMap map[string]*Source) bool {
+	if r.SourceIdentifier != "" || len(r.Sources) > 0 {
+		return true
+	}
+	_, exists := sourceMap[strings.Split(r.Repo, "/")[0]]
+	return exists
 }
 
 func (r *BinmanRelease) findTarget() {
@@ -207,6 +280,15 @@ func (r *BinmanRelease) knownUrlCheck() {
 	}
 }
 
+// indexURL returns the index to search for an http-index release. The source url is templated with release data,
+// if it contains no template the project is appended. e.g https://releases.hashicorp.com + hashicorp/terraform = https://releases.hashicorp.com/terraform/
+func (r *BinmanRelease) indexURL() string {
+	if strings.Contains(r.source.URL, "{{") {
+		return templating.TemplateString(r.source.URL, r.getDataMap())
+	}
+	return strings.TrimSuffix(r.source.URL, "/") + "/" + r.project + "/"
+}
+
 // Helper method to set artifactPath for a requested release object
 // This will be called early in a main loop iteration so we can check if we already have a release
 func (r *BinmanRelease) setpublishPath(ReleasePath string, tag string) {
@@ -229,9 +311,66 @@ func (r *BinmanRelease) getDataMap() map[string]any {
 	dataMap["linkPath"] = r.linkPath
 	dataMap["assetName"] = r.assetName
 	dataMap["createdAt"] = r.createdAtTime
+	dataMap["servedBy"] = r.servedBy()
 	return dataMap
 }
 
+// dlAuth returns the download credential for dlUrl. Rewritten urls only ever use the credentials of their rule
+func (r *BinmanRelease) dlAuth() *downloader.DlAuth {
+	if r.rewrite != nil {
+		return r.rewrite.dlAuth(r.dlUrl)
+	}
+	return r.source.downloadAuth(r.dlUrl)
+}
+
+// dlTransport returns the transport for dlUrl. Rewritten urls are not served by the source so they use the default transport
+func (r *BinmanRelease) dlTransport() http.RoundTripper {
+	if r.rewrite != nil {
+		return nil
+	}
+	return r.source.transport()
+}
+
+// platform returns the os and arch of the release with templating applied. Since we're looking to template these fields we
+// can't rely on getting them directly from getDataMap() as it may return a templated string instead of what we expect.
+// Instead we rely on setting the data map back to the defaults for the environment to allow the user to template them
+func (r *BinmanRelease) platform() (string, string) {
+
+	dataMapWithDefaults := r.getDataMap()
+	dataMapWithDefaults["os"] = runtime.GOOS
+	dataMapWithDefaults["arch"] = runtime.GOARCH
+
+	osName, arch := r.Os, r.Arch
+	if arch != "" {
+		arch = templating.TemplateString(arch, dataMapWithDefaults)
+	}
+	if osName != "" {
+		osName = templating.TemplateString(osName, dataMapWithDefaults)
+	}
+
+	return osName, arch
+}
+
+// dlFileRoot returns the directory of a file source. Downloads of every other source may not read local files
+func (r *BinmanRelease) dlFileRoot() string {
+	if r.rewrite != nil || r.source == nil || r.source.Apitype != "file" {
+		return ""
+	}
+	root, err := filesource.Root(r.source.URL)
+	if err != nil {
+		return ""
+	}
+	return root
+}
+
+// servedBy returns the name of the source the release was found on
+func (r *BinmanRelease) servedBy() string {
+	if r.source == nil {
+		return ""
+	}
+	return r.source.Name
+}
+
 // Helper method to set paths for a requested release object
 func (r *BinmanRelease) setArtifactPath(ReleasePath, BinPath string, assetName string) {
 
@@ -289,7 +428,7 @@ func (r *BinmanRelease) FetchReleaseData(versions ...string) error {
 		repoPath := fmt.Sprintf("%s/repos/%s/%s", r.ReleasePath, r.SourceIdentifier, r.Repo)
 		log.Debugf("Scanning %s", repoPath)
 
-		versions, err = sortSemvers(GetVersionFromPath(repoPath))
+		versions, err = r.sortVersions(GetVersionFromPath(repoPath))
 		if err != nil {
 			log.Warnf("Error sorting %s %s", r.Repo, err)
 			return err
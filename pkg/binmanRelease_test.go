@@ -3,6 +3,7 @@ package binman
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"testing"
 
 	"github.com/rjbrown57/binman/pkg/constants"
@@ -99,7 +100,7 @@ func TestSetSource(t *testing.T) {
 			t.Fatalf("%d expected repo name %s : got %s", caseNum, test.expectedReponame, test.rel.Repo)
 		}
 		if test.expectedSource != test.rel.source {
-			t.Fatalf("%d expected source %s : got %s", caseNum, test.expectedSource, test.rel.source)
+			t.Fatalf("%d expected source %v : got %v", caseNum, test.expectedSource, test.rel.source)
 		}
 		if test.expectedSourceId != test.rel.SourceIdentifier {
 			t.Fatalf("%d expected source id %s : got %s", caseNum, test.expectedSourceId, test.rel.SourceIdentifier)
@@ -367,6 +368,7 @@ func TestGetDataMap(t *testing.T) {
 	testdataMap["publishPath"] = "test"
 	testdataMap["linkPath"] = "test"
 	testdataMap["assetName"] = "test"
+	testdataMap["servedBy"] = ""
 
 	m := rel.getDataMap()
 	for k, v := range m {
@@ -403,3 +405,40 @@ func TestKnownUrlCheck(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexURL(t *testing.T) {
+
+	var tests = []struct {
+		sourceUrl   string
+		expectedurl string
+	}{
+		{"https://releases.hashicorp.com", "https://releases.hashicorp.com/terraform/"},
+		{"https://releases.hashicorp.com/", "https://releases.hashicorp.com/terraform/"},
+		{"https://mirror.local/tools/{{.org}}/{{.project}}/", "https://mirror.local/tools/hashicorp/terraform/"},
+	}
+
+	for _, test := range tests {
+		rel := BinmanRelease{Repo: "hashicorp/terraform", source: &Source{URL: test.sourceUrl, Apitype: "http-index"}}
+		rel.getOR()
+		if got := rel.indexURL(); got != test.expectedurl {
+			t.Fatalf("Expected %s got %s", test.expectedurl, got)
+		}
+	}
+}
+
+func TestPlatform(t *testing.T) {
+
+	rel := BinmanRelease{Repo: "org/tool", Os: "{{ .os }}", Arch: "{{ .arch }}"}
+	rel.getOR()
+
+	osName, arch := rel.platform()
+	if osName != runtime.GOOS || arch != runtime.GOARCH {
+		t.Fatalf("Expected %s/%s got %s/%s", runtime.GOOS, runtime.GOARCH, osName, arch)
+	}
+
+	// oci pullers are shared by templated platform, so the lookup and the pull use the same puller
+	source := &Source{Name: "registry", Apitype: "oci", URL: "registry.example.com"}
+	if source.ociPuller(rel.platform()) != source.ociPuller(runtime.GOOS, runtime.GOARCH) {
+		t.Fatalf("Expected the puller for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+}
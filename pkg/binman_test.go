@@ -1,11 +1,19 @@
 package binman
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 
 	"github.com/google/go-github/v50/github"
+	db "github.com/rjbrown57/binman/pkg/db"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/templating"
 )
 
 func TestLibrary(t *testing.T) {
@@ -35,3 +43,89 @@ func TestLibrary(t *testing.T) {
 
 	log.Infof("%s - %s", c.Releases[0].Repo, data.GetTagName())
 }
+
+// writeTestTarGz writes a tar.gz containing a single executable
+func writeTestTarGz(t *testing.T, path string, name string, content string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Unable to create %s", path)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("Unable to write tar header %s", err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatalf("Unable to write tar content %s", err)
+	}
+
+	tw.Close()
+	gw.Close()
+}
+
+const fileSourceConfig = `
+config:
+  releasepath: {{ .releasePath }}
+  sources:
+   - name: usb
+     apitype: file
+     url: file://{{ .sourcePath }}
+releases:
+  - repo: usb/org1/tool
+`
+
+// TestFileSourceSync runs a full sync against a local file source
+func TestFileSourceSync(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	sourcePath := t.TempDir()
+	releasePath := t.TempDir()
+
+	for _, version := range []string{"v1.0.0", "v1.1.0", "v2.0.0-rc1"} {
+		dir := filepath.Join(sourcePath, "org1", "tool", version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		asset := fmt.Sprintf("tool_%s_%s_%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+		writeTestTarGz(t, filepath.Join(dir, asset), "tool", "#!/bin/sh\necho "+version+"\n")
+	}
+
+	cf := filepath.Join(releasePath, "config")
+	cfg := templating.TemplateString(fileSourceConfig, map[string]any{"releasePath": releasePath, "sourcePath": sourcePath})
+	if err := WriteStringtoFile(cf, cfg); err != nil {
+		t.Fatalf("Unable to write test config")
+	}
+
+	var dwg sync.WaitGroup
+	dbOptions := db.DbConfig{
+		Dwg:       &dwg,
+		DbChan:    make(chan db.DbMsg),
+		Path:      filepath.Join(releasePath, "binman.db"),
+		Overwrite: true,
+	}
+
+	c := NewBMConfig(cf).WithDb(dbOptions).WithDownloader().WithOutput(false, false).SetConfig(false)
+	c.CollectData()
+	c.BMClose()
+
+	if len(c.Msgs) != 1 || c.Msgs[0].Err != nil {
+		t.Fatalf("Expected a successful sync got %+v", c.Msgs)
+	}
+
+	if c.Msgs[0].Rel.Version != "v1.1.0" {
+		t.Fatalf("Expected v1.1.0 got %s", c.Msgs[0].Rel.Version)
+	}
+
+	target, err := filepath.EvalSymlinks(filepath.Join(releasePath, "tool"))
+	if err != nil {
+		t.Fatalf("Expected link to tool - %s", err)
+	}
+
+	if filepath.Base(filepath.Dir(target)) != "v1.1.0" {
+		t.Fatalf("Expected link into v1.1.0 got %s", target)
+	}
+}
@@ -3,14 +3,20 @@ package binman
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	semver "github.com/Masterminds/semver/v3"
+	"github.com/fatih/color"
 	db "github.com/rjbrown57/binman/pkg/db"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rodaine/table"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -19,7 +25,63 @@ var (
 	ErrVersionsListNotSorted = errors.New("Provided list of versions is not sorted")
 )
 
-func Clean(dryrun, scan bool, threshold int, dbPath, config string) error {
+// Clean reasons
+const (
+	cleanKeep    = "keep"    // more than keep newer versions are stored
+	cleanMaxAge  = "maxage"  // the version is older than maxage
+	cleanMaxSize = "maxsize" // the store is larger than maxsize
+	cleanOrphan  = "orphan"  // the version is on disk but not in the db
+	cleanLink    = "link"    // the link points into the release store at something that is gone
+)
+
+// skippedNoVersions is the skip reason of a release with nothing stored
+const skippedNoVersions = "no versions stored"
+
+// CleanedItem is a single version or link removed by Clean
+type CleanedItem struct {
+	Repo    string `json:"repo,omitempty"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path"`
+	Reason  string `json:"reason"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// CleanResult is the outcome of Clean for a single release
+type CleanResult struct {
+	Repo    string   `json:"repo"`
+	Kept    []string `json:"kept,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Skipped string   `json:"skipped,omitempty"` // why the versions of the release were not considered
+	Error   string   `json:"error,omitempty"`
+}
+
+// CleanReport lists what Clean removed, or would remove during a dry run
+type CleanReport struct {
+	DryRun    bool          `json:"dryRun"`
+	Releases  []CleanResult `json:"releases"`
+	Removed   []CleanedItem `json:"removed"`
+	Reclaimed int64         `json:"reclaimed"`
+}
+
+func (report *CleanReport) add(item CleanedItem) {
+	report.Removed = append(report.Removed, item)
+	report.Reclaimed += item.Bytes
+}
+
+// storedVersion is a version of a release considered by Clean
+type storedVersion struct {
+	version     string
+	publishPath string
+	created     time.Time
+	size        int64
+	protected   bool   // the newest, linked and rolled back versions are never removed
+	reason      string // why the version will be removed. Empty if it is kept
+}
+
+// Clean removes the versions each release's retention policy no longer needs and links into the release store that point at nothing.
+// Version directories the db does not know about are only removed when orphans is set or the db has been refreshed by scan.
+// threshold is used when no keep is configured
+func Clean(dryrun, scan, orphans bool, threshold int, dbPath, config string, sel ReleaseSelector) (*CleanReport, error) {
 
 	log.Infof("Binman Clean started")
 
@@ -37,55 +99,445 @@ func Clean(dryrun, scan bool, threshold int, dbPath, config string) error {
 		dryrun = true
 		fallthrough
 	case scan:
-		populateDB(dbOptions, config)
+		if err := populateDB(dbOptions, config); err != nil {
+			return &CleanReport{DryRun: dryrun}, fmt.Errorf("unable to populate db - %w", err)
+		}
 	}
 
 	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: false})
 
 	// Check here later and see if using dbOptions instead of defaults makes sense
-	c := NewBMConfig(config).SetConfig(false)
+	c := NewBMConfig(config).SetConfig(false).Select(sel)
+
+	report := &CleanReport{DryRun: dryrun}
+
+	err := c.clean(dryrun, orphans || scan, threshold, bdb, report)
+
+	if closeErr := bdb.Close(); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("unable to close db - %w", closeErr))
+	}
+
+	if err != nil {
+		return report, err
+	}
+
+	log.Infof("Clean complete")
+	return report, nil
+}
+
+func (config *BMConfig) clean(dryrun, orphans bool, threshold int, bdb *bolt.DB, report *CleanReport) error {
 
 	var cleanErrs []error
-	for _, rel := range c.Releases {
-		// Collect All Versions
-		err := rel.getVersions(bdb)
+
+	results := make([]CleanResult, len(config.Releases))
+
+	// A failure is recorded against its release and the clean moves on to the next one
+	fail := func(index int, err error) {
+		log.Warnf("Issue cleaning %s %s", config.Releases[index].Repo, err)
+		if results[index].Error != "" {
+			results[index].Error += "; "
+		}
+		results[index].Error += err.Error()
+		cleanErrs = append(cleanErrs, fmt.Errorf("%s: %w", config.Releases[index].Repo, err))
+	}
+
+	planned, err := config.planRetention(threshold, time.Now(), bdb, results, fail)
+	if err != nil {
+		return err
+	}
+
+	// Removals are made once every release has been planned so maxsize sees the whole store
+	for index := range config.Releases {
+		rel := &config.Releases[index]
+		for _, v := range planned[index] {
+			if v.reason == "" {
+				results[index].Kept = append(results[index].Kept, v.version)
+				continue
+			}
+			if err := rel.cleanVersion(v, dryrun, bdb, report); err != nil {
+				fail(index, err)
+				continue
+			}
+			results[index].Removed = append(results[index].Removed, v.version)
+		}
+	}
+
+	// Version directories missing from the db are only trusted to be orphans once the db has been refreshed or when asked for
+	if orphans {
+		for index := range config.Releases {
+			removed, err := config.Releases[index].cleanOrphans(dryrun, bdb, report)
+			results[index].Removed = append(results[index].Removed, removed...)
+			if err != nil {
+				fail(index, err)
+			}
+		}
+	}
+
+	if err := config.cleanDanglingLinks(dryrun, report); err != nil {
+		cleanErrs = append(cleanErrs, err)
+	}
+
+	report.Releases = results
+
+	return errors.Join(cleanErrs...)
+}
+
+// planRetention decides which stored versions of each release clean removes. Releases that fail are reported to fail and left out
+// of the plan, results records the skip reason of each release
+func (config *BMConfig) planRetention(threshold int, now time.Time, bdb *bolt.DB, results []CleanResult, fail func(int, error)) ([][]*storedVersion, error) {
+
+	storePolicy, err := config.Config.Retention.policy(threshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retention - %w", err)
+	}
+
+	var kept []*storedVersion
+
+	planned := make([][]*storedVersion, len(config.Releases))
+
+	for index := range config.Releases {
+		rel := &config.Releases[index]
+		results[index].Repo = rel.Repo
+
+		p, err := config.Config.Retention.merge(rel.Retention).policy(threshold)
 		if err != nil {
-			log.Warnf("Unable to get all versions for %s %s", rel.Repo, err)
+			fail(index, fmt.Errorf("invalid retention - %w", err))
 			continue
 		}
 
-		rel.versions, err = sortSemvers(rel.versions)
+		versions, skipped, err := rel.retain(p, now, bdb)
 		if err != nil {
-			log.Warnf("Unable to sort semvers for %s %s", rel.Repo, err)
+			fail(index, err)
 			continue
 		}
 
-		if len(rel.versions) < threshold {
-			log.Infof("Skipping %s this repo does not have more %d versions. %s", rel.Repo, threshold, rel.versions)
+		results[index].Skipped = skipped
+
+		for _, v := range versions {
+			if v.reason == "" {
+				kept = append(kept, v)
+			}
+		}
+		planned[index] = versions
+	}
+
+	// The global maxsize applies across every release, the oldest versions go first
+	if storePolicy.maxSize != 0 {
+		applyMaxSize(kept, storePolicy.maxSize)
+	}
+
+	return planned, nil
+}
+
+// retain decides which stored versions of a release are removed by policy p. If the versions can't be ordered the release is
+// skipped and the reason is returned
+func (r *BinmanRelease) retain(p retentionPolicy, now time.Time, bdb *bolt.DB) ([]*storedVersion, string, error) {
+
+	// Collect All Versions
+	if err := r.getVersions(bdb); err != nil {
+		log.Debugf("Unable to get all versions for %s %s", r.Repo, err)
+		return nil, skippedNoVersions, nil
+	}
+
+	data := make(map[string]map[string]any, len(r.versions))
+	for _, version := range r.versions {
+		byteData, err := db.GetData(fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, version), bdb)
+		if err != nil {
+			return nil, "", fmt.Errorf("issue getting data for %s/%s: %w", r.Repo, version, err)
+		}
+
+		if data[version], err = decodeData(byteData); err != nil {
+			return nil, "", fmt.Errorf("issue decoding data for %s/%s: %w", r.Repo, version, err)
+		}
+	}
+
+	versions, err := sortVersions(r.VersionScheme, r.versions, func(v string) time.Time { return dataCreated(data[v]) })
+	if err != nil {
+		log.Warnf("Unable to sort versions for %s %s. Set a versionscheme for this release", r.Repo, err)
+		return nil, err.Error(), nil
+	}
+
+	// The version a release is rolled back to is in use, so it is always kept
+	rollback, err := db.GetData(r.rollbackKey(), bdb)
+	if err != nil && !errors.Is(err, db.ErrNilReadResponse) {
+		return nil, "", fmt.Errorf("issue getting rollback for %s: %w", r.Repo, err)
+	}
+
+	linked, err := r.linkedVersions(bdb)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var planned []*storedVersion
+
+	for i, version := range versions {
+		d := data[version]
+		publishPath, ok := d["publishPath"].(string)
+		if !ok || publishPath == "" {
+			return nil, "", fmt.Errorf("missing publishPath for %s/%s", r.Repo, version)
+		}
+
+		v := &storedVersion{
+			version:     version,
+			publishPath: publishPath,
+			size:        dirSize(publishPath),
+			created:     dataCreated(d),
+			protected:   i == len(versions)-1 || version == string(rollback) || slices.Contains(linked, version),
+		}
+
+		// Versions added by a scan have no createdAt
+		if info, err := os.Stat(publishPath); err == nil && v.created.IsZero() {
+			v.created = info.ModTime()
+		}
+
+		switch {
+		case v.protected:
+			log.Debugf("%s(%s): is newest, linked or the rollback version and will be kept", r.Repo, version)
+		case len(versions)-1-i >= p.keep:
+			v.reason = cleanKeep
+		case p.maxAge != 0 && !v.created.IsZero() && now.Sub(v.created) > p.maxAge:
+			v.reason = cleanMaxAge
+		}
+
+		planned = append(planned, v)
+	}
+
+	// A release maxsize only counts the versions of that release
+	if r.Retention != nil && r.Retention.MaxSize != "" {
+		applyMaxSize(planned, p.maxSize)
+	}
+
+	return planned, "", nil
+}
+
+// applyMaxSize marks the oldest unprotected versions for removal until the kept versions fit in maxSize
+func applyMaxSize(versions []*storedVersion, maxSize int64) {
+
+	var total int64
+	var candidates []*storedVersion
+
+	for _, v := range versions {
+		if v.reason != "" {
 			continue
 		}
+		total += v.size
+		if !v.protected {
+			candidates = append(candidates, v)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].created.Before(candidates[j].created) })
+
+	for _, v := range candidates {
+		if total <= maxSize {
+			return
+		}
+		v.reason = cleanMaxSize
+		total -= v.size
+	}
+}
 
-		// Execute the Clean
-		err = rel.cleanOldReleases(dryrun, threshold, bdb)
+// linkedVersions returns the versions of a release that a link currently points at
+func (r *BinmanRelease) linkedVersions(bdb *bolt.DB) ([]string, error) {
+
+	links, err := r.releaseLinks(bdb)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, link := range links {
+		target, err := filepath.EvalSymlinks(link)
 		if err != nil {
-			log.Warnf("Issue cleaning %s %s", rel.Repo, err)
-			cleanErrs = append(cleanErrs, fmt.Errorf("%s: %w", rel.Repo, err))
+			continue
+		}
+		if rel, err := filepath.Rel(r.repoPath(), target); err == nil && !strings.HasPrefix(rel, "..") {
+			versions = append(versions, strings.Split(rel, string(filepath.Separator))[0])
 		}
 	}
 
-	err := bdb.Close()
+	return versions, nil
+}
+
+// cleanVersion deletes a stored version from disk and the db
+func (r *BinmanRelease) cleanVersion(v *storedVersion, dryrun bool, bdb *bolt.DB, report *CleanReport) error {
+
+	log.Infof("%s(%s): %s will be deleted (%s)", r.Repo, v.version, v.publishPath, v.reason)
+
+	if !dryrun {
+		// If path does not exist err value is nil
+		if err := os.RemoveAll(v.publishPath); err != nil {
+			return fmt.Errorf("error deleting %s: %w", v.publishPath, err)
+		}
+
+		// Delete the version bucket
+		if err := db.DeleteData(fmt.Sprintf("%s/%s/%s", r.SourceIdentifier, r.Repo, v.version), bdb); err != nil {
+			return fmt.Errorf("error removing %s-%s from db: %w", r.Repo, v.version, err)
+		}
+
+		log.Infof("%s(%s): cleaned successfully", r.Repo, v.version)
+	}
+
+	report.add(CleanedItem{Repo: r.Repo, Version: v.version, Path: v.publishPath, Reason: v.reason, Bytes: v.size})
+
+	return nil
+}
+
+// cleanOrphans deletes version directories of a release that are not in the db and returns their names.
+// Releases the db has no versions for and directories a link points into are left alone
+func (r *BinmanRelease) cleanOrphans(dryrun bool, bdb *bolt.DB, report *CleanReport) ([]string, error) {
+
+	var orphans []string
+
+	// During a dry run removed versions are still in the db, so they are not reported twice
+	data, err := r.storedVersionData(bdb)
 	if err != nil {
-		log.Fatalf("Unable to close db %s", err)
+		return nil, err
 	}
 
-	if len(cleanErrs) != 0 {
-		return errors.Join(cleanErrs...)
+	// Without a project bucket every directory would look like an orphan
+	if len(data) == 0 {
+		log.Debugf("%s: no versions stored, skipping orphan removal", r.Repo)
+		return nil, nil
+	}
+
+	linked, err := r.linkedVersions(bdb)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, _ := os.ReadDir(r.repoPath())
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, ok := data[e.Name()]; ok {
+			continue
+		}
+
+		orphan := filepath.Join(r.repoPath(), e.Name())
+
+		if slices.Contains(linked, e.Name()) {
+			log.Infof("%s(%s): %s is not in the db but is linked, keeping it", r.Repo, e.Name(), orphan)
+			continue
+		}
+		log.Infof("%s(%s): %s is not in the db and will be deleted", r.Repo, e.Name(), orphan)
+
+		item := CleanedItem{Repo: r.Repo, Version: e.Name(), Path: orphan, Reason: cleanOrphan, Bytes: dirSize(orphan)}
+
+		if !dryrun {
+			if err := os.RemoveAll(orphan); err != nil {
+				return orphans, fmt.Errorf("error deleting %s: %w", orphan, err)
+			}
+		}
+
+		report.add(item)
+		orphans = append(orphans, e.Name())
+	}
+
+	return orphans, nil
+}
+
+// cleanDanglingLinks deletes links in each binpath that point into a release store at something that no longer exists.
+// Links to anything outside the release store are left alone
+func (config *BMConfig) cleanDanglingLinks(dryrun bool, report *CleanReport) error {
+
+	seen := make(map[string]bool)
+
+	for _, rel := range config.Releases {
+		if seen[rel.BinPath] {
+			continue
+		}
+		seen[rel.BinPath] = true
+
+		store := filepath.Join(rel.ReleasePath, "repos") + string(filepath.Separator)
+
+		entries, _ := os.ReadDir(rel.BinPath)
+		for _, e := range entries {
+			link := filepath.Join(rel.BinPath, e.Name())
+
+			target, err := os.Readlink(link)
+			if err != nil {
+				continue
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(rel.BinPath, target)
+			}
+
+			if !strings.HasPrefix(filepath.Clean(target), store) {
+				continue
+			}
+			if _, err := os.Stat(link); err == nil {
+				continue
+			}
+
+			log.Infof("%s points at %s which is gone and will be deleted", link, target)
+
+			if !dryrun {
+				if err := os.Remove(link); err != nil {
+					return fmt.Errorf("error deleting %s: %w", link, err)
+				}
+			}
+
+			report.add(CleanedItem{Path: link, Reason: cleanLink})
+		}
 	}
 
-	log.Infof("Clean complete")
 	return nil
 }
 
+// dirSize returns the size of the files under path
+func dirSize(path string) int64 {
+
+	var size int64
+
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return size
+}
+
+// OutputCleanReport prints what Clean removed
+func OutputCleanReport(report *CleanReport) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	cleanTable := table.New("Repo", "Version", "Reason", "Size", "Path")
+	cleanTable.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, item := range report.Removed {
+		cleanTable.AddRow(item.Repo, item.Version, item.Reason, formatSize(item.Bytes), item.Path)
+	}
+
+	cleanTable.Print()
+
+	for _, result := range report.Releases {
+		switch {
+		case result.Error != "":
+			fmt.Printf("%s : error = %s\n", result.Repo, result.Error)
+		case result.Skipped != "" && result.Skipped != skippedNoVersions:
+			fmt.Printf("%s : skipped = %s\n", result.Repo, result.Skipped)
+		}
+	}
+
+	verb := "Reclaimed"
+	if report.DryRun {
+		verb = "Would reclaim"
+	}
+
+	fmt.Printf("\n%s %s\n", verb, formatSize(report.Reclaimed))
+}
+
 func sortSemvers(versions []string) ([]string, error) {
 	var sorted []string
 	var err error
@@ -120,68 +572,16 @@ func sortSemvers(versions []string) ([]string, error) {
 
 // getVersions will collect all versions we currently have stored in the DB
 func (r *BinmanRelease) getVersions(bdb *bolt.DB) error {
+	r.versions = nil
 	return bdb.View(func(tx *bolt.Tx) error {
-		sourceBucket := tx.Bucket([]byte(r.SourceIdentifier))
-		if sourceBucket == nil {
-			return fmt.Errorf("no versions stored for source %s", r.SourceIdentifier)
-		}
-		orgBucket := sourceBucket.Bucket([]byte(r.org))
-		if orgBucket == nil {
-			return fmt.Errorf("no versions stored for org %s/%s", r.SourceIdentifier, r.org)
-		}
-		projBucket := orgBucket.Bucket([]byte(r.project))
+		projBucket := r.projectBucket(tx)
 		if projBucket == nil {
-			return fmt.Errorf("no versions stored for %s/%s/%s", r.SourceIdentifier, r.org, r.project)
-		}
-		projBucket.ForEachBucket(func(k []byte) error {
-			version := string(k)
-			_, err := semver.NewVersion(version)
-			if err == nil {
-				r.versions = append(r.versions, version)
-				return nil
-			}
-			return err
+			return fmt.Errorf("no versions stored for %s/%s", r.SourceIdentifier, r.Repo)
+		}
+		// Versions are ordered by the versionscheme of the release, so any version is collected here
+		return projBucket.ForEachBucket(func(k []byte) error {
+			r.versions = append(r.versions, string(k))
+			return nil
 		})
-		return nil
 	})
 }
-
-func (r *BinmanRelease) cleanOldReleases(dryrun bool, threshold int, bdb *bolt.DB) error {
-
-	numVersions := len(r.versions)
-
-	for _, toDelete := range r.versions[:numVersions-threshold] {
-
-		byteData, err := db.GetData(fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, toDelete), bdb)
-		if err != nil {
-			return fmt.Errorf("issue getting data for %s/%s: %w", r.Repo, toDelete, err)
-		}
-
-		d := bytesToData(byteData)
-		publishPath, ok := d["publishPath"].(string)
-		if !ok || publishPath == "" {
-			return fmt.Errorf("missing publishPath for %s/%s", r.Repo, toDelete)
-		}
-
-		log.Infof("%s(%s): %s will be deleted", d["repo"], d["version"], publishPath)
-
-		if dryrun {
-			continue
-		}
-
-		// If path does not exist err value is nil
-		err = os.RemoveAll(publishPath)
-		if err != nil {
-			log.Fatalf("Error deleting %s", publishPath)
-		}
-
-		// Delete the version bucket
-		err = db.DeleteData(fmt.Sprintf("%s/%s/%s", r.SourceIdentifier, r.Repo, toDelete), bdb)
-		if err != nil {
-			log.Fatalf("Error removing %s-%s from db", r.Repo, toDelete)
-		}
-		log.Infof("%s(%s): cleaned successfully", d["repo"], d["version"])
-
-	}
-	return nil
-}
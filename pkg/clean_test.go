@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	db "github.com/rjbrown57/binman/pkg/db"
 	log "github.com/rjbrown57/binman/pkg/logging"
@@ -73,7 +76,7 @@ func TestClean(t *testing.T) {
 			log.Fatalf("Issue populating DB %s", err)
 		}
 
-		err = Clean(false, true, test.threshold, dbPath, testConfig)
+		_, err = Clean(false, true, false, test.threshold, dbPath, testConfig, ReleaseSelector{})
 		if err != nil {
 			t.Fatalf("Failed to run clean %s", err)
 		}
@@ -136,8 +139,203 @@ func TestCleanReturnsErrorForMissingVersionData(t *testing.T) {
 		t.Fatalf("Unable to close db")
 	}
 
-	err = Clean(false, false, 1, dbPath, testConfig)
+	_, err = Clean(false, false, false, 1, dbPath, testConfig, ReleaseSelector{})
 	if err == nil {
 		t.Fatalf("Expected clean to return an error for missing version data")
 	}
 }
+
+const testRetentionConfig = `
+config:
+  releasepath: {{ .releasePath }}
+  retention:
+    keep: 10
+    maxage: 30d
+releases:
+  - repo: org1/repo1
+  - repo: org1/repo2
+`
+
+const testMaxSizeConfig = `
+config:
+  releasepath: {{ .releasePath }}
+releases:
+  - repo: org1/repo1
+    retention:
+      keep: 10
+      maxsize: 12B
+`
+
+// ageVersions sets the modification time of each version directory, oldest first
+func ageVersions(t *testing.T, releasePath string, ages map[string]time.Duration) {
+	for v, age := range ages {
+		when := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", v), when, when); err != nil {
+			t.Fatalf("Unable to age %s - %s", v, err)
+		}
+	}
+}
+
+func TestCleanRetention(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testRetentionConfig, "retention")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3", "v0.0.4"})
+
+	day := 24 * time.Hour
+	ageVersions(t, releasePath, map[string]time.Duration{"v0.0.1": 90 * day, "v0.0.2": 60 * day, "v0.0.3": 10 * day, "v0.0.4": 90 * day})
+
+	// A version copied in by hand, a link to a release that is gone and a dangling link outside the release store
+	orphan := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.1.0")
+	if err := os.MkdirAll(orphan, 0755); err != nil {
+		t.Fatalf("Unable to create %s", orphan)
+	}
+	if err := WriteStringtoFile(filepath.Join(orphan, "repo1"), "orphan"); err != nil {
+		t.Fatalf("Unable to write orphan")
+	}
+	dangling := filepath.Join(releasePath, "gone")
+	if err := os.Symlink(filepath.Join(releasePath, "repos", "github.com", "org1", "gone", "v1.0.0", "gone"), dangling); err != nil {
+		t.Fatalf("Unable to create %s", dangling)
+	}
+	other := filepath.Join(releasePath, "other")
+	if err := os.Symlink("/nonexistent/other", other); err != nil {
+		t.Fatalf("Unable to create %s", other)
+	}
+
+	// A version missing from the db that is still linked and a release the db has no versions for are never orphans
+	pinned := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.2.0")
+	unknown := filepath.Join(releasePath, "repos", "github.com", "org1", "repo2", "v1.0.0")
+	for _, dir := range []string{pinned, unknown} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		if err := WriteStringtoFile(filepath.Join(dir, filepath.Base(filepath.Dir(dir))), "kept"); err != nil {
+			t.Fatalf("Unable to write to %s", dir)
+		}
+	}
+	if err := os.Symlink(filepath.Join(pinned, "repo1"), filepath.Join(releasePath, "pinned")); err != nil {
+		t.Fatalf("Unable to link %s", pinned)
+	}
+
+	// Orphans are left alone unless asked for
+	report, err := Clean(true, false, false, 3, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected dry run error %s", err)
+	}
+	if len(report.Removed) != 3 {
+		t.Fatalf("Expected a dry run without orphans to report 3 items got %+v", report.Removed)
+	}
+
+	report, err = Clean(true, false, true, 3, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected dry run error %s", err)
+	}
+	if _, err := os.Stat(orphan); err != nil || len(report.Removed) != 4 || !report.DryRun {
+		t.Fatalf("Expected a dry run to report 4 items and remove nothing got %+v", report)
+	}
+
+	report, err = Clean(false, false, true, 3, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected clean error %s", err)
+	}
+
+	reasons := make(map[string]string)
+	for _, item := range report.Removed {
+		reasons[item.Path] = item.Reason
+	}
+
+	// v0.0.4 is older than maxage but it is the newest and linked version
+	expected := map[string]string{
+		filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.0.1"): cleanMaxAge,
+		filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.0.2"): cleanMaxAge,
+		orphan:   cleanOrphan,
+		dangling: cleanLink,
+	}
+	if len(reasons) != len(expected) {
+		t.Fatalf("Expected %v got %v", expected, reasons)
+	}
+	for path, reason := range expected {
+		if reasons[path] != reason {
+			t.Fatalf("Expected %s to be removed for %s got %v", path, reason, reasons)
+		}
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed", path)
+		}
+	}
+
+	// 6 bytes for each version and the orphan
+	if report.Reclaimed != 18 {
+		t.Fatalf("Expected 18 bytes reclaimed got %d", report.Reclaimed)
+	}
+
+	if _, err := os.Lstat(other); err != nil {
+		t.Fatalf("Links outside the release store should be kept")
+	}
+
+	for _, dir := range []string{pinned, unknown} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Fatalf("Expected %s to be kept", dir)
+		}
+	}
+}
+
+func TestCleanMaxSize(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testMaxSizeConfig, "maxsize")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3"})
+
+	day := 24 * time.Hour
+	ageVersions(t, releasePath, map[string]time.Duration{"v0.0.1": 3 * day, "v0.0.2": 2 * day, "v0.0.3": day})
+
+	report, err := Clean(false, false, false, 3, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected clean error %s", err)
+	}
+
+	if len(report.Removed) != 1 || report.Removed[0].Version != "v0.0.1" || report.Removed[0].Reason != cleanMaxSize {
+		t.Fatalf("Expected v0.0.1 to be removed for maxsize got %+v", report.Removed)
+	}
+}
+
+const testCalverConfig = `
+config:
+  releasepath: {{ .releasePath }}
+releases:
+  - repo: org1/repo1
+    versionscheme: calver
+`
+
+func TestCleanVersionScheme(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testCalverConfig, "calver")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"2024.10", "2023.12", "2024.9", "2024.11"})
+
+	report, err := Clean(false, false, false, 2, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected clean error %s", err)
+	}
+
+	if len(report.Releases) != 1 {
+		t.Fatalf("Expected a result for org1/repo1 got %+v", report.Releases)
+	}
+
+	result := report.Releases[0]
+	slices.Sort(result.Removed)
+	if !slices.Equal(result.Kept, []string{"2024.10", "2024.11"}) || !slices.Equal(result.Removed, []string{"2023.12", "2024.9"}) {
+		t.Fatalf("Expected 2024.10 and 2024.11 to be kept got %+v", result)
+	}
+}
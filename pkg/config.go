@@ -1,10 +1,13 @@
 package binman
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"gopkg.in/yaml.v3"
 )
 
 // if user does not provide a -c this will be populated at ~/.config/binman/config
@@ -117,3 +120,82 @@ func mustEnsureDefaultPaths() string {
 	}
 	return binmanConfigFile
 }
+
+// readConfigReleases parses the config at path as a yaml node tree and returns it along with its releases sequence. Editing
+// the node tree instead of a BMConfig keeps comments and the order of keys intact. An empty releases sequence is added if
+// the config has none
+func readConfigReleases(path string) (*yaml.Node, *yaml.Node, error) {
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("unable to find releases in %s", path)
+	}
+
+	root := doc.Content[0]
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "releases" {
+			continue
+		}
+		releases := root.Content[i+1]
+		// releases: with no entries is parsed as null
+		if releases.Kind == yaml.ScalarNode && releases.Tag == "!!null" {
+			releases.Kind, releases.Tag, releases.Value = yaml.SequenceNode, "!!seq", ""
+		}
+		if releases.Kind != yaml.SequenceNode {
+			return nil, nil, fmt.Errorf("unable to find releases in %s", path)
+		}
+		return &doc, releases, nil
+	}
+
+	releases := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "releases"}, releases)
+
+	return &doc, releases, nil
+}
+
+// writeConfigDoc writes a node tree returned by readConfigReleases back to path
+func writeConfigDoc(path string, doc *yaml.Node) error {
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return WriteStringtoFile(path, buf.String())
+}
+
+// addReleasesToConfig appends rels to the releases of the config file. Comments and existing entries are left untouched
+func (config *BMConfig) addReleasesToConfig(rels ...BinmanRelease) error {
+
+	doc, releases, err := readConfigReleases(config.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range rels {
+		var node yaml.Node
+		if err := node.Encode(rel); err != nil {
+			return err
+		}
+		releases.Content = append(releases.Content, &node)
+	}
+
+	return writeConfigDoc(config.ConfigPath, doc)
+}
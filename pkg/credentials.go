@@ -0,0 +1,280 @@
+package binman
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/rjbrown57/binman/pkg/gh"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrNetrcNoMatch is returned when a netrc file has no entry for the requested host
+	ErrNetrcNoMatch = errors.New("No matching netrc entry")
+)
+
+// cachedToken holds a resolved credential. Credentials are resolved once and kept for the lifetime of the process
+type cachedToken struct {
+	once  sync.Once
+	token string
+}
+
+var credentialCache = struct {
+	sync.Mutex
+	tokens map[string]*cachedToken
+}{tokens: make(map[string]*cachedToken)}
+
+// cachedTokenSource holds the token source for a github app. The token source handles refreshing installation tokens
+type cachedTokenSource struct {
+	once sync.Once
+	ts   oauth2.TokenSource
+	err  error
+}
+
+var appTokenSources = struct {
+	sync.Mutex
+	sources map[string]*cachedTokenSource
+}{sources: make(map[string]*cachedTokenSource)}
+
+// credentialKey identifies the credential configuration of a source. It never contains the credential itself
+func (s *Source) credentialKey() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%t|%s|%d|%d|%s", s.Name, s.Tokenvar, s.Tokenfile, strings.Join(s.Tokencommand, " "), s.Netrc, s.host(), s.AppID, s.InstallationID, s.PrivateKeyFile)
+}
+
+// hasCredentials reports whether any credential method has been configured for a source
+func (s *Source) hasCredentials() bool {
+	return (s.Tokenvar != "" && s.Tokenvar != "none") || s.Tokenfile != "" || len(s.Tokencommand) != 0 || s.Netrc || s.isGitHubApp()
+}
+
+// isGitHubApp reports whether a source authenticates as a github app installation
+func (s *Source) isGitHubApp() bool {
+	return s.Apitype == "github" && s.AppID != 0
+}
+
+// appTokenSource returns the shared token source for a github app source
+func (s *Source) appTokenSource() (oauth2.TokenSource, error) {
+
+	key := s.credentialKey()
+
+	appTokenSources.Lock()
+	c, exists := appTokenSources.sources[key]
+	if !exists {
+		c = &cachedTokenSource{}
+		appTokenSources.sources[key] = c
+	}
+	appTokenSources.Unlock()
+
+	c.once.Do(func() {
+		keyPEM, err := os.ReadFile(filepath.Clean(expandHome(s.PrivateKeyFile)))
+		if err != nil {
+			c.err = fmt.Errorf("unable to read privatekeyfile %s - %w", s.PrivateKeyFile, err)
+			return
+		}
+		c.ts, c.err = gh.NewAppTokenSource(s.URL, s.AppID, s.InstallationID, keyPEM, s.transport())
+	})
+
+	return c.ts, c.err
+}
+
+// Token returns the credential for a source. An empty string means anonymous access.
+// Github app sources take precedence over any other configured method.
+// The token is resolved on first use and cached, it must never be logged
+func (s *Source) Token() string {
+	if s == nil || !s.hasCredentials() {
+		return ""
+	}
+
+	// Installation tokens expire, so they are cached by the token source rather than for the process lifetime
+	if s.isGitHubApp() {
+		ts, err := s.appTokenSource()
+		if err == nil {
+			var t *oauth2.Token
+			if t, err = ts.Token(); err == nil {
+				return t.AccessToken
+			}
+		}
+		log.Warnf("Unable to get github app token for source %s, using anonymous access - %s", s.Name, err)
+		return ""
+	}
+
+	key := s.credentialKey()
+
+	credentialCache.Lock()
+	c, exists := credentialCache.tokens[key]
+	if !exists {
+		c = &cachedToken{}
+		credentialCache.tokens[key] = c
+	}
+	credentialCache.Unlock()
+
+	c.once.Do(func() {
+		token, err := s.resolveToken()
+		if err != nil {
+			log.Warnf("Unable to resolve credentials for source %s, using anonymous access - %s", s.Name, err)
+		}
+		c.token = token
+	})
+
+	return c.token
+}
+
+// resolveToken will get a token from the first configured method. Precedence is tokenvar, tokenfile, tokencommand then netrc
+func (s *Source) resolveToken() (string, error) {
+	switch {
+	case s.Tokenvar != "" && s.Tokenvar != "none":
+		token := os.Getenv(s.Tokenvar)
+		if token == "" {
+			return "", fmt.Errorf("environment variable %s is empty", s.Tokenvar)
+		}
+		return token, nil
+	case s.Tokenfile != "":
+		return readTokenFile(s.Tokenfile)
+	case len(s.Tokencommand) != 0:
+		return runTokenCommand(s.Tokencommand)
+	case s.Netrc:
+		return netrcToken(netrcPath(), s.host())
+	}
+
+	return "", nil
+}
+
+// expandHome will replace a leading ~ with the users home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	hDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Debugf("Unable to detect home directory %v", err)
+		return path
+	}
+
+	return filepath.Join(hDir, strings.TrimPrefix(path, "~"))
+}
+
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(expandHome(path)))
+	if err != nil {
+		return "", fmt.Errorf("unable to read tokenfile %s - %w", path, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("tokenfile %s is empty", path)
+	}
+
+	return token, nil
+}
+
+// runTokenCommand executes a credential helper such as `gh auth token` and returns its trimmed stdout
+func runTokenCommand(command []string) (string, error) {
+	// #nosec G204 the command is supplied by the user in their own config
+	out, err := exec.Command(command[0], command[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("token command %s failed - %w", command[0], err)
+	}
+
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token command %s returned no output", command[0])
+	}
+
+	return token, nil
+}
+
+// netrcPath returns $NETRC if set, otherwise the default netrc location for the platform
+func netrcPath() string {
+	if p, ok := os.LookupEnv("NETRC"); ok {
+		return p
+	}
+
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+
+	return expandHome(filepath.Join("~", name))
+}
+
+// netrcToken will return the password of the netrc entry matching host. API hosts such as api.github.com will
+// also match an entry for the parent host
+func netrcToken(path, host string) (string, error) {
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("unable to open netrc %s - %w", path, err)
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	var defaultPassword string
+
+	scanner := bufio.NewScanner(f)
+	scanner.Split(bufio.ScanWords)
+
+	var machine string
+	var inDefault, inMacro bool
+
+	for scanner.Scan() {
+		word := scanner.Text()
+
+		// macdef bodies run until a blank line, which ScanWords can not see, so we skip until the next keyword
+		if inMacro && word != "machine" && word != "default" {
+			continue
+		}
+
+		switch word {
+		case "machine":
+			inMacro, inDefault = false, false
+			if scanner.Scan() {
+				machine = scanner.Text()
+			}
+		case "default":
+			inMacro, inDefault, machine = false, true, ""
+		case "macdef":
+			inMacro = true
+		case "password":
+			if !scanner.Scan() {
+				break
+			}
+			switch {
+			case inDefault:
+				defaultPassword = scanner.Text()
+			case machine != "":
+				if _, exists := entries[machine]; !exists {
+					entries[machine] = scanner.Text()
+				}
+			}
+		case "login", "account":
+			// we only need the password, but the value must be consumed
+			scanner.Scan()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("unable to read netrc %s - %w", path, err)
+	}
+
+	hostname := strings.Split(host, ":")[0]
+
+	for _, candidate := range []string{host, hostname, strings.TrimPrefix(hostname, "api.")} {
+		if password, exists := entries[candidate]; exists {
+			return password, nil
+		}
+	}
+
+	if defaultPassword != "" {
+		return defaultPassword, nil
+	}
+
+	return "", fmt.Errorf("%w for %s in %s", ErrNetrcNoMatch, host, path)
+}
@@ -0,0 +1,137 @@
+package binman
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+const testNetrc = `
+machine example.com
+  login someone
+  password examplepassword
+
+macdef init
+  cd /pub
+  password ignored
+
+machine github.com login someone password githubpassword
+default login anon password defaultpassword
+`
+
+func TestNetrcToken(t *testing.T) {
+	d := t.TempDir()
+	netrc := fmt.Sprintf("%s/.netrc", d)
+
+	if err := WriteStringtoFile(netrc, testNetrc); err != nil {
+		t.Fatalf("Unable to write test netrc %s", err)
+	}
+
+	var tests = []struct {
+		host     string
+		expected string
+	}{
+		{"example.com", "examplepassword"},
+		{"api.github.com", "githubpassword"},
+		{"github.com:443", "githubpassword"},
+		{"gitlab.com", "defaultpassword"},
+	}
+
+	for _, test := range tests {
+		got, err := netrcToken(netrc, test.host)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s - %s", test.host, err)
+		}
+		if got != test.expected {
+			t.Fatalf("Expected %s for %s got %s", test.expected, test.host, got)
+		}
+	}
+
+	// Without a default entry unknown hosts return ErrNetrcNoMatch
+	if err := WriteStringtoFile(netrc, "machine example.com password examplepassword\n"); err != nil {
+		t.Fatalf("Unable to write test netrc %s", err)
+	}
+
+	if _, err := netrcToken(netrc, "gitlab.com"); !errors.Is(err, ErrNetrcNoMatch) {
+		t.Fatalf("Expected ErrNetrcNoMatch got %v", err)
+	}
+}
+
+func TestSourceToken(t *testing.T) {
+	d := t.TempDir()
+
+	tokenFile := fmt.Sprintf("%s/token", d)
+	if err := WriteStringtoFile(tokenFile, "filetoken\n"); err != nil {
+		t.Fatalf("Unable to write token file %s", err)
+	}
+
+	netrc := fmt.Sprintf("%s/.netrc", d)
+	if err := WriteStringtoFile(netrc, "machine gitlab.example.com password netrctoken\n"); err != nil {
+		t.Fatalf("Unable to write test netrc %s", err)
+	}
+	t.Setenv("NETRC", netrc)
+	t.Setenv("BINMAN_CRED_TEST", "envtoken")
+
+	var tests = []struct {
+		name     string
+		source   Source
+		expected string
+	}{
+		{"env", Source{Name: "env", URL: "https://env.example.com", Tokenvar: "BINMAN_CRED_TEST"}, "envtoken"},
+		{"envPrecedence", Source{Name: "envPrecedence", URL: "https://env.example.com", Tokenvar: "BINMAN_CRED_TEST", Tokenfile: tokenFile}, "envtoken"},
+		{"file", Source{Name: "file", URL: "https://file.example.com", Tokenvar: "none", Tokenfile: tokenFile}, "filetoken"},
+		{"missingFile", Source{Name: "missingFile", URL: "https://file.example.com", Tokenfile: d + "/dne"}, ""},
+		{"netrc", Source{Name: "netrc", URL: "https://gitlab.example.com", Netrc: true}, "netrctoken"},
+		{"anonymous", Source{Name: "anonymous", URL: "https://anonymous.example.com", Tokenvar: "none"}, ""},
+	}
+
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name     string
+			source   Source
+			expected string
+		}{"command", Source{Name: "command", URL: "https://command.example.com", Tokencommand: []string{"echo", "commandtoken"}}, "commandtoken"})
+	}
+
+	for _, test := range tests {
+		if got := test.source.Token(); got != test.expected {
+			t.Fatalf("%s: expected %q got %q", test.name, test.expected, got)
+		}
+	}
+
+	// Tokens are cached for the process lifetime, so removing the file must not change the result
+	os.Remove(tokenFile)
+	cached := Source{Name: "file", URL: "https://file.example.com", Tokenvar: "none", Tokenfile: tokenFile}
+	if got := cached.Token(); got != "filetoken" {
+		t.Fatalf("Expected cached token got %q", got)
+	}
+}
+
+func TestGlobalTokenvar(t *testing.T) {
+
+	var tests = []struct {
+		name     string
+		source   Source
+		expected string
+	}{
+		{"unset", Source{Name: "github.com", Apitype: "github"}, "GH_TOKEN"},
+		{"tokenfile", Source{Name: "github.com", Apitype: "github", Tokenfile: "~/.config/gh-token"}, ""},
+		{"netrc", Source{Name: "github.com", Apitype: "github", Netrc: true}, ""},
+		{"app", Source{Name: "github.com", Apitype: "github", AppID: 1, InstallationID: 2, PrivateKeyFile: "key.pem"}, ""},
+		{"anonymous", Source{Name: "github.com", Apitype: "github", Tokenvar: "none"}, "none"},
+	}
+
+	for _, test := range tests {
+		c := &BMConfig{}
+		c.Config.TokenVar = "GH_TOKEN"
+		c.Config.Sources = []Source{test.source}
+
+		setDefaultSources(c)
+
+		if got := c.Config.SourceMap["github.com"].Tokenvar; got != test.expected {
+			t.Fatalf("%s: expected tokenvar %q got %q", test.name, test.expected, got)
+		}
+	}
+}
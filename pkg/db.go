@@ -5,6 +5,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,71 +31,85 @@ func dataToBytes(r map[string]any) []byte {
 
 func bytesToData(b []byte) map[string]any {
 
-	dataMap := make(map[string]any)
-
-	decoder := gob.NewDecoder(bytes.NewBuffer(b))
-	err := decoder.Decode(&dataMap)
+	dataMap, err := decodeData(b)
 	if err != nil {
 		log.Fatalf("Unable to decode data from DB %s", err)
 	}
 	return dataMap
 }
 
-// checkDb lets us know if a new db will be created, if an empty string is sent we use os.ConfigDir
-func checkNewDb(path string) bool {
+// decodeData is bytesToData for callers that can recover from bad data
+func decodeData(b []byte) (map[string]any, error) {
 
-	var err error
+	dataMap := make(map[string]any)
 
-	if path == "" {
-		path, err = os.UserConfigDir()
-		if err != nil {
-			log.Fatalf("Unable to find userConfigDir")
-		}
+	decoder := gob.NewDecoder(bytes.NewBuffer(b))
+	if err := decoder.Decode(&dataMap); err != nil {
+		return nil, err
 	}
+	return dataMap, nil
+}
+
+// checkDb lets us know if a new db will be created. path is the db file, if an empty string is sent the default db is checked
+func checkNewDb(path string) bool {
 
-	dbPath := fmt.Sprintf("%s/binman/binman.db", path)
+	_, err := os.Stat(db.Path(path))
 
-	_, err = os.Stat(dbPath)
-	if os.IsNotExist(err) {
-		return true
-	}
+	return os.IsNotExist(err)
+}
 
-	return false
+// versionBucket is the bucket of a stored version and the source it was synced from
+type versionBucket struct {
+	source string
+	*bolt.Bucket
 }
 
-func getVersionBuckets(tx *bolt.Tx) ([]*bolt.Bucket, error) {
+// getVersionBuckets returns every stored version. Versions are the buckets holding data at any depth below their source,
+// so gitlab subgroup repos are found as well
+func getVersionBuckets(tx *bolt.Tx) ([]versionBucket, error) {
+
+	var buckets []versionBucket
 
-	var buckets []*bolt.Bucket
+	var walk func(source string, b *bolt.Bucket) error
+	walk = func(source string, b *bolt.Bucket) error {
+		if b.Get([]byte("data")) != nil {
+			buckets = append(buckets, versionBucket{source, b})
+			return nil
+		}
+		return b.ForEachBucket(func(key []byte) error {
+			return walk(source, b.Bucket(key))
+		})
+	}
 
 	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		// cached api responses are not versions
+		if string(name) == apiCacheBucket {
+			return nil
+		}
 		log.Debugf("scanning source = %s", name)
-		return b.ForEachBucket(func(orgKey []byte) error {
-			b2 := b.Bucket(orgKey)
-			if b2 == nil {
-				return fmt.Errorf("missing org bucket %s/%s", name, orgKey)
-			}
-			return b2.ForEachBucket(func(projKey []byte) error {
-				b3 := b2.Bucket(projKey)
-				if b3 == nil {
-					return fmt.Errorf("missing project bucket %s/%s/%s", name, orgKey, projKey)
-				}
-				return b3.ForEachBucket(func(versionKey []byte) error {
-					versionBucket := b3.Bucket(versionKey)
-					if versionBucket == nil {
-						return fmt.Errorf("missing version bucket %s/%s/%s/%s", name, orgKey, projKey, versionKey)
-					}
-					buckets = append(buckets, versionBucket)
-					return nil
-				})
-			})
-		})
+		return walk(string(name), b)
 	})
 
 	return buckets, err
 }
 
+// projectBucket returns the bucket holding the versions of a release, or nil if none are stored. Keys are split on every /,
+// so gitlab subgroup repos are nested one bucket per group
+func (r *BinmanRelease) projectBucket(tx *bolt.Tx) *bolt.Bucket {
+
+	b := tx.Bucket([]byte(r.SourceIdentifier))
+	for _, key := range strings.Split(r.Repo, "/") {
+		if b == nil {
+			return nil
+		}
+		b = b.Bucket([]byte(key))
+	}
+
+	return b
+}
+
 // OutputDbStatus will output all keys and values
-func OutputDbStatus() error {
+func OutputDbStatus(sel ReleaseSelector, config string) error {
 
 	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
 	columnFmt := color.New(color.FgYellow).SprintfFunc()
@@ -106,6 +121,16 @@ func OutputDbStatus() error {
 		log.Fatalf("DB has not been initialized yet. Run `binman` first")
 	}
 
+	// Selectors are resolved against the config since labels are not stored in the db
+	var selected map[string]bool
+	if !sel.IsEmpty() {
+		selected = make(map[string]bool)
+		// Repos are only unique within a source, github.com/org/proj and gitlab.com/org/proj are different releases
+		for _, rel := range NewBMConfig(config).SetConfig(false).Select(sel).Releases {
+			selected[rel.SourceIdentifier+"/"+rel.Repo] = true
+		}
+	}
+
 	bdb := db.GetDB("", bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
 
 	viewErr := bdb.View(func(tx *bolt.Tx) error {
@@ -114,16 +139,10 @@ func OutputDbStatus() error {
 			return err
 		}
 		for _, bucket := range buckets {
-			if bucket == nil {
-				log.Warnf("Skipping nil version bucket")
-				continue
-			}
-			data := bucket.Get([]byte("data"))
-			if data == nil {
-				log.Warnf("Skipping version bucket with no data")
+			dataMap := bytesToData(bucket.Get([]byte("data")))
+			if repo, _ := dataMap["repo"].(string); selected != nil && !selected[bucket.source+"/"+repo] {
 				continue
 			}
-			dataMap := bytesToData(data)
 			// Releases added via populate will not have createdAt/artifactPath populated
 			// This will show us as 1969 unless we handle the like this
 			createdAt, ok := dataMap["createdAt"].(int64)
@@ -159,6 +178,7 @@ func populateDB(dbOptions db.DbConfig, config string) error {
 
 	// Start the DB direct. If we use WithDB we will create recursively loops of populateDB calls
 	go db.RunDB(dbOptions)
+	defer close(dbOptions.DbChan)
 
 	log.Debugf("Updating binman db from filesystem")
 
@@ -189,14 +209,12 @@ func populateDB(dbOptions db.DbConfig, config string) error {
 			close(msg.ReturnChan)
 			m := <-msg.ReturnChan
 			if m.Err != nil && !errors.Is(m.Err, db.ErrKeyExists) {
-				log.Fatalf("Issue writing data to db %v", m.Err)
+				return fmt.Errorf("issue writing %s(%s) to db - %w", rel.Repo, rel.Version, m.Err)
 			}
 
 		}
 	}
 
-	close(dbOptions.DbChan)
-
 	log.Debugf("DB Update complete")
 	return nil
 }
@@ -22,14 +22,7 @@ func GetDB(dbPath string, o ...bolt.Options) *bolt.DB {
 
 	log.Debugf("Starting db with options %+v", boltOptions)
 
-	configPath, err := os.UserConfigDir()
-	if err != nil {
-		log.Fatalf("Unable to find userConfigDir")
-	}
-
-	if dbPath == "" {
-		dbPath = fmt.Sprintf("%s/binman/binman.db", configPath)
-	}
+	dbPath = Path(dbPath)
 
 	createDBFileIfNotExists(dbPath)
 
@@ -43,6 +36,21 @@ func GetDB(dbPath string, o ...bolt.Options) *bolt.DB {
 	return db
 }
 
+// Path returns the db file used for dbPath. An empty dbPath is the default db in the user config dir
+func Path(dbPath string) string {
+
+	if dbPath != "" {
+		return dbPath
+	}
+
+	configPath, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatalf("Unable to find userConfigDir")
+	}
+
+	return fmt.Sprintf("%s/binman/binman.db", configPath)
+}
+
 func createDBFileIfNotExists(dbPath string) {
 	dir := filepath.Dir(dbPath)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
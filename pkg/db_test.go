@@ -3,12 +3,14 @@ package binman
 import (
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 
 	db "github.com/rjbrown57/binman/pkg/db"
 	log "github.com/rjbrown57/binman/pkg/logging"
 	"github.com/rjbrown57/binman/pkg/templating"
+	bolt "go.etcd.io/bbolt"
 )
 
 // if user does not provide a -c this will be populated at ~/.config/binman/config
@@ -101,3 +103,39 @@ func TestPopulateData(t *testing.T) {
 		}
 	}
 }
+
+func TestGetVersionBuckets(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	dir := t.TempDir()
+
+	testDb := db.GetDB(fmt.Sprintf("%s/binman.db", dir))
+	defer testDb.Close()
+
+	// The same repo from two sources and a gitlab subgroup repo nested one bucket deeper
+	keys := []string{"github.com/org/proj/v1.0.0", "gitlab.com/org/proj/v1.0.0", "gitlab.com/group/sub/tool/v1.0.0"}
+	for _, k := range keys {
+		_, repo, _ := strings.Cut(k, "/")
+		if err := db.WriteData(true, k+"/data", dataToBytes(map[string]any{"repo": repo}), testDb); err != nil {
+			t.Fatalf("Unable to write %s - %s", k, err)
+		}
+	}
+
+	got := make(map[string]bool)
+	if err := testDb.View(func(tx *bolt.Tx) error {
+		buckets, err := getVersionBuckets(tx)
+		for _, b := range buckets {
+			got[b.source+"/"+bytesToData(b.Get([]byte("data")))["repo"].(string)] = true
+		}
+		return err
+	}); err != nil {
+		t.Fatalf("Unable to get version buckets - %s", err)
+	}
+
+	for _, k := range keys {
+		if !got[k] {
+			t.Fatalf("Expected %s got %v", k, got)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package binman
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rodaine/table"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Doctor finding severities
+const (
+	DoctorInfo  = "info"
+	DoctorWarn  = "warn"
+	DoctorError = "error"
+)
+
+// Doctor checks
+const (
+	checkPath       = "path"       // binpath is missing from $PATH
+	checkDb         = "db"         // the db has not been created
+	checkBrokenLink = "brokenlink" // a link points at a version that is gone
+	checkMissing    = "missing"    // a db version whose publishPath is gone
+	checkIncomplete = "incomplete" // a version directory that was never finished
+	checkUntracked  = "untracked"  // a version directory the db does not know about
+)
+
+// Finding is a single problem found by Doctor
+type Finding struct {
+	Severity string
+	Repo     string
+	Check    string
+	Message  string
+	Fixed    bool
+}
+
+// Doctor checks that links, the db and the versions stored on disk agree with each other. If fix is set, links are pointed at
+// the newest valid version, orphaned versions are pruned and the db is rebuilt from disk
+func Doctor(fix bool, dbPath, config string, sel ReleaseSelector) ([]Finding, error) {
+
+	c := NewBMConfig(config).SetConfig(false).Select(sel)
+
+	findings := checkBinPaths(c.Releases, filepath.SplitList(os.Getenv("PATH")))
+
+	var dwg sync.WaitGroup
+	dbOptions := db.DbConfig{
+		Path:   dbPath,
+		Dwg:    &dwg,
+		DbChan: make(chan db.DbMsg),
+	}
+
+	if _, err := os.Stat(db.Path(dbPath)); os.IsNotExist(err) {
+		f := Finding{Severity: DoctorInfo, Check: checkDb, Message: fmt.Sprintf("db %s has not been created", db.Path(dbPath))}
+		if !fix {
+			return append(findings, f), nil
+		}
+		if err := populateDB(dbOptions, config); err != nil {
+			return findings, err
+		}
+		f.Fixed = true
+		findings = append(findings, f)
+	}
+
+	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: !fix})
+
+	var errs []error
+	untracked := false
+
+	for index := range c.Releases {
+		relFindings, err := c.Releases[index].doctor(fix, bdb)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Releases[index].Repo, err))
+		}
+		for _, f := range relFindings {
+			if f.Check == checkUntracked && fix {
+				untracked = true
+			}
+		}
+		findings = append(findings, relFindings...)
+	}
+
+	if err := bdb.Close(); err != nil {
+		log.Fatalf("Unable to close db %s", err)
+	}
+
+	// Versions the db doesn't know about are added by a scan of the filesystem
+	if untracked {
+		dbOptions.DbChan = make(chan db.DbMsg)
+		if err := populateDB(dbOptions, config); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return findings, errors.Join(errs...)
+}
+
+// checkBinPaths reports each binpath that is not in path
+func checkBinPaths(releases []BinmanRelease, path []string) []Finding {
+
+	var findings []Finding
+	var seen []string
+
+	for _, rel := range releases {
+		binPath := filepath.Clean(rel.BinPath)
+		if rel.BinPath == "" || slices.Contains(seen, binPath) {
+			continue
+		}
+		seen = append(seen, binPath)
+
+		if !slices.ContainsFunc(path, func(p string) bool { return filepath.Clean(p) == binPath }) {
+			findings = append(findings, Finding{Severity: DoctorWarn, Check: checkPath, Message: fmt.Sprintf("%s is not in $PATH", rel.BinPath)})
+		}
+	}
+
+	return findings
+}
+
+// storedVersionData returns the db data of each version stored for a release
+func (r *BinmanRelease) storedVersionData(bdb *bolt.DB) (map[string]map[string]any, error) {
+
+	data := make(map[string]map[string]any)
+
+	err := bdb.View(func(tx *bolt.Tx) error {
+		b := r.projectBucket(tx)
+		if b == nil {
+			return nil
+		}
+		return b.ForEachBucket(func(k []byte) error {
+			if d := b.Bucket(k).Get([]byte("data")); d != nil {
+				decoded, err := decodeData(d)
+				if err != nil {
+					return fmt.Errorf("unable to decode %s/%s/%s: %w", r.SourceIdentifier, r.Repo, k, err)
+				}
+				data[string(k)] = decoded
+			}
+			return nil
+		})
+	})
+
+	return data, err
+}
+
+// doctor checks a single release
+func (r *BinmanRelease) doctor(fix bool, bdb *bolt.DB) ([]Finding, error) {
+
+	var findings []Finding
+
+	report := func(severity, check, format string, a ...any) *Finding {
+		findings = append(findings, Finding{Severity: severity, Repo: r.Repo, Check: check, Message: fmt.Sprintf(format, a...)})
+		return &findings[len(findings)-1]
+	}
+
+	data, err := r.storedVersionData(bdb)
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions in the db must still be on disk
+	for version, d := range data {
+		publishPath, _ := d["publishPath"].(string)
+		artifactPath, _ := d["artifactPath"].(string)
+
+		var f *Finding
+		switch {
+		case !pathExists(publishPath):
+			f = report(DoctorError, checkMissing, "%s is in the db but %s is gone", version, publishPath)
+		case artifactPath != "" && !pathExists(artifactPath):
+			f = report(DoctorError, checkIncomplete, "%s is missing %s", version, artifactPath)
+		default:
+			continue
+		}
+
+		if fix {
+			if err := os.RemoveAll(publishPath); err != nil {
+				return findings, err
+			}
+			if err := db.DeleteData(fmt.Sprintf("%s/%s/%s", r.SourceIdentifier, r.Repo, version), bdb); err != nil {
+				return findings, err
+			}
+			f.Fixed = true
+		}
+		delete(data, version)
+	}
+
+	// Versions on disk must be in the db
+	entries, _ := os.ReadDir(r.repoPath())
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, ok := data[e.Name()]; ok {
+			continue
+		}
+
+		versionPath := filepath.Join(r.repoPath(), e.Name())
+
+		// A download that failed before anything was extracted leaves an empty version directory behind
+		if contents, _ := os.ReadDir(versionPath); len(contents) == 0 {
+			f := report(DoctorWarn, checkIncomplete, "%s is an empty version directory", versionPath)
+			if fix {
+				if err := os.Remove(versionPath); err != nil {
+					return findings, err
+				}
+				f.Fixed = true
+			}
+			continue
+		}
+
+		f := report(DoctorWarn, checkUntracked, "%s is on disk but not in the db", e.Name())
+		f.Fixed = fix
+	}
+
+	// Links must point at something
+	links, err := r.releaseLinks(bdb)
+	if err != nil {
+		return findings, err
+	}
+
+	for _, link := range links {
+		if _, err := os.Stat(link); err == nil {
+			continue
+		}
+
+		f := report(DoctorError, checkBrokenLink, "%s points at a version that is gone", link)
+		if !fix {
+			continue
+		}
+
+		target, err := r.relinkTarget(data, bdb)
+		if err != nil {
+			return findings, err
+		}
+
+		if target == "" {
+			log.Infof("%s has no valid version to link, removing %s", r.Repo, link)
+			err = os.Remove(link)
+		} else {
+			log.Infof("Linking %s to %s", link, target)
+			err = createLink(target, link)
+		}
+		if err != nil {
+			return findings, err
+		}
+		f.Fixed = true
+	}
+
+	return findings, nil
+}
+
+// relinkTarget returns the artifact a broken link should point at. The rollback version is used if one is set, otherwise the newest
+// version with an artifact on disk
+func (r *BinmanRelease) relinkTarget(data map[string]map[string]any, bdb *bolt.DB) (string, error) {
+
+	var versions []string
+	for v, d := range data {
+		if artifactPath, _ := d["artifactPath"].(string); artifactPath != "" && pathExists(artifactPath) {
+			versions = append(versions, v)
+		}
+	}
+
+	if len(versions) == 0 {
+		return "", nil
+	}
+
+	rollback, err := db.GetData(r.rollbackKey(), bdb)
+	if err != nil && !errors.Is(err, db.ErrNilReadResponse) {
+		return "", err
+	}
+
+	target := string(rollback)
+	if !slices.Contains(versions, target) {
+		if versions, err = sortVersions(r.VersionScheme, versions, func(v string) time.Time { return dataCreated(data[v]) }); err != nil {
+			return "", err
+		}
+		target = versions[len(versions)-1]
+	}
+
+	return data[target]["artifactPath"].(string), nil
+}
+
+// pathExists returns true if path is present on disk
+func pathExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// OutputFindings prints the findings of Doctor
+func OutputFindings(findings []Finding) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	findingsTable := table.New("Severity", "Repo", "Check", "Message", "Fixed")
+	findingsTable.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, f := range findings {
+		findingsTable.AddRow(f.Severity, f.Repo, f.Check, f.Message, f.Fixed)
+	}
+
+	findingsTable.Print()
+}
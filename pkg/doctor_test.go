@@ -0,0 +1,148 @@
+package binman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+func findingChecks(findings []Finding) map[string]int {
+	checks := make(map[string]int)
+	for _, f := range findings {
+		checks[f.Check]++
+	}
+	return checks
+}
+
+func TestDoctor(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testCleanConfig, "doctor")
+	defer os.RemoveAll(releasePath)
+
+	t.Setenv("PATH", releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	linkPath := writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3"})
+
+	repoPath := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1")
+
+	// The linked version is deleted, a download was left unfinished and a version was copied in by hand
+	if err := os.RemoveAll(filepath.Join(repoPath, "v0.0.3")); err != nil {
+		t.Fatalf("Unable to remove v0.0.3")
+	}
+	if err := os.MkdirAll(filepath.Join(repoPath, "v0.0.4"), 0755); err != nil {
+		t.Fatalf("Unable to create v0.0.4")
+	}
+	if err := os.MkdirAll(filepath.Join(repoPath, "v0.0.0"), 0755); err != nil {
+		t.Fatalf("Unable to create v0.0.0")
+	}
+	if err := WriteStringtoFile(filepath.Join(repoPath, "v0.0.0", "repo1"), "v0.0.0"); err != nil {
+		t.Fatalf("Unable to write v0.0.0")
+	}
+
+	findings, err := Doctor(false, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Doctor failed - %s", err)
+	}
+
+	expected := map[string]int{checkMissing: 1, checkIncomplete: 1, checkUntracked: 1, checkBrokenLink: 1}
+	got := findingChecks(findings)
+	for check, n := range expected {
+		if got[check] != n {
+			t.Fatalf("Expected %d %s findings got %+v", n, check, findings)
+		}
+	}
+	if len(findings) != 4 {
+		t.Fatalf("Expected 4 findings got %+v", findings)
+	}
+
+	if _, err := Doctor(true, dbPath, testConfig, ReleaseSelector{}); err != nil {
+		t.Fatalf("Doctor fix failed - %s", err)
+	}
+
+	if got := linkedVersion(t, linkPath); got != "v0.0.2" {
+		t.Fatalf("Expected link to v0.0.2 got %s", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoPath, "v0.0.4")); !os.IsNotExist(err) {
+		t.Fatalf("Expected empty version directory to be pruned")
+	}
+
+	bdb := db.GetDB(dbPath)
+	if _, err := db.GetData("github.com/org1/repo1/v0.0.0/data", bdb); err != nil {
+		t.Fatalf("Expected v0.0.0 to be added to the db - %s", err)
+	}
+	if _, err := db.GetData("github.com/org1/repo1/v0.0.3/data", bdb); err == nil {
+		t.Fatalf("Expected v0.0.3 to be removed from the db")
+	}
+	bdb.Close()
+
+	findings, err = Doctor(false, dbPath, testConfig, ReleaseSelector{})
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Expected no findings after fix got %+v - %v", findings, err)
+	}
+}
+
+func TestCheckBinPaths(t *testing.T) {
+
+	releases := []BinmanRelease{{Repo: "org/a", BinPath: "/opt/bin"}, {Repo: "org/b", BinPath: "/opt/bin/"}, {Repo: "org/c", BinPath: "/usr/local/bin"}}
+
+	findings := checkBinPaths(releases, []string{"/usr/local/bin/", "/usr/bin"})
+	if len(findings) != 1 || findings[0].Check != checkPath {
+		t.Fatalf("Expected /opt/bin to be missing from path got %+v", findings)
+	}
+}
+
+func TestDoctorSubgroup(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testDuSubgroupConfig, "doctorsubgroup")
+	defer os.RemoveAll(releasePath)
+
+	t.Setenv("PATH", releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	linkPath := filepath.Join(releasePath, "tool")
+
+	testDb := db.GetDB(dbPath)
+	for _, v := range []string{"v1.0.0", "v1.0.1"} {
+		publishPath := filepath.Join(releasePath, "repos", "gitlab.com", "group", "sub", "tool", v)
+		artifactPath := filepath.Join(publishPath, "tool")
+		if err := os.MkdirAll(publishPath, 0755); err != nil {
+			t.Fatalf("Unable to create %s", publishPath)
+		}
+		if err := WriteStringtoFile(artifactPath, v); err != nil {
+			t.Fatalf("Unable to write %s", artifactPath)
+		}
+
+		data := map[string]any{"repo": "group/sub/tool", "version": v, "publishPath": publishPath, "artifactPath": artifactPath, "linkPath": linkPath}
+		if err := db.WriteData(true, "gitlab.com/group/sub/tool/"+v+"/data", dataToBytes(data), testDb); err != nil {
+			t.Fatalf("Unable to write %s to db - %s", v, err)
+		}
+
+		if err := createLink(artifactPath, linkPath); err != nil {
+			t.Fatalf("Unable to link %s - %s", artifactPath, err)
+		}
+	}
+	testDb.Close()
+
+	// Versions of subgroup repos are nested one bucket per group and must still be found
+	findings, err := Doctor(false, dbPath, testConfig, ReleaseSelector{})
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("Expected no findings got %+v - %v", findings, err)
+	}
+
+	if err := Rollback("group/sub/tool", "", false, dbPath, testConfig); err != nil {
+		t.Fatalf("Rollback failed - %s", err)
+	}
+
+	if got := linkedVersion(t, linkPath); got != "v1.0.0" {
+		t.Fatalf("Expected link to v1.0.0 got %s", got)
+	}
+}
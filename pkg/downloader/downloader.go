@@ -1,39 +1,145 @@
 package downloader
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	log "github.com/rjbrown57/binman/pkg/logging"
 )
 
+// progressInterval limits how often an in flight download reports progress
+const progressInterval = 250 * time.Millisecond
+
+// transport fetches http(s) urls. file:// urls are only served for a download that sets FileRoot
+var transport = http.DefaultTransport.(*http.Transport).Clone()
+
+// fileTransport serves file:// urls from below root and hands every other url to next, so local sources share the download path
+type fileTransport struct {
+	root string
+	next http.RoundTripper
+}
+
+func (f *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "file" {
+		return f.next.RoundTrip(req)
+	}
+
+	rel, err := filepath.Rel(f.root, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is outside of %s", req.URL.Path, f.root)
+	}
+
+	r := req.Clone(req.Context())
+	r.URL.Path = "/" + filepath.ToSlash(rel)
+
+	return http.NewFileTransport(http.Dir(f.root)).RoundTrip(r)
+}
+
 // dlMsg is used to communicate with downloader pool
 type DlMsg struct {
-	Url         string
-	Filepath    string
-	Wg          *sync.WaitGroup
-	ConfirmChan chan error
-	DlAuth      *DlAuth
+	Url          string
+	Filepath     string
+	Name         string // Display name used when reporting progress
+	Accept       string // Optional Accept header. Github release asset api requests require application/octet-stream
+	Wg           *sync.WaitGroup
+	ConfirmChan  chan error
+	DlAuth       *DlAuth
+	ProgressChan chan Progress     // Optional channel to report download progress on
+	Transport    http.RoundTripper // Optional transport of the source that owns the download. e.g for a custom CA or proxy
+	FileRoot     string            // Directory file:// urls may be read from. Only set for sources with apitype file, file urls are refused otherwise
 }
 
-func (d *DlMsg) DownloadFile() error {
+// Progress reports the state of a single download
+type Progress struct {
+	Name    string
+	Current int64 // bytes written so far
+	Total   int64 // total bytes expected, -1 if the server did not supply a length
+	Done    bool
+	Err     error
+}
+
+// progressReader wraps a response body and reports bytes read to a progress channel
+type progressReader struct {
+	r        io.Reader
+	msg      *DlMsg
+	current  int64
+	total    int64
+	lastSent time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.current += int64(n)
+
+	if time.Since(p.lastSent) >= progressInterval {
+		p.lastSent = time.Now()
+		// Intermediate updates are best effort, we never want to stall a download on output
+		select {
+		case p.msg.ProgressChan <- p.progress(false, nil):
+		default:
+		}
+	}
+
+	return n, err
+}
+
+func (p *progressReader) progress(done bool, err error) Progress {
+	return Progress{Name: p.msg.Name, Current: p.current, Total: p.total, Done: done, Err: err}
+}
+
+func (d *DlMsg) DownloadFile() (err error) {
 	log.Debugf("Downloading %s", d.Url)
 
-	c := http.Client{}
+	var rt http.RoundTripper = transport
+	if d.Transport != nil {
+		rt = d.Transport
+	}
+
+	if d.FileRoot != "" {
+		rt = &fileTransport{root: d.FileRoot, next: rt}
+	}
+
+	c := http.Client{
+		Transport: rt,
+		// Headers are copied to redirected requests, so credentials must be removed if we leave the owning host
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			// A remote server must never be able to point a download at the local filesystem
+			if req.URL.Scheme == "file" {
+				return fmt.Errorf("refusing redirect to %s", req.URL)
+			}
+			d.DlAuth.apply(req)
+			return nil
+		},
+	}
 
 	r, err := http.NewRequest(http.MethodGet, d.Url, nil)
 	if err != nil {
 		log.Debugf("%s", err)
+		return err
 	}
 
-	if d.DlAuth != nil {
-		r.Header.Set(d.DlAuth.Header, fmt.Sprintf("Bearer %s", d.DlAuth.Token))
+	if r.URL.Scheme == "file" && d.FileRoot == "" {
+		return fmt.Errorf("refusing to download %s, file urls are only allowed for file sources", d.Url)
 	}
 
+	if d.Accept != "" {
+		r.Header.Set("Accept", d.Accept)
+	}
+
+	d.DlAuth.apply(r)
+
 	resp, err := c.Do(r)
 	if err != nil {
 		log.Debugf("%+v %v", resp, err)
@@ -53,7 +159,16 @@ func (d *DlMsg) DownloadFile() error {
 
 	defer out.Close()
 
-	_, err = io.Copy(io.MultiWriter(out), resp.Body)
+	var body io.Reader = resp.Body
+
+	if d.ProgressChan != nil {
+		pr := &progressReader{r: resp.Body, msg: d, total: resp.ContentLength}
+		body = pr
+		// The final update is always delivered so the caller can retire this download
+		defer func() { d.ProgressChan <- pr.progress(true, err) }()
+	}
+
+	_, err = io.Copy(io.MultiWriter(out), body)
 	if err != nil {
 		return err
 	}
@@ -62,26 +177,59 @@ func (d *DlMsg) DownloadFile() error {
 	return nil
 }
 
+// DlAuth contains the credential for a download. Credentials are scoped to a single host
+// and will never be sent to any other host, including hosts we are redirected to
 type DlAuth struct {
 	Token  string
 	Header string
+	Scheme string // Prefix for the header value e.g "Bearer". If empty the raw token is sent
+	Host   string // Host the credential belongs to
 }
 
-// NewDlAauth will return a bearer token auth header if token is not nil
-func NewDlAuth(token, header string) *DlAuth {
+// NewDlAauth will return an auth header scoped to host if token is not empty
+func NewDlAuth(token, header, scheme, host string) *DlAuth {
 
-	if token == "" {
+	if token == "" || host == "" {
 		return nil
 	}
 
 	d := DlAuth{
 		Token:  token,
 		Header: http.CanonicalHeaderKey(header),
+		Scheme: scheme,
+		Host:   host,
 	}
 
 	return &d
 }
 
+// matches reports whether u belongs to the host that owns the credential
+func (a *DlAuth) matches(u *url.URL) bool {
+	return strings.EqualFold(u.Host, a.Host) || strings.EqualFold(u.Hostname(), a.Host)
+}
+
+// apply sets the auth header on req if the request host owns the credential, otherwise it ensures the header is absent
+func (a *DlAuth) apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+
+	if !a.matches(req.URL) {
+		if req.Header.Get(a.Header) != "" {
+			log.Debugf("Removing credentials from request to %s", req.URL.Host)
+		}
+		req.Header.Del(a.Header)
+		return
+	}
+
+	value := a.Token
+	if a.Scheme != "" {
+		value = fmt.Sprintf("%s %s", a.Scheme, a.Token)
+	}
+
+	req.Header.Set(a.Header, value)
+}
+
 func GetDownloader(downloadChan chan DlMsg, id int) {
 	log.Tracef("Downloader %d started", id)
 	for msg := range downloadChan {
@@ -2,7 +2,11 @@ package downloader
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -42,18 +46,152 @@ func TestNewDlAuth(t *testing.T) {
 		Got      *DlAuth
 		Token    string
 		Header   string
+		Host     string
 	}{
-		{Expected: nil, Token: "", Header: "Authorization"},
-		{Expected: nil, Token: "asdf", Header: "Authorization"},
+		{Expected: nil, Token: "", Header: "Authorization", Host: "api.github.com"},
+		{Expected: nil, Token: "asdf", Header: "Authorization", Host: ""},
+		{Expected: &DlAuth{Token: "asdf", Header: "Authorization", Scheme: "Bearer", Host: "api.github.com"}, Token: "asdf", Header: "authorization", Host: "api.github.com"},
 	}
 
 	for _, test := range tests {
-		test.Got = NewDlAuth(test.Token, test.Header)
+		test.Got = NewDlAuth(test.Token, test.Header, "Bearer", test.Host)
+		if (test.Got == nil) != (test.Expected == nil) {
+			t.Fatalf("Expected %+v got %+v", test.Expected, test.Got)
+		}
 		if test.Got != nil && test.Expected != nil {
-			if reflect.DeepEqual(*test.Got, *test.Expected) {
+			if !reflect.DeepEqual(*test.Got, *test.Expected) {
 				t.Fatalf("%s does not = %s", test.Got, test.Expected)
 			}
 		}
 
 	}
 }
+
+func TestDownloadFileProgress(t *testing.T) {
+	var body = strings.Repeat("binman", 1024)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(body)))
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	d := t.TempDir()
+
+	progChan := make(chan Progress, 10)
+	dlMsg := DlMsg{Url: srv.URL, Filepath: fmt.Sprintf("%s/testString", d), Name: "test", ProgressChan: progChan}
+
+	if err := dlMsg.DownloadFile(); err != nil {
+		t.Fatalf("Issue downloading %s - %s", srv.URL, err)
+	}
+
+	close(progChan)
+
+	var last Progress
+	for p := range progChan {
+		last = p
+	}
+
+	if !last.Done || last.Name != "test" || last.Current != int64(len(body)) || last.Total != int64(len(body)) {
+		t.Fatalf("Unexpected final progress %+v", last)
+	}
+}
+
+func TestDownloadFileAuthScope(t *testing.T) {
+	var otherHostAuth string
+
+	// other stands in for a third party host, such as the storage a github asset redirects to
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHostAuth = r.Header.Get("PRIVATE-TOKEN")
+		fmt.Fprint(w, "binman")
+	}))
+	defer other.Close()
+
+	var ownerAuth, ownerAccept string
+
+	owner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ownerAuth = r.Header.Get("PRIVATE-TOKEN")
+		ownerAccept = r.Header.Get("Accept")
+		http.Redirect(w, r, other.URL, http.StatusFound)
+	}))
+	defer owner.Close()
+
+	ownerUrl, _ := url.Parse(owner.URL)
+
+	d := t.TempDir()
+
+	dlMsg := DlMsg{
+		Url:      owner.URL,
+		Filepath: fmt.Sprintf("%s/testString", d),
+		Accept:   "application/octet-stream",
+		DlAuth:   NewDlAuth("secret", "PRIVATE-TOKEN", "", ownerUrl.Host),
+	}
+
+	if err := dlMsg.DownloadFile(); err != nil {
+		t.Fatalf("Issue downloading %s - %s", owner.URL, err)
+	}
+
+	if ownerAuth != "secret" || ownerAccept != "application/octet-stream" {
+		t.Fatalf("Expected credentials and accept header to be sent to owning host, got %q %q", ownerAuth, ownerAccept)
+	}
+
+	if otherHostAuth != "" {
+		t.Fatalf("Credentials leaked to redirected host")
+	}
+
+	// A direct request to a host that does not own the credential should never carry it
+	dlMsg.Url = other.URL
+	if err := dlMsg.DownloadFile(); err != nil {
+		t.Fatalf("Issue downloading %s - %s", other.URL, err)
+	}
+
+	if otherHostAuth != "" {
+		t.Fatalf("Credentials sent to non owning host")
+	}
+}
+
+func TestDownloadFileUrls(t *testing.T) {
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "asset"), []byte("local"), 0600); err != nil {
+		t.Fatalf("Unable to write asset")
+	}
+
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Unable to write secret")
+	}
+
+	d := t.TempDir()
+	assetUrl := "file://" + filepath.ToSlash(filepath.Join(root, "asset"))
+
+	// file urls are refused unless the download belongs to a file source
+	dlMsg := DlMsg{Url: assetUrl, Filepath: filepath.Join(d, "asset")}
+	if err := dlMsg.DownloadFile(); err == nil {
+		t.Fatalf("Expected %s to be refused without a file root", assetUrl)
+	}
+
+	dlMsg.FileRoot = root
+	if err := dlMsg.DownloadFile(); err != nil {
+		t.Fatalf("Issue downloading %s - %s", assetUrl, err)
+	}
+	if b, _ := os.ReadFile(dlMsg.Filepath); string(b) != "local" {
+		t.Fatalf("Expected local got %s", b)
+	}
+
+	dlMsg.Url = "file://" + filepath.ToSlash(outside)
+	if err := dlMsg.DownloadFile(); err == nil {
+		t.Fatalf("Expected %s outside of %s to be refused", outside, root)
+	}
+
+	// Redirects to the filesystem are refused even for file sources
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, assetUrl, http.StatusFound)
+	}))
+	defer srv.Close()
+
+	dlMsg.Url = srv.URL
+	if err := dlMsg.DownloadFile(); err == nil {
+		t.Fatalf("Expected a redirect to %s to be refused", assetUrl)
+	}
+}
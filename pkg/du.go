@@ -0,0 +1,364 @@
+package binman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rodaine/table"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Disk usage sort orders
+const (
+	DuSortSize = "size" // largest first
+	DuSortName = "name"
+)
+
+// Disk usage report levels
+const (
+	DuLevelSource  = "source"
+	DuLevelOrg     = "org"
+	DuLevelRepo    = "repo"
+	DuLevelVersion = "version"
+)
+
+// DuArchive is a downloaded archive left in a version directory because cleanup is off
+type DuArchive struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+// DuVersion is the disk usage of a single stored version
+type DuVersion struct {
+	Version     string      `json:"version"`
+	Path        string      `json:"path"`
+	Bytes       int64       `json:"bytes"`
+	Linked      bool        `json:"linked,omitempty"`
+	Untracked   bool        `json:"untracked,omitempty"`   // on disk but not in the db
+	Reclaimable string      `json:"reclaimable,omitempty"` // why clean would remove the version
+	Archives    []DuArchive `json:"archives,omitempty"`
+}
+
+// DuRepo is the disk usage of a release
+type DuRepo struct {
+	Source       string      `json:"source"`
+	Org          string      `json:"org"`
+	Repo         string      `json:"repo"`
+	Configured   bool        `json:"configured"` // repos left on disk after being removed from the config are not cleaned
+	Bytes        int64       `json:"bytes"`
+	Reclaimable  int64       `json:"reclaimable"`
+	ArchiveBytes int64       `json:"archiveBytes"`
+	Versions     []DuVersion `json:"versions"`
+}
+
+// DuTotal is the disk usage of a source or org
+type DuTotal struct {
+	Name        string `json:"name"`
+	Bytes       int64  `json:"bytes"`
+	Reclaimable int64  `json:"reclaimable"`
+}
+
+// DuReport is the disk usage of the release store
+type DuReport struct {
+	Bytes        int64     `json:"bytes"`
+	Reclaimable  int64     `json:"reclaimable"` // what binman clean would free with the same threshold and the configured retention
+	ArchiveBytes int64     `json:"archiveBytes"`
+	Sources      []DuTotal `json:"sources"`
+	Orgs         []DuTotal `json:"orgs"`
+	Repos        []DuRepo  `json:"repos"`
+}
+
+// DiskUsage reports the space used by the release store. Reclaimable space is what binman clean would free using threshold and the
+// configured retention. Version directories missing from the db only count when orphans is set, as they do for clean
+func DiskUsage(threshold int, orphans bool, sortBy string, dbPath, config string, sel ReleaseSelector) (*DuReport, error) {
+
+	if sortBy != DuSortSize && sortBy != DuSortName {
+		return nil, fmt.Errorf("unknown sort %s. Use %s or %s", sortBy, DuSortSize, DuSortName)
+	}
+
+	c := NewBMConfig(config).SetConfig(false).Select(sel)
+
+	report := &DuReport{}
+
+	var bdb *bolt.DB
+	if checkNewDb(dbPath) {
+		log.Warnf("db has not been created yet, linked and reclaimable versions can't be shown. Run `binman` first")
+	} else {
+		bdb = db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+		defer bdb.Close()
+	}
+
+	if err := c.diskUsage(threshold, orphans, bdb, report); err != nil {
+		return nil, err
+	}
+
+	// Repos on disk that are no longer configured are only shown when every release was asked for
+	if sel.IsEmpty() {
+		report.addUnconfigured(c.Releases)
+	}
+
+	report.total(sortBy)
+
+	return report, nil
+}
+
+func (config *BMConfig) diskUsage(threshold int, orphans bool, bdb *bolt.DB, report *DuReport) error {
+
+	planned := make([][]*storedVersion, len(config.Releases))
+
+	if bdb != nil {
+		var err error
+		results := make([]CleanResult, len(config.Releases))
+		fail := func(index int, err error) {
+			log.Warnf("Unable to work out what clean would free for %s %s", config.Releases[index].Repo, err)
+		}
+		if planned, err = config.planRetention(threshold, time.Now(), bdb, results, fail); err != nil {
+			return err
+		}
+	}
+
+	for index := range config.Releases {
+		rel := &config.Releases[index]
+
+		du := DuRepo{Source: rel.SourceIdentifier, Org: rel.org, Repo: rel.Repo, Configured: true}
+
+		reasons := make(map[string]string)
+		for _, v := range planned[index] {
+			reasons[v.version] = v.reason
+		}
+
+		data := make(map[string]map[string]any)
+		var linked []string
+		if bdb != nil {
+			var err error
+			if data, err = rel.storedVersionData(bdb); err != nil {
+				return err
+			}
+			if linked, err = rel.linkedVersions(bdb); err != nil {
+				return err
+			}
+		}
+
+		entries, _ := os.ReadDir(rel.repoPath())
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+
+			v := newDuVersion(e.Name(), filepath.Join(rel.repoPath(), e.Name()))
+			v.Linked = slices.Contains(linked, e.Name())
+			v.Reclaimable = reasons[e.Name()]
+
+			// clean --orphans removes versions the db does not know about unless they are linked
+			if _, ok := data[e.Name()]; !ok && bdb != nil {
+				v.Untracked = true
+				if orphans && len(data) != 0 && !v.Linked {
+					v.Reclaimable = cleanOrphan
+				}
+			}
+
+			du.add(v)
+		}
+
+		if len(du.Versions) != 0 {
+			report.Repos = append(report.Repos, du)
+		}
+	}
+
+	return nil
+}
+
+// addUnconfigured adds every repo in the release store of releases that is not in releases
+func (report *DuReport) addUnconfigured(releases []BinmanRelease) {
+
+	known := make(map[string]bool)
+	var stores []string
+
+	for _, rel := range releases {
+		known[rel.repoPath()] = true
+		store := filepath.Join(rel.ReleasePath, "repos")
+		if !slices.Contains(stores, store) {
+			stores = append(stores, store)
+		}
+	}
+
+	for _, store := range stores {
+		// The release store is laid out as source/org/project/version
+		projects, _ := filepath.Glob(filepath.Join(store, "*", "*", "*"))
+		for _, project := range projects {
+			if info, err := os.Stat(project); err != nil || !info.IsDir() || known[project] {
+				continue
+			}
+
+			// Releases in gitlab subgroups are stored deeper, so their parent directories are not projects
+			if containsRepo(project, known) {
+				continue
+			}
+
+			rel, _ := filepath.Rel(store, project)
+			parts := strings.Split(rel, string(filepath.Separator))
+
+			du := DuRepo{Source: parts[0], Org: parts[1], Repo: parts[1] + "/" + parts[2]}
+
+			entries, _ := os.ReadDir(project)
+			for _, e := range entries {
+				if e.IsDir() {
+					du.add(newDuVersion(e.Name(), filepath.Join(project, e.Name())))
+				}
+			}
+
+			report.Repos = append(report.Repos, du)
+		}
+	}
+}
+
+// containsRepo returns true if dir is a parent directory of one of repoPaths
+func containsRepo(dir string, repoPaths map[string]bool) bool {
+	for path := range repoPaths {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDuVersion measures the version stored at path
+func newDuVersion(version, path string) DuVersion {
+
+	v := DuVersion{Version: version, Path: path, Bytes: dirSize(path)}
+
+	entries, _ := os.ReadDir(path)
+	for _, e := range entries {
+		if !e.Type().IsRegular() || findfType(e.Name()) == "default" {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			v.Archives = append(v.Archives, DuArchive{Path: filepath.Join(path, e.Name()), Bytes: info.Size()})
+		}
+	}
+
+	return v
+}
+
+func (du *DuRepo) add(v DuVersion) {
+	du.Versions = append(du.Versions, v)
+	du.Bytes += v.Bytes
+	if v.Reclaimable != "" {
+		du.Reclaimable += v.Bytes
+	}
+	for _, a := range v.Archives {
+		du.ArchiveBytes += a.Bytes
+	}
+}
+
+// total sums the repos of the report by source and org and sorts every level
+func (report *DuReport) total(sortBy string) {
+
+	sources := make(map[string]*DuTotal)
+	orgs := make(map[string]*DuTotal)
+
+	for _, du := range report.Repos {
+		report.Bytes += du.Bytes
+		report.Reclaimable += du.Reclaimable
+		report.ArchiveBytes += du.ArchiveBytes
+
+		for name, totals := range map[string]map[string]*DuTotal{du.Source: sources, du.Source + "/" + du.Org: orgs} {
+			if totals[name] == nil {
+				totals[name] = &DuTotal{Name: name}
+			}
+			totals[name].Bytes += du.Bytes
+			totals[name].Reclaimable += du.Reclaimable
+		}
+	}
+
+	for _, t := range sources {
+		report.Sources = append(report.Sources, *t)
+	}
+	for _, t := range orgs {
+		report.Orgs = append(report.Orgs, *t)
+	}
+
+	less := func(aName, bName string, aBytes, bBytes int64) bool {
+		if sortBy == DuSortSize && aBytes != bBytes {
+			return aBytes > bBytes
+		}
+		return aName < bName
+	}
+
+	sort.Slice(report.Sources, func(i, j int) bool {
+		return less(report.Sources[i].Name, report.Sources[j].Name, report.Sources[i].Bytes, report.Sources[j].Bytes)
+	})
+	sort.Slice(report.Orgs, func(i, j int) bool {
+		return less(report.Orgs[i].Name, report.Orgs[j].Name, report.Orgs[i].Bytes, report.Orgs[j].Bytes)
+	})
+	sort.Slice(report.Repos, func(i, j int) bool {
+		a, b := report.Repos[i], report.Repos[j]
+		return less(a.Source+"/"+a.Repo, b.Source+"/"+b.Repo, a.Bytes, b.Bytes)
+	})
+	for _, du := range report.Repos {
+		sort.Slice(du.Versions, func(i, j int) bool {
+			return less(du.Versions[i].Version, du.Versions[j].Version, du.Versions[i].Bytes, du.Versions[j].Bytes)
+		})
+	}
+}
+
+// OutputDiskUsage prints the report at level
+func OutputDiskUsage(report *DuReport, level string) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	var duTable table.Table
+
+	switch level {
+	case DuLevelSource, DuLevelOrg:
+		totals := report.Sources
+		if level == DuLevelOrg {
+			totals = report.Orgs
+		}
+		duTable = table.New(strings.ToUpper(level[:1])+level[1:], "Size", "Reclaimable")
+		for _, t := range totals {
+			duTable.AddRow(t.Name, formatSize(t.Bytes), formatSize(t.Reclaimable))
+		}
+	case DuLevelVersion:
+		duTable = table.New("Source", "Repo", "Version", "Size", "Linked", "Reclaimable")
+		for _, du := range report.Repos {
+			for _, v := range du.Versions {
+				duTable.AddRow(du.Source, du.Repo, v.Version, formatSize(v.Bytes), v.Linked, v.Reclaimable)
+			}
+		}
+	default:
+		duTable = table.New("Source", "Repo", "Versions", "Size", "Archives", "Reclaimable")
+		for _, du := range report.Repos {
+			repo := du.Repo
+			if !du.Configured {
+				repo += " (not configured)"
+			}
+			duTable.AddRow(du.Source, repo, len(du.Versions), formatSize(du.Bytes), formatSize(du.ArchiveBytes), formatSize(du.Reclaimable))
+		}
+	}
+
+	duTable.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	duTable.Print()
+
+	if report.ArchiveBytes != 0 {
+		fmt.Printf("\nArchives kept because cleanup is off:\n")
+		for _, du := range report.Repos {
+			for _, v := range du.Versions {
+				for _, a := range v.Archives {
+					fmt.Printf("  %s %s\n", formatSize(a.Bytes), a.Path)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\nTotal %s, archives %s, clean would free %s\n", formatSize(report.Bytes), formatSize(report.ArchiveBytes), formatSize(report.Reclaimable))
+}
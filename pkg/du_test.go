@@ -0,0 +1,124 @@
+package binman
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+func TestDiskUsage(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, []string{"github.com/org2/old/v1.0.0"}, testCleanConfig, "du")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3"})
+
+	// An archive left behind because cleanup is off
+	archive := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.0.3", "repo1_linux_amd64.tar.gz")
+	if err := WriteStringtoFile(archive, "archive"); err != nil {
+		t.Fatalf("Unable to write %s", archive)
+	}
+
+	if _, err := DiskUsage(1, false, "largest", dbPath, testConfig, ReleaseSelector{}); err == nil {
+		t.Fatalf("Expected an unknown sort to fail")
+	}
+
+	report, err := DiskUsage(2, false, DuSortName, dbPath, testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if len(report.Repos) != 2 || len(report.Sources) != 1 || len(report.Orgs) != 2 {
+		t.Fatalf("Expected org1/repo1 and the unconfigured org2/old got %+v", report)
+	}
+
+	du := report.Repos[0]
+	if du.Repo != "org1/repo1" || !du.Configured || len(du.Versions) != 3 {
+		t.Fatalf("Unexpected repo %+v", du)
+	}
+
+	// Each version holds a 6 byte binary, v0.0.3 also holds the archive
+	if du.Bytes != 25 || du.ArchiveBytes != 7 || report.ArchiveBytes != 7 {
+		t.Fatalf("Expected 25 bytes with a 7 byte archive got %+v", du)
+	}
+
+	// Keeping 2 versions frees v0.0.1
+	if du.Reclaimable != 6 || du.Versions[0].Reclaimable != cleanKeep || du.Versions[1].Reclaimable != "" {
+		t.Fatalf("Expected v0.0.1 to be reclaimable got %+v", du.Versions)
+	}
+
+	if !du.Versions[2].Linked || du.Versions[1].Linked {
+		t.Fatalf("Expected only v0.0.3 to be linked got %+v", du.Versions)
+	}
+
+	if other := report.Repos[1]; other.Repo != "org2/old" || other.Configured || other.Reclaimable != 0 {
+		t.Fatalf("Expected org2/old to be reported as not configured got %+v", other)
+	}
+
+	// A version copied in by hand is untracked, clean only removes it with --orphans
+	if err := os.MkdirAll(filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.0.0"), 0755); err != nil {
+		t.Fatalf("Unable to create v0.0.0")
+	}
+	for _, orphans := range []bool{false, true} {
+		report, err = DiskUsage(2, orphans, DuSortName, dbPath, testConfig, ReleaseSelector{Only: []string{"org1/repo1"}})
+		if err != nil {
+			t.Fatalf("Unexpected error %s", err)
+		}
+		want := ""
+		if orphans {
+			want = cleanOrphan
+		}
+		if v := report.Repos[0].Versions[0]; v.Version != "v0.0.0" || !v.Untracked || v.Reclaimable != want {
+			t.Fatalf("Expected untracked v0.0.0 to be reclaimable %q with orphans %t got %+v", want, orphans, v)
+		}
+	}
+
+	// Selecting a release leaves out repos that are not configured
+	report, err = DiskUsage(2, false, DuSortSize, dbPath, testConfig, ReleaseSelector{Only: []string{"org1/repo1"}})
+	if err != nil || len(report.Repos) != 1 {
+		t.Fatalf("Expected only org1/repo1 got %+v %v", report, err)
+	}
+}
+
+const testDuSubgroupConfig = `
+config:
+  releasepath: {{ .releasePath }}
+releases:
+  - repo: gitlab.com/group/sub/tool
+`
+
+func TestDiskUsageSubgroup(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, []string{"gitlab.com/group/sub/tool/v1.0.0", "gitlab.com/group/old/v0.1.0"}, testDuSubgroupConfig, "dusubgroup")
+	defer os.RemoveAll(releasePath)
+
+	report, err := DiskUsage(2, false, DuSortName, filepath.Join(releasePath, "binman.db"), testConfig, ReleaseSelector{})
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	// group/sub holds a configured release, it is not an unconfigured repo with a version named tool
+	repos := make(map[string]DuRepo)
+	for _, du := range report.Repos {
+		repos[du.Repo] = du
+	}
+
+	if len(repos) != 2 {
+		t.Fatalf("Expected group/sub/tool and the unconfigured group/old got %+v", report.Repos)
+	}
+
+	if du, ok := repos["group/sub/tool"]; !ok || !du.Configured || len(du.Versions) != 1 {
+		t.Fatalf("Expected group/sub/tool to be configured with 1 version got %+v", report.Repos)
+	}
+
+	if du, ok := repos["group/old"]; !ok || du.Configured {
+		t.Fatalf("Expected group/old to be reported as not configured got %+v", report.Repos)
+	}
+}
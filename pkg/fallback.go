@@ -0,0 +1,201 @@
+package binman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-github/v50/github"
+	"github.com/rjbrown57/binman/pkg/filesource"
+	"github.com/rjbrown57/binman/pkg/gitea"
+	"github.com/rjbrown57/binman/pkg/httpindex"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"gitlab.com/gitlab-org/api/client-go"
+)
+
+const (
+	breakerThreshold = 3               // consecutive failures before a source is marked unhealthy
+	breakerCooldown  = 5 * time.Minute // how long an unhealthy source is skipped before it is tried again
+)
+
+// sourceBreaker tracks the health of sources across releases. A source that fails breakerThreshold times in a row
+// is skipped until breakerCooldown has passed, after which a single success marks it healthy again
+type sourceBreaker struct {
+	mu        sync.Mutex
+	failures  map[string]int
+	openUntil map[string]time.Time
+	now       func() time.Time
+}
+
+func newSourceBreaker() *sourceBreaker {
+	return &sourceBreaker{
+		failures:  make(map[string]int),
+		openUntil: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// sourceHealth is shared by all releases so a source failing for one release is skipped by the rest
+var sourceHealth = newSourceBreaker()
+
+// healthy returns false while the breaker for source is open
+func (b *sourceBreaker) healthy(source string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.now().Before(b.openUntil[source])
+}
+
+// record updates the breaker for source with the result of a query
+func (b *sourceBreaker) record(source string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.failures, source)
+		delete(b.openUntil, source)
+		return
+	}
+
+	b.failures[source]++
+	if b.failures[source] >= breakerThreshold {
+		log.Warnf("source %s failed %d times in a row, skipping it for %s", source, b.failures[source], breakerCooldown)
+		b.openUntil[source] = b.now().Add(breakerCooldown)
+		b.failures[source] = 0
+	}
+}
+
+// sourceFailure reports whether err means the source itself is unavailable rather than missing the release.
+// Only transport errors, timeouts and server errors count against the health of a source
+func sourceFailure(err error) bool {
+
+	// fs errors also satisfy net.Error, so transport failures are matched by type
+	var urlErr *url.Error
+	var opErr *net.OpError
+	if errors.As(err, &urlErr) || errors.As(err, &opErr) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	if errors.Is(err, filesource.ErrUnavailable) || errors.Is(err, ErrServer) {
+		return true
+	}
+
+	var ghErr *github.ErrorResponse
+	var glErr *gitlab.ErrorResponse
+	var giteaErr *gitea.ErrorResponse
+	var indexErr *httpindex.StatusError
+	var ociErr *transport.Error
+
+	var status int
+	switch {
+	case errors.As(err, &ghErr) && ghErr.Response != nil:
+		status = ghErr.Response.StatusCode
+	case errors.As(err, &glErr) && glErr.Response != nil:
+		status = glErr.Response.StatusCode
+	case errors.As(err, &giteaErr):
+		status = giteaErr.StatusCode
+	case errors.As(err, &indexErr):
+		status = indexErr.StatusCode
+	case errors.As(err, &ociErr):
+		status = ociErr.StatusCode
+	}
+
+	return status >= http.StatusInternalServerError
+}
+
+type GetFallbackReleaseAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddGetFallbackReleaseAction() Action {
+	return &GetFallbackReleaseAction{
+		r,
+	}
+}
+
+// candidates returns the healthy sources in priority order. If every source is unhealthy all are tried rather than failing outright
+func (action *GetFallbackReleaseAction) candidates() []*Source {
+
+	var healthy []*Source
+
+	for _, source := range action.r.fallbacks {
+		if sourceHealth.healthy(source.Name) {
+			healthy = append(healthy, source)
+			continue
+		}
+		log.Debugf("skipping unhealthy source %s for %s", source.Name, action.r.Repo)
+	}
+
+	if len(healthy) == 0 {
+		return action.r.fallbacks
+	}
+
+	return healthy
+}
+
+// GetFallbackReleaseAction queries the sources of a release in order, stopping at the first that answers.
+// The release is left pointing at the source that served it
+func (action *GetFallbackReleaseAction) execute() error {
+
+	var errs []error
+	deferred := true
+
+	// Data prefetched by graphql belongs to the first source only
+	prefetched := action.r.relData
+	first := action.r.fallbacks[0]
+
+	for _, source := range action.candidates() {
+
+		action.r.source = source
+		action.r.relData = nil
+		if source == first {
+			action.r.relData = prefetched
+		}
+
+		var err error
+		if get := action.r.getReleaseAction(); get != nil {
+			err = get.execute()
+		} else {
+			err = fmt.Errorf("source %s has unsupported apitype %s", source.Name, source.Apitype)
+		}
+
+		// Rate limits are not a sign of an unhealthy source
+		var deferredErr *DeferredError
+		if errors.As(err, &deferredErr) {
+			log.Debugf("source %s deferred %s, trying next source", source.Name, action.r.Repo)
+			errs = append(errs, err)
+			continue
+		}
+
+		if err == nil {
+			sourceHealth.record(source.Name, nil)
+			log.Debugf("%s served by %s", action.r.Repo, source.Name)
+			return nil
+		}
+
+		errs = append(errs, err)
+		deferred = false
+
+		// A source that answered without the release is not unhealthy, only failures of the source itself are counted
+		if !sourceFailure(err) {
+			log.Infof("source %s does not have %s, trying next source - %s", source.Name, action.r.Repo, err)
+			continue
+		}
+
+		sourceHealth.record(source.Name, err)
+		log.Warnf("source %s failed for %s, trying next source - %s", source.Name, action.r.Repo, err)
+	}
+
+	// Only defer the release if every source was rate limited so it is retried on the next sync
+	if deferred {
+		return errs[len(errs)-1]
+	}
+
+	return fmt.Errorf("all sources failed for %s - %w", action.r.Repo, errors.Join(errs...))
+}
@@ -0,0 +1,195 @@
+package binman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rjbrown57/binman/pkg/filesource"
+	"github.com/rjbrown57/binman/pkg/gitea"
+	"github.com/rjbrown57/binman/pkg/httpindex"
+)
+
+func TestSourceBreaker(t *testing.T) {
+
+	now := time.Now()
+	b := newSourceBreaker()
+	b.now = func() time.Time { return now }
+
+	failure := errors.New("unavailable")
+
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.record("internal", failure)
+	}
+
+	if !b.healthy("internal") {
+		t.Fatalf("Expected source to be healthy below the threshold")
+	}
+
+	// A success resets the count
+	b.record("internal", nil)
+	for i := 0; i < breakerThreshold-1; i++ {
+		b.record("internal", failure)
+	}
+
+	if !b.healthy("internal") {
+		t.Fatalf("Expected source to be healthy after a success reset the failure count")
+	}
+
+	b.record("internal", failure)
+	if b.healthy("internal") {
+		t.Fatalf("Expected source to be unhealthy after %d failures", breakerThreshold)
+	}
+
+	if !b.healthy("github.com") {
+		t.Fatalf("Expected other sources to be unaffected")
+	}
+
+	now = now.Add(breakerCooldown)
+	if !b.healthy("internal") {
+		t.Fatalf("Expected source to be retried after the cooldown")
+	}
+}
+
+func TestSetFallbacks(t *testing.T) {
+
+	github := &Source{Name: "github.com", Apitype: "github"}
+	internal := &Source{Name: "binman-internal", Apitype: "binman"}
+	mirror := &Source{Name: "ghe.internal", Apitype: "github"}
+
+	sourceMap := map[string]*Source{
+		"github.com":      github,
+		"binman-internal": internal,
+		"ghe.internal":    mirror,
+		"default":         github,
+	}
+
+	var tests = []struct {
+		rel              BinmanRelease
+		expectedSourceId string
+		expectedSources  []*Source
+	}{
+		{
+			rel:              BinmanRelease{Repo: "rjbrown57/binman", Sources: []string{"binman-internal", "github.com"}},
+			expectedSourceId: "github.com",
+			expectedSources:  []*Source{internal, github},
+		},
+		{
+			rel:              BinmanRelease{Repo: "rjbrown57/binman", Sources: []string{"ghe.internal", "missing", "github.com"}},
+			expectedSourceId: "ghe.internal",
+			expectedSources:  []*Source{mirror, github},
+		},
+		{
+			rel:              BinmanRelease{Repo: "rjbrown57/binman", Sources: []string{"missing"}},
+			expectedSourceId: "github.com",
+			expectedSources:  nil,
+		},
+	}
+
+	for caseNum, test := range tests {
+		test.rel.SetSource(sourceMap)
+
+		if len(test.rel.fallbacks) != len(test.expectedSources) {
+			t.Fatalf("%d expected %d sources got %d", caseNum, len(test.expectedSources), len(test.rel.fallbacks))
+		}
+		for i, source := range test.expectedSources {
+			if test.rel.fallbacks[i] != source {
+				t.Fatalf("%d expected source %s at %d got %s", caseNum, source.Name, i, test.rel.fallbacks[i].Name)
+			}
+		}
+		if len(test.expectedSources) > 0 && test.rel.source != test.expectedSources[0] {
+			t.Fatalf("%d expected first source to be used, got %s", caseNum, test.rel.source.Name)
+		}
+		if test.rel.SourceIdentifier != test.expectedSourceId {
+			t.Fatalf("%d expected source id %s got %s", caseNum, test.expectedSourceId, test.rel.SourceIdentifier)
+		}
+	}
+
+	if (&BinmanRelease{Repo: "rjbrown57/binman"}).hasSource(sourceMap) {
+		t.Fatalf("Expected release without a source to use the default sources")
+	}
+	if !(&BinmanRelease{Repo: "ghe.internal/rjbrown57/binman"}).hasSource(sourceMap) {
+		t.Fatalf("Expected source prefix to be detected")
+	}
+}
+
+func TestGetFallbackReleaseAction(t *testing.T) {
+
+	defer func(b *sourceBreaker) { sourceHealth = b }(sourceHealth)
+	sourceHealth = newSourceBreaker()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "org", "tool", "v1.0.0")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Unable to create %s", dir)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tool_linux_amd64"), []byte("tool"), 0644); err != nil {
+		t.Fatalf("Unable to write asset")
+	}
+
+	down := &Source{Name: "down", Apitype: "file", URL: filepath.Join(root, "missing")}
+	usb := &Source{Name: "usb", Apitype: "file", URL: root}
+
+	for i := 0; i < breakerThreshold; i++ {
+		rel := BinmanRelease{Repo: "org/tool", QueryType: "release", source: down, fallbacks: []*Source{down, usb}}
+
+		if err := rel.AddGetFallbackReleaseAction().execute(); err != nil {
+			t.Fatalf("Expected fallback to usb to succeed - %s", err)
+		}
+		if rel.source != usb || rel.Version != "v1.0.0" || rel.getDataMap()["servedBy"] != "usb" {
+			t.Fatalf("Expected release served by usb, got %s %s", rel.source.Name, rel.Version)
+		}
+	}
+
+	if sourceHealth.healthy("down") {
+		t.Fatalf("Expected failing source to be marked unhealthy")
+	}
+
+	// Every source failing returns an error
+	rel := BinmanRelease{Repo: "org/missing", QueryType: "release", source: down, fallbacks: []*Source{down, usb}}
+	if err := rel.AddGetFallbackReleaseAction().execute(); err == nil {
+		t.Fatalf("Expected an error when no source has the release")
+	}
+
+	// A source that does not have a release is not unhealthy
+	for i := 0; i < breakerThreshold; i++ {
+		rel := BinmanRelease{Repo: "org/missing", QueryType: "release", source: usb, fallbacks: []*Source{usb}}
+		if err := rel.AddGetFallbackReleaseAction().execute(); err == nil {
+			t.Fatalf("Expected an error for a release usb does not have")
+		}
+	}
+
+	if !sourceHealth.healthy("usb") {
+		t.Fatalf("Expected a release missing from usb not to mark it unhealthy")
+	}
+}
+
+func TestSourceFailure(t *testing.T) {
+
+	var tests = []struct {
+		err  error
+		want bool
+	}{
+		{&url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("query failed - %w", context.DeadlineExceeded), true},
+		{fmt.Errorf("%w - missing", filesource.ErrUnavailable), true},
+		{&httpindex.StatusError{URL: "https://example.com", StatusCode: http.StatusBadGateway}, true},
+		{&httpindex.StatusError{URL: "https://example.com", StatusCode: http.StatusNotFound}, false},
+		{&gitea.ErrorResponse{URL: "https://example.com", StatusCode: http.StatusServiceUnavailable}, true},
+		{ErrServer, true},
+		{ErrNotFound, false},
+		{errors.New("no versions found"), false},
+	}
+
+	for _, test := range tests {
+		if got := sourceFailure(test.err); got != test.want {
+			t.Fatalf("sourceFailure(%v) expected %v got %v", test.err, test.want, got)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/rjbrown57/binman/pkg/constants"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/templating"
 	"github.com/ulikunitz/xz"
 
 	"gopkg.in/yaml.v3"
@@ -72,6 +73,83 @@ func selectAsset(relArch string, relOS string, version string, project string, a
 	return possibleAsset.Name, possibleAsset.Url
 }
 
+// selectAsset picks an asset for the os/arch of the release. Assets that do not pass the assetfilters of the release are
+// ignored, and if only one asset is left it is used whatever its name
+func (r *BinmanRelease) selectAsset(assets map[string]string) (string, string) {
+
+	if len(r.AssetFilters) != 0 {
+		assets = filterAssets(assets, r.assetFilters())
+		if len(assets) == 1 {
+			for name, url := range assets {
+				log.Debugf("Selected asset %s, the only one matching %v", name, r.AssetFilters)
+				return name, url
+			}
+		}
+	}
+
+	return selectAsset(r.Arch, r.Os, r.Version, r.project, assets)
+}
+
+// assetFilter is a compiled entry of assetfilters
+type assetFilter struct {
+	rx      *regexp.Regexp
+	exclude bool
+}
+
+// assetFilters templates and compiles the assetfilters of the release
+func (r *BinmanRelease) assetFilters() []assetFilter {
+
+	var filters []assetFilter
+
+	// Templated values are matched literally so regex characters in a version or repo can't break a filter
+	dataMap := r.getDataMap()
+	for k, v := range dataMap {
+		if s, ok := v.(string); ok {
+			dataMap[k] = regexp.QuoteMeta(s)
+		}
+	}
+
+	for _, f := range r.AssetFilters {
+		exclude := strings.HasPrefix(f, "!")
+		// Filters are checked by populateReleases and templated values are quoted so they always compile
+		rx := regexp.MustCompile(templating.TemplateString(strings.TrimPrefix(f, "!"), dataMap))
+		filters = append(filters, assetFilter{rx, exclude})
+	}
+
+	return filters
+}
+
+// validAssetFilters returns an error for the first filter that is not a valid regex
+func validAssetFilters(filters []string) error {
+	for _, f := range filters {
+		if _, err := regexp.Compile(strings.TrimPrefix(f, "!")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterAssets returns the assets matching every filter. Assets matching an exclude filter are dropped
+func filterAssets(assets map[string]string, filters []assetFilter) map[string]string {
+
+	filtered := make(map[string]string)
+
+	for name, url := range assets {
+		keep := true
+		for _, f := range filters {
+			if f.rx.MatchString(name) == f.exclude {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered[name] = url
+		}
+	}
+
+	return filtered
+}
+
 // Create the link to new release
 func createLink(source string, target string) error {
 
@@ -234,6 +234,46 @@ func TestSelectAsset(t *testing.T) {
 	}
 }
 
+// TestReleaseSelectAsset checks assetfilters narrow the assets considered
+func TestReleaseSelectAsset(t *testing.T) {
+
+	assets := map[string]string{
+		"tool_v1.0.0_linux_amd64.tar.gz":      "gnu",
+		"tool_v1.0.0_linux_amd64_musl.tar.gz": "musl",
+		"tool_v1.0.0_linux_arm64.tar.gz":      "arm",
+		"tool-installer.sh":                   "installer",
+	}
+
+	var tests = []struct {
+		filters []string
+		want    string
+	}{
+		{[]string{"musl"}, "musl"},
+		{[]string{"linux_amd64", "!musl"}, "gnu"},
+		{[]string{`_{{.version}}_linux_amd64\.tar`}, "gnu"},
+		// a single asset left is used even though it has no os/arch
+		{[]string{`installer\.sh$`}, "installer"},
+		{[]string{"nomatch"}, ""},
+	}
+
+	for _, test := range tests {
+		r := BinmanRelease{Repo: "org/tool", project: "tool", Os: "linux", Arch: "amd64", Version: "v1.0.0", AssetFilters: test.filters}
+		if _, url := r.selectAsset(assets); url != test.want {
+			t.Fatalf("assetfilters %v expected %s got %s", test.filters, test.want, url)
+		}
+	}
+
+	// regex characters in templated values are matched literally instead of breaking the filter
+	r := BinmanRelease{Repo: "org/tool", project: "tool", Os: "linux", Arch: "amd64", Version: "v1.0(rc", AssetFilters: []string{`_{{.version}}_linux`}}
+	if _, url := r.selectAsset(map[string]string{"tool_v1.0(rc_linux_amd64.tar.gz": "rc", "tool_v1.0.0_linux_amd64.tar.gz": "gnu"}); url != "rc" {
+		t.Fatalf("Expected the templated version to match literally got %s", url)
+	}
+
+	if err := validAssetFilters([]string{"!(unclosed"}); err == nil {
+		t.Fatalf("Expected an invalid assetfilter to fail")
+	}
+}
+
 func TestGetVersionFromPath(t *testing.T) {
 
 	d := fmt.Sprintf("%s/%s/%s", os.TempDir(), "repos", "repo")
@@ -0,0 +1,188 @@
+package filesource
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	semver "github.com/Masterminds/semver/v3"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// ErrUnavailable is returned when the root of a source is missing. e.g a removable drive that is not mounted
+var ErrUnavailable = errors.New("file source is unavailable")
+
+// Asset is a file published for a version
+type Asset struct {
+	Name string
+	URL  string // file:// url of the asset
+}
+
+// Release is a version directory of a file source
+type Release struct {
+	Version   string
+	Path      string
+	CreatedAt time.Time // modification time of the version directory
+	Assets    []Asset
+}
+
+// Root returns the directory a source url points at. Plain paths and file:// urls are accepted
+func Root(sourceUrl string) (string, error) {
+
+	if strings.HasPrefix(sourceUrl, "file://") {
+		u, err := url.Parse(sourceUrl)
+		if err != nil {
+			return "", err
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return "", fmt.Errorf("file url %s must not have a host", sourceUrl)
+		}
+		sourceUrl = u.Path
+	}
+
+	if sourceUrl == "" {
+		return "", fmt.Errorf("file source url is empty")
+	}
+
+	return filepath.Abs(sourceUrl)
+}
+
+// ListVersions returns the semver version directories of repo under root newest first. Prereleases are skipped unless prerelease is true
+func ListVersions(root string, repo string, prerelease bool) ([]string, error) {
+
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(repo)))
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []*semver.Version
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v, err := semver.NewVersion(e.Name())
+		if err != nil {
+			log.Debugf("Skipping %s of %s, not a semver", e.Name(), repo)
+			continue
+		}
+		if v.Prerelease() != "" && !prerelease {
+			continue
+		}
+		vs = append(vs, v)
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(vs)))
+
+	versions := make([]string, 0, len(vs))
+	for _, v := range vs {
+		versions = append(versions, v.Original())
+	}
+
+	return versions, nil
+}
+
+// available returns ErrUnavailable if root is missing
+func available(root string) error {
+	if _, err := os.Stat(root); err != nil {
+		return fmt.Errorf("%w - %s", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// ListTags returns the name and modification time of every version directory of repo under root
+func ListTags(root string, repo string) (map[string]time.Time, error) {
+
+	if err := available(root); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(repo)))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]time.Time)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		tags[e.Name()] = info.ModTime()
+	}
+
+	return tags, nil
+}
+
+// GetRelease returns the release of repo for tag. If tag is empty the newest stable semver version is used
+func GetRelease(root string, repo string, tag string) (*Release, error) {
+
+	if err := available(root); err != nil {
+		return nil, err
+	}
+
+	if tag == "" {
+		versions, err := ListVersions(root, repo, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no versions found for %s in %s", repo, root)
+		}
+		tag = versions[0]
+	}
+
+	dir := filepath.Join(root, filepath.FromSlash(repo), tag)
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &Release{Version: tag, Path: dir, CreatedAt: info.ModTime()}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		rel.Assets = append(rel.Assets, Asset{Name: e.Name(), URL: (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String()})
+	}
+
+	return rel, nil
+}
+
+// GetAssetbyName returns the name and url of the asset matching relFileName
+func GetAssetbyName(relFileName string, assets []Asset) (string, string) {
+	for _, asset := range assets {
+		if asset.Name == relFileName {
+			log.Debugf("Selected asset == %+v\n", asset.Name)
+			return asset.Name, asset.URL
+		}
+	}
+
+	return "", ""
+}
+
+// GetAssetData will create a map of names + download urls
+func GetAssetData(assets []Asset) map[string]string {
+	m := make(map[string]string)
+
+	for _, asset := range assets {
+		m[strings.ToLower(asset.Name)] = asset.URL
+	}
+
+	return m
+}
@@ -0,0 +1,70 @@
+package filesource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoot(t *testing.T) {
+
+	var tests = []struct {
+		sourceUrl string
+		expected  string
+		err       bool
+	}{
+		{"/mnt/usb/binman", "/mnt/usb/binman", false},
+		{"file:///mnt/usb/binman", "/mnt/usb/binman", false},
+		{"file://localhost/mnt/usb", "/mnt/usb", false},
+		{"file://otherhost/mnt/usb", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := Root(test.sourceUrl)
+		if (err != nil) != test.err || got != test.expected {
+			t.Fatalf("%s: expected %s got %s, %v", test.sourceUrl, test.expected, got, err)
+		}
+	}
+}
+
+func TestGetRelease(t *testing.T) {
+
+	root := t.TempDir()
+
+	for _, version := range []string{"v0.9.0", "v0.10.0", "v1.0.0-beta.1", "latest"} {
+		dir := filepath.Join(root, "org", "tool", version)
+		if err := os.MkdirAll(filepath.Join(dir, "subdir"), 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		for _, asset := range []string{"tool_linux_amd64.tar.gz", "checksums.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, asset), []byte(version), 0644); err != nil {
+				t.Fatalf("Unable to write %s", asset)
+			}
+		}
+	}
+
+	versions, err := ListVersions(root, "org/tool", false)
+	if err != nil || strings.Join(versions, ",") != "v0.10.0,v0.9.0" {
+		t.Fatalf("Unexpected versions %v, %v", versions, err)
+	}
+
+	rel, err := GetRelease(root, "org/tool", "")
+	if err != nil || rel.Version != "v0.10.0" || len(rel.Assets) != 2 {
+		t.Fatalf("Unexpected release %+v, %v", rel, err)
+	}
+
+	name, url := GetAssetbyName("tool_linux_amd64.tar.gz", rel.Assets)
+	if url != "file://"+filepath.Join(root, "org", "tool", "v0.10.0", name) {
+		t.Fatalf("Unexpected asset url %s", url)
+	}
+
+	if rel, err = GetRelease(root, "org/tool", "latest"); err != nil || rel.Version != "latest" {
+		t.Fatalf("Expected pinned non semver version, got %+v, %v", rel, err)
+	}
+
+	if _, err = GetRelease(root, "org/missing", ""); err == nil {
+		t.Fatalf("Expected error for missing repo")
+	}
+}
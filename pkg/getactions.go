@@ -2,14 +2,41 @@ package binman
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/google/go-github/v50/github"
+	"github.com/rjbrown57/binman/pkg/filesource"
+	"github.com/rjbrown57/binman/pkg/gitea"
 	"github.com/rjbrown57/binman/pkg/gl"
+	"github.com/rjbrown57/binman/pkg/httpindex"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/oci"
+	"github.com/rjbrown57/binman/pkg/ratelimit"
+	"github.com/rjbrown57/binman/pkg/templating"
+	"github.com/rjbrown57/binman/pkg/versionurl"
 	"gitlab.com/gitlab-org/api/client-go"
 )
 
+// deferOnRateLimit will convert rate limit errors into a DeferredError so the release is retried on the next sync rather than failing
+func (r *BinmanRelease) deferOnRateLimit(err error) error {
+
+	var limited *ratelimit.LimitedError
+	var ghLimited *github.RateLimitError
+
+	switch {
+	case errors.As(err, &limited):
+		return &DeferredError{RepoName: r.Repo, Until: limited.Reset}
+	case errors.As(err, &ghLimited):
+		return &DeferredError{RepoName: r.Repo, Until: ghLimited.Rate.Reset.Time}
+	}
+
+	return err
+}
+
 type GetGHReleaseAction struct {
 	r        *BinmanRelease
 	ghClient *github.Client
@@ -29,6 +56,15 @@ func (action *GetGHReleaseAction) execute() error {
 
 	ctx := context.Background()
 
+	// Releases may have been fetched in a graphql batch before the action chain started
+	if ghd, ok := action.r.relData.(*github.RepositoryRelease); ok && ghd != nil {
+		log.Debugf("Using batched release data for %s", action.r.Repo)
+		action.r.Version = ghd.GetTagName()
+		action.r.relNotes = ghd.GetBody()
+		action.r.createdAtTime = ghd.GetCreatedAt().Unix()
+		return nil
+	}
+
 	switch action.r.QueryType {
 	case "release":
 		log.Debugf("Querying github api for latest release of %s", action.r.Repo)
@@ -46,7 +82,7 @@ func (action *GetGHReleaseAction) execute() error {
 
 	action.r.relData = ghd
 
-	return err
+	return action.r.deferOnRateLimit(err)
 }
 
 type GetGLReleaseAction struct {
@@ -71,7 +107,7 @@ func (action *GetGLReleaseAction) execute() error {
 		log.Debugf("Querying gitlab api for latest release of %s", action.r.Repo)
 		action.r.Version, err = gl.GLGetLatestTag(action.glClient, action.r.Repo)
 		if err != nil {
-			return err
+			return action.r.deferOnRateLimit(err)
 		}
 		log.Debugf("Latest release of %s == %s", action.r.Repo, action.r.Version)
 	case "releasebytag":
@@ -94,6 +130,231 @@ func (action *GetGLReleaseAction) execute() error {
 	return err
 }
 
+type GetGiteaReleaseAction struct {
+	r           *BinmanRelease
+	giteaClient *gitea.Client
+}
+
+func (r *BinmanRelease) AddGetGiteaReleaseAction(giteaClient *gitea.Client) Action {
+	return &GetGiteaReleaseAction{
+		r,
+		giteaClient,
+	}
+}
+
+func (action *GetGiteaReleaseAction) execute() error {
+
+	var err error
+	var rel *gitea.Release
+
+	ctx := context.Background()
+
+	switch action.r.QueryType {
+	case "release":
+		log.Debugf("Querying gitea api for latest release of %s", action.r.Repo)
+		rel, err = action.giteaClient.GetLatestRelease(ctx, action.r.Repo)
+	case "releasebytag":
+		log.Debugf("Querying gitea api for tag %s of %s", action.r.Version, action.r.Repo)
+		rel, err = action.giteaClient.GetReleaseByTag(ctx, action.r.Repo, action.r.Version)
+	}
+
+	if err != nil {
+		return action.r.deferOnRateLimit(err)
+	}
+
+	// Older gitea versions may omit assets from the release response
+	if rel.Assets == nil {
+		if rel.Assets, err = action.giteaClient.ListReleaseAssets(ctx, action.r.Repo, rel.ID); err != nil {
+			return action.r.deferOnRateLimit(err)
+		}
+	}
+
+	action.r.Version = rel.TagName
+	action.r.relNotes = rel.Body
+	action.r.createdAtTime = rel.CreatedAt.Unix()
+	action.r.relData = rel
+
+	return nil
+}
+
+type GetHTTPIndexReleaseAction struct {
+	r           *BinmanRelease
+	indexClient *httpindex.Client
+}
+
+func (r *BinmanRelease) AddGetHTTPIndexReleaseAction(indexClient *httpindex.Client) Action {
+	return &GetHTTPIndexReleaseAction{
+		r,
+		indexClient,
+	}
+}
+
+func (action *GetHTTPIndexReleaseAction) execute() error {
+
+	var err error
+	var rel *httpindex.Release
+
+	ctx := context.Background()
+	indexURL := action.r.indexURL()
+
+	switch action.r.QueryType {
+	case "release":
+		log.Debugf("Querying %s for latest release of %s", indexURL, action.r.Repo)
+		if !action.r.schemeOrdered() {
+			rel, err = action.indexClient.GetLatestRelease(ctx, indexURL)
+			break
+		}
+		var tags []string
+		if tags, err = action.indexClient.ListTags(ctx, indexURL); err != nil {
+			break
+		}
+		var tag string
+		if tag, err = action.r.latestTag(tags, nil); err != nil {
+			break
+		}
+		rel, err = action.indexClient.GetReleaseByTag(ctx, indexURL, tag)
+	case "releasebytag":
+		log.Debugf("Querying %s for version %s of %s", indexURL, action.r.Version, action.r.Repo)
+		rel, err = action.indexClient.GetReleaseByTag(ctx, indexURL, action.r.Version)
+	}
+
+	if err != nil {
+		return action.r.deferOnRateLimit(err)
+	}
+
+	action.r.Version = rel.Version
+	action.r.relData = rel
+
+	return nil
+}
+
+type GetOCIReleaseAction struct {
+	r      *BinmanRelease
+	puller *oci.Puller
+}
+
+func (r *BinmanRelease) AddGetOCIReleaseAction(puller *oci.Puller) Action {
+	return &GetOCIReleaseAction{
+		r,
+		puller,
+	}
+}
+
+func (action *GetOCIReleaseAction) execute() error {
+
+	var tag string
+
+	repo := fmt.Sprintf("%s/%s", action.r.source.registry(), action.r.Repo)
+
+	switch action.r.QueryType {
+	case "release":
+		log.Debugf("Listing tags of %s for latest version", repo)
+		if action.r.schemeOrdered() {
+			tags, err := action.puller.ListTags(repo)
+			if err != nil {
+				return action.r.deferOnRateLimit(err)
+			}
+			if tag, err = action.r.latestTag(tags, nil); err != nil {
+				return err
+			}
+		}
+	case "releasebytag":
+		log.Debugf("Querying %s for tag %s", repo, action.r.Version)
+		tag = action.r.Version
+	}
+
+	rel, err := action.puller.GetRelease(repo, tag)
+	if err != nil {
+		return action.r.deferOnRateLimit(err)
+	}
+
+	action.r.Version = rel.Version
+	action.r.relData = rel
+
+	return nil
+}
+
+type GetFileReleaseAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddGetFileReleaseAction() Action {
+	return &GetFileReleaseAction{
+		r,
+	}
+}
+
+func (action *GetFileReleaseAction) execute() error {
+
+	var tag string
+
+	root, err := filesource.Root(action.r.source.URL)
+	if err != nil {
+		return err
+	}
+
+	switch action.r.QueryType {
+	case "release":
+		log.Debugf("Listing %s for latest version of %s", root, action.r.Repo)
+		if action.r.schemeOrdered() {
+			created, err := filesource.ListTags(root, action.r.Repo)
+			if err != nil {
+				return err
+			}
+			// The created scheme uses the modification time of each version directory
+			if tag, err = action.r.latestTag(slices.Collect(maps.Keys(created)), func(v string) time.Time { return created[v] }); err != nil {
+				return err
+			}
+		}
+	case "releasebytag":
+		log.Debugf("Looking in %s for version %s of %s", root, action.r.Version, action.r.Repo)
+		tag = action.r.Version
+	}
+
+	rel, err := filesource.GetRelease(root, action.r.Repo, tag)
+	if err != nil {
+		return err
+	}
+
+	action.r.Version = rel.Version
+	action.r.createdAtTime = rel.CreatedAt.Unix()
+	action.r.relData = rel
+
+	return nil
+}
+
+type GetVersionUrlAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddGetVersionUrlAction() Action {
+	return &GetVersionUrlAction{
+		r,
+	}
+}
+
+func (action *GetVersionUrlAction) execute() error {
+
+	// A pinned version needs no lookup
+	if action.r.QueryType == "releasebytag" {
+		return nil
+	}
+
+	versionUrl := templating.TemplateString(action.r.VersionUrl, action.r.getDataMap())
+
+	log.Debugf("Querying %s for latest version of %s", versionUrl, action.r.Repo)
+
+	version, err := versionurl.GetVersion(context.Background(), versionUrl, action.r.VersionPath, action.r.VersionRegex)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Latest version of %s from %s == %s", action.r.Repo, versionUrl, version)
+	action.r.Version = version
+
+	return nil
+}
+
 type GetBinmanReleaseAction struct {
 	r *BinmanRelease
 }
@@ -113,7 +374,7 @@ func (action *GetBinmanReleaseAction) execute() error {
 		Version:      action.r.Version,
 	}
 
-	resp, err := q.SendQuery(action.r.source.URL)
+	resp, err := q.SendQueryWithTransport(action.r.source.URL, action.r.source.transport())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,150 @@
+package gh
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("Unable to parse github app private key")
+)
+
+// appJWTLifetime is how long a github app JWT is valid for. Github allows a maximum of 10 minutes
+const appJWTLifetime = 9 * time.Minute
+
+// parsePrivateKey will parse a PEM encoded PKCS1 or PKCS8 rsa private key
+func parsePrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w - %s", ErrInvalidPrivateKey, err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w - key is not rsa", ErrInvalidPrivateKey)
+	}
+
+	return rsaKey, nil
+}
+
+// appJWT returns an RS256 signed JWT identifying the github app
+func appJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+
+	enc := base64.RawURLEncoding
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// iat is backdated to allow for clock drift between us and github
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": fmt.Sprintf("%d", appID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
+
+// jwtTransport authenticates requests as the github app itself
+type jwtTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := appJWT(t.appID, t.key, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	if t.base == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// appTokenSource mints installation tokens for a github app
+type appTokenSource struct {
+	installationID int64
+	client         *github.Client
+}
+
+func (a *appTokenSource) Token() (*oauth2.Token, error) {
+
+	log.Debugf("Minting github app installation token for installation %d", a.installationID)
+
+	t, _, err := a.client.Apps.CreateInstallationToken(context.Background(), a.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create installation token for installation %d - %w", a.installationID, err)
+	}
+
+	return &oauth2.Token{AccessToken: t.GetToken(), TokenType: "Bearer", Expiry: t.GetExpiresAt().Time}, nil
+}
+
+// NewAppTokenSource returns a token source for a github app installation. Tokens are reused until they expire and then minted again.
+// If base is nil the default transport is used
+func NewAppTokenSource(baseUrl string, appID, installationID int64, keyPEM []byte, base http.RoundTripper) (oauth2.TokenSource, error) {
+
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	ghUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse configured github url %s - %w", baseUrl, err)
+	}
+
+	// go-github requires a trailing slash on the base url
+	if !strings.HasSuffix(ghUrl.Path, "/") {
+		ghUrl.Path += "/"
+	}
+
+	client := github.NewClient(&http.Client{Transport: &jwtTransport{appID: appID, key: key, base: base}})
+	client.BaseURL = ghUrl
+
+	return oauth2.ReuseTokenSource(nil, &appTokenSource{installationID: installationID, client: client}), nil
+}
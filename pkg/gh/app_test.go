@@ -0,0 +1,140 @@
+package gh
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate key %s", err)
+	}
+
+	token, err := appJWT(1234, key, time.Now())
+	if err != nil {
+		t.Fatalf("Unable to create jwt %s", err)
+	}
+
+	if err := verifyJWT(token, &key.PublicKey, "1234"); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate key %s", err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Unable to marshal key %s", err)
+	}
+
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		if _, err := parsePrivateKey(pem.EncodeToMemory(block)); err != nil {
+			t.Fatalf("Unable to parse %s - %s", block.Type, err)
+		}
+	}
+
+	if _, err := parsePrivateKey([]byte("notakey")); err == nil {
+		t.Fatalf("Expected error parsing invalid key")
+	}
+}
+
+func TestAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Unable to generate key %s", err)
+	}
+
+	var minted int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := verifyJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey, "7"); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		minted++
+		fmt.Fprintf(w, `{"token": "installation-token-%d", "expires_at": "%s"}`, minted, time.Now().Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	ts, err := NewAppTokenSource(srv.URL, 7, 42, keyPEM, nil)
+	if err != nil {
+		t.Fatalf("Unable to create token source %s", err)
+	}
+
+	// The second call should reuse the unexpired token
+	for range 2 {
+		token, err := ts.Token()
+		if err != nil {
+			t.Fatalf("Unable to get token %s", err)
+		}
+		if token.AccessToken != "installation-token-1" {
+			t.Fatalf("Unexpected token %s", token.AccessToken)
+		}
+	}
+
+	if minted != 1 {
+		t.Fatalf("Expected a single token to be minted, got %d", minted)
+	}
+}
+
+// verifyJWT checks the signature and issuer of an RS256 JWT
+func verifyJWT(token string, key *rsa.PublicKey, iss string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed jwt %s", token)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("invalid jwt signature %s", err)
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return err
+	}
+
+	if claims["iss"] != iss {
+		return fmt.Errorf("expected iss %s got %v", iss, claims["iss"])
+	}
+
+	return nil
+}
@@ -31,3 +31,14 @@ func GHGetAssetData(assets []*github.ReleaseAsset) map[string]string {
 
 	return m
 }
+
+// GetAssetAPIUrl will return the release asset api url for an asset. The api url must be used for authenticated downloads of private assets
+func GetAssetAPIUrl(assetName string, assets []*github.ReleaseAsset) string {
+	for _, asset := range assets {
+		if strings.EqualFold(asset.GetName(), assetName) {
+			return asset.GetURL()
+		}
+	}
+
+	return ""
+}
@@ -55,3 +55,19 @@ func TestGetAssetbyName(t *testing.T) {
 		t.Fatalf("%s should = %s", assetName, name)
 	}
 }
+
+func TestGetAssetAPIUrl(t *testing.T) {
+	var assetName = "My_Awesome_File"
+	var apiUrl = "https://api.github.com/repos/org/repo/releases/assets/1"
+
+	assets := createTestData(assetName)
+	assets[len(assets)-1].URL = &apiUrl
+
+	if got := GetAssetAPIUrl("my_awesome_file", assets); got != apiUrl {
+		t.Fatalf("Expected %s got %s", apiUrl, got)
+	}
+
+	if got := GetAssetAPIUrl("missing", assets); got != "" {
+		t.Fatalf("Expected empty url for missing asset got %s", got)
+	}
+}
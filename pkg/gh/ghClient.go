@@ -1,7 +1,7 @@
 package gh
 
 import (
-	"context"
+	"net/http"
 	"net/url"
 	"os"
 
@@ -13,17 +13,9 @@ import (
 // GetGHClient will get a go-github client with auth for api access
 func GetGHCLient(baseUrl string, tokenvar string) *github.Client {
 
-	ghUrl, err := url.Parse(baseUrl)
-	if err != nil {
-		log.Fatalf("Unable to parse configured github url %s", baseUrl)
-	}
-
 	// No auth client if user does not supply envvar
 	if tokenvar == "none" || tokenvar == "" {
-		log.Tracef("Returning github client without auth")
-		gh := github.NewClient(nil)
-		gh.BaseURL = ghUrl
-		return gh
+		return GetGHClientWithToken(baseUrl, "")
 	}
 
 	ghtoken := os.Getenv(tokenvar)
@@ -34,14 +26,48 @@ func GetGHCLient(baseUrl string, tokenvar string) *github.Client {
 
 	log.Tracef("Returning github client using %s for auth", tokenvar)
 
-	ctx := context.Background()
+	return GetGHClientWithToken(baseUrl, ghtoken)
+}
+
+// GetGHClientWithToken will get a go-github client using the supplied token. An empty token returns an anonymous client
+func GetGHClientWithToken(baseUrl string, ghtoken string) *github.Client {
+
+	if ghtoken == "" {
+		log.Tracef("Returning github client without auth")
+		return GetGHClientWithTransport(baseUrl, nil, nil)
+	}
+
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: ghtoken},
 	)
 
-	tc := oauth2.NewClient(ctx, ts)
+	return GetGHClientWithTransport(baseUrl, ts, nil)
+}
+
+// GetGHClientWithTokenSource will get a go-github client that authenticates with tokens from ts. Used for tokens that expire such as github app installation tokens
+func GetGHClientWithTokenSource(baseUrl string, ts oauth2.TokenSource) *github.Client {
+	return GetGHClientWithTransport(baseUrl, ts, nil)
+}
+
+// GetGHClientWithTransport will get a go-github client that sends requests through base. If ts is nil the client is anonymous, if base is nil the default transport is used
+func GetGHClientWithTransport(baseUrl string, ts oauth2.TokenSource, base http.RoundTripper) *github.Client {
+
+	ghUrl, err := url.Parse(baseUrl)
+	if err != nil {
+		log.Fatalf("Unable to parse configured github url %s", baseUrl)
+	}
+
+	var rt http.RoundTripper = base
+	if ts != nil {
+		rt = &oauth2.Transport{Source: oauth2.ReuseTokenSource(nil, ts), Base: base}
+	}
+
+	var hc *http.Client
+	if rt != nil {
+		hc = &http.Client{Transport: rt}
+	}
 
-	gh := github.NewClient(tc)
+	gh := github.NewClient(hc)
 	gh.BaseURL = ghUrl
 
 	return gh
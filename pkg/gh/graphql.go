@@ -0,0 +1,164 @@
+package gh
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// GraphQLBatchSize is the number of repositories requested in a single graphql query
+const GraphQLBatchSize = 50
+
+// ReleaseQuery identifies a release to look up. An empty Tag requests the latest release
+type ReleaseQuery struct {
+	Repo string // org/project
+	Tag  string
+}
+
+const releaseFields = `tagName description createdAt releaseAssets(first: 100) { nodes { databaseId name downloadUrl contentType size } }`
+
+type gqlAsset struct {
+	DatabaseId  int64  `json:"databaseId"`
+	Name        string `json:"name"`
+	DownloadUrl string `json:"downloadUrl"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
+
+type gqlRelease struct {
+	TagName       string    `json:"tagName"`
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ReleaseAssets struct {
+		Nodes []gqlAsset `json:"nodes"`
+	} `json:"releaseAssets"`
+}
+
+type gqlRepository struct {
+	LatestRelease *gqlRelease `json:"latestRelease"`
+	Release       *gqlRelease `json:"release"`
+}
+
+type gqlResponse struct {
+	Data   map[string]*gqlRepository `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// toRepositoryRelease converts a graphql release to the rest type so results can be used in place of a rest lookup.
+// graphql has no rest url for assets, so it is built from the asset id under base for repo. Private assets are fetched from it
+func (r *gqlRelease) toRepositoryRelease(base *url.URL, repo string) *github.RepositoryRelease {
+
+	rel := &github.RepositoryRelease{
+		TagName:   github.String(r.TagName),
+		Body:      github.String(r.Description),
+		CreatedAt: &github.Timestamp{Time: r.CreatedAt},
+	}
+
+	for _, a := range r.ReleaseAssets.Nodes {
+		asset := &github.ReleaseAsset{
+			Name:               github.String(a.Name),
+			BrowserDownloadURL: github.String(a.DownloadUrl),
+			ContentType:        github.String(a.ContentType),
+			Size:               github.Int(a.Size),
+		}
+		if a.DatabaseId != 0 {
+			asset.ID = github.Int64(a.DatabaseId)
+			asset.URL = github.String(base.JoinPath("repos", repo, "releases", "assets", strconv.FormatInt(a.DatabaseId, 10)).String())
+		}
+		rel.Assets = append(rel.Assets, asset)
+	}
+
+	return rel
+}
+
+// graphqlURL returns the graphql endpoint for a rest base url. github.com serves graphql from api.github.com/graphql
+// while github enterprise serves rest from /api/v3/ and graphql from /api/graphql
+func graphqlURL(base *url.URL) string {
+	if strings.HasSuffix(base.Path, "/api/v3/") {
+		u := *base
+		u.Path = strings.TrimSuffix(base.Path, "v3/") + "graphql"
+		return u.String()
+	}
+	return base.ResolveReference(&url.URL{Path: "graphql"}).String()
+}
+
+// buildReleaseQuery returns a query requesting each release under an alias of its index
+func buildReleaseQuery(queries []ReleaseQuery) string {
+
+	var b strings.Builder
+
+	b.WriteString("query {")
+
+	for i, q := range queries {
+		org, project := getOR(q.Repo)
+		fmt.Fprintf(&b, " r%d: repository(owner: %q, name: %q) {", i, org, project)
+		if q.Tag == "" {
+			fmt.Fprintf(&b, " latestRelease { %s }", releaseFields)
+		} else {
+			fmt.Fprintf(&b, " release(tagName: %q) { %s }", q.Tag, releaseFields)
+		}
+		b.WriteString(" }")
+	}
+
+	b.WriteString(" }")
+
+	return b.String()
+}
+
+// GetReleasesGraphQL looks up releases for queries in batches of GraphQLBatchSize. The result is keyed by the index of the query,
+// releases that could not be found are absent from the result. Errors for individual repositories do not fail the batch.
+func GetReleasesGraphQL(ctx context.Context, ghClient *github.Client, queries []ReleaseQuery) (map[int]*github.RepositoryRelease, error) {
+
+	releases := make(map[int]*github.RepositoryRelease)
+	endpoint := graphqlURL(ghClient.BaseURL)
+
+	for start := 0; start < len(queries); start += GraphQLBatchSize {
+
+		end := start + GraphQLBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+		batch := queries[start:end]
+
+		req, err := ghClient.NewRequest("POST", endpoint, map[string]string{"query": buildReleaseQuery(batch)})
+		if err != nil {
+			return releases, err
+		}
+
+		var resp gqlResponse
+		if _, err := ghClient.Do(ctx, req, &resp); err != nil {
+			return releases, err
+		}
+
+		// Missing repositories are reported as errors alongside the data for the rest of the batch
+		for _, e := range resp.Errors {
+			log.Debugf("graphql release lookup - %s", e.Message)
+		}
+
+		for i := range batch {
+			repo, ok := resp.Data[fmt.Sprintf("r%d", i)]
+			if !ok || repo == nil {
+				continue
+			}
+
+			rel := repo.LatestRelease
+			if batch[i].Tag != "" {
+				rel = repo.Release
+			}
+
+			if rel != nil {
+				releases[start+i] = rel.toRepositoryRelease(ghClient.BaseURL, batch[i].Repo)
+			}
+		}
+	}
+
+	return releases, nil
+}
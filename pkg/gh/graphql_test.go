@@ -0,0 +1,118 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGraphqlURL(t *testing.T) {
+	var tests = []struct {
+		base     string
+		expected string
+	}{
+		{"https://api.github.com/", "https://api.github.com/graphql"},
+		{"https://ghe.example.com/api/v3/", "https://ghe.example.com/api/graphql"},
+	}
+
+	for _, test := range tests {
+		u, _ := url.Parse(test.base)
+		if got := graphqlURL(u); got != test.expected {
+			t.Fatalf("Expected %s got %s", test.expected, got)
+		}
+	}
+}
+
+func TestGetReleasesGraphQL(t *testing.T) {
+
+	var requests atomic.Int64
+	alias := regexp.MustCompile(`(r\d+): repository\(owner: "([^"]+)", name: "([^"]+)"\) \{ (latestRelease|release\(tagName: "([^"]+)"\))`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		requests.Add(1)
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Unable to decode query %s", err)
+		}
+
+		data := make(map[string]any)
+		for _, m := range alias.FindAllStringSubmatch(body["query"], -1) {
+			if m[3] == "missing" {
+				data[m[1]] = nil
+				continue
+			}
+
+			tag := "v1.0.0"
+			field := "latestRelease"
+			if m[5] != "" {
+				tag, field = m[5], "release"
+			}
+
+			data[m[1]] = map[string]any{field: map[string]any{
+				"tagName":     tag,
+				"description": "notes for " + m[2] + "/" + m[3],
+				"createdAt":   "2024-01-02T03:04:05Z",
+				"releaseAssets": map[string]any{"nodes": []map[string]any{
+					{"databaseId": 100 + len(m[3]), "name": m[3] + "_linux_amd64.tar.gz", "downloadUrl": "https://example.com/" + m[3], "contentType": "application/gzip", "size": 10},
+				}},
+			}}
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"data": data, "errors": []map[string]string{{"message": "Could not resolve to a Repository"}}})
+	}))
+	defer srv.Close()
+
+	ghClient := GetGHClientWithToken(srv.URL+"/", "token")
+
+	var queries []ReleaseQuery
+	for i := 0; i < GraphQLBatchSize+10; i++ {
+		queries = append(queries, ReleaseQuery{Repo: fmt.Sprintf("org/repo%d", i)})
+	}
+	queries = append(queries, ReleaseQuery{Repo: "org/missing"}, ReleaseQuery{Repo: "org/tagged", Tag: "v0.1.0"})
+
+	releases, err := GetReleasesGraphQL(context.Background(), ghClient, queries)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if requests.Load() != 2 {
+		t.Fatalf("Expected 2 batched requests got %d", requests.Load())
+	}
+
+	if len(releases) != len(queries)-1 {
+		t.Fatalf("Expected %d releases got %d", len(queries)-1, len(releases))
+	}
+
+	if _, ok := releases[GraphQLBatchSize+10]; ok {
+		t.Fatalf("Expected missing repository to be absent")
+	}
+
+	rel := releases[GraphQLBatchSize+5]
+	if rel.GetTagName() != "v1.0.0" || rel.GetBody() != "notes for org/repo55" || rel.GetCreatedAt().Unix() != 1704164645 {
+		t.Fatalf("Unexpected release %+v", rel)
+	}
+
+	if len(rel.Assets) != 1 || !strings.HasSuffix(rel.Assets[0].GetBrowserDownloadURL(), "repo55") {
+		t.Fatalf("Unexpected assets %+v", rel.Assets)
+	}
+
+	// Private assets are downloaded from the rest api, so graphql assets need its url
+	if want := srv.URL + "/repos/org/repo55/releases/assets/106"; GetAssetAPIUrl("repo55_linux_amd64.tar.gz", rel.Assets) != want {
+		t.Fatalf("Expected asset api url %s got %s", want, rel.Assets[0].GetURL())
+	}
+
+	if releases[GraphQLBatchSize+11].GetTagName() != "v0.1.0" {
+		t.Fatalf("Expected tagged release got %s", releases[GraphQLBatchSize+11].GetTagName())
+	}
+}
@@ -2,11 +2,17 @@ package gh
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/google/go-github/v50/github"
 	log "github.com/rjbrown57/binman/pkg/logging"
 )
 
+var (
+	ErrLimitsExceeded = errors.New("Github API limits exceeded")
+)
+
 func getLimits(ghClient *github.Client) (*github.RateLimits, error) {
 
 	ctx := context.Background()
@@ -32,7 +38,8 @@ func ShowLimits(ghClient *github.Client) error {
 	return nil
 }
 
-// CheckLimits will verify you have not exceeded your quota
+// CheckLimits will verify you have not exceeded your quota. ErrLimitsExceeded is returned if no requests remain
+// Note this spends an api request, binman tracks limits from response headers during syncs instead
 func CheckLimits(ghClient *github.Client) error {
 
 	limits, err := getLimits(ghClient)
@@ -41,7 +48,7 @@ func CheckLimits(ghClient *github.Client) error {
 	}
 
 	if limits.Core.Remaining == 0 {
-		log.Fatalf("Github API limits exceeded. %s", limits.Core.String())
+		return fmt.Errorf("%w. %s", ErrLimitsExceeded, limits.Core.String())
 	}
 
 	return nil
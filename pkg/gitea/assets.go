@@ -0,0 +1,30 @@
+package gitea
+
+import (
+	"strings"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// GetAssetbyName returns the name and download url of the asset matching relFileName
+func GetAssetbyName(relFileName string, assets []*Asset) (string, string) {
+	for _, asset := range assets {
+		if asset.Name == relFileName {
+			log.Debugf("Selected asset == %+v\n", asset.Name)
+			return asset.Name, asset.BrowserDownloadURL
+		}
+	}
+
+	return "", ""
+}
+
+// GiteaGetAssetData will create a map of names + download urls
+func GiteaGetAssetData(assets []*Asset) map[string]string {
+	m := make(map[string]string)
+
+	for _, asset := range assets {
+		m[strings.ToLower(asset.Name)] = asset.BrowserDownloadURL
+	}
+
+	return m
+}
@@ -0,0 +1,28 @@
+package gitea
+
+import (
+	"testing"
+)
+
+var testAssets = []*Asset{
+	{Name: "Tool_linux_amd64.tar.gz", BrowserDownloadURL: "https://example.com/Tool_linux_amd64.tar.gz"},
+	{Name: "tool_darwin_arm64.tar.gz", BrowserDownloadURL: "https://example.com/tool_darwin_arm64.tar.gz"},
+}
+
+func TestGetAssetbyName(t *testing.T) {
+	name, url := GetAssetbyName("tool_darwin_arm64.tar.gz", testAssets)
+	if name != "tool_darwin_arm64.tar.gz" || url != "https://example.com/tool_darwin_arm64.tar.gz" {
+		t.Fatalf("Unexpected asset %s %s", name, url)
+	}
+
+	if name, _ := GetAssetbyName("missing", testAssets); name != "" {
+		t.Fatalf("Expected no asset got %s", name)
+	}
+}
+
+func TestGiteaGetAssetData(t *testing.T) {
+	m := GiteaGetAssetData(testAssets)
+	if m["tool_linux_amd64.tar.gz"] != "https://example.com/Tool_linux_amd64.tar.gz" || len(m) != 2 {
+		t.Fatalf("Unexpected asset data %v", m)
+	}
+}
@@ -0,0 +1,86 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// apiPath is the path gitea and forgejo serve their api from
+const apiPath = "/api/v1/"
+
+// Client is a minimal gitea/forgejo api client covering the release endpoints binman uses
+type Client struct {
+	BaseURL    *url.URL // api url, always ending in /api/v1/
+	token      string
+	httpClient *http.Client
+}
+
+// ErrorResponse is returned for non 2xx api responses
+type ErrorResponse struct {
+	StatusCode int
+	Message    string `json:"message"`
+	URL        string
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s returned %d - %s", e.URL, e.StatusCode, e.Message)
+}
+
+// GetGiteaClient will get a gitea client for baseUrl, the url of the gitea instance. An empty token returns an anonymous client,
+// if base is nil the default transport is used
+func GetGiteaClient(baseUrl string, token string, base http.RoundTripper) *Client {
+
+	u, err := url.Parse(baseUrl)
+	if err != nil || u.Host == "" {
+		log.Fatalf("Unable to parse configured gitea url %s", baseUrl)
+	}
+
+	// Accept either the instance url or the api url
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(apiPath, "/")) + apiPath
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Client{BaseURL: u, token: token, httpClient: &http.Client{Transport: base}}
+}
+
+// get will request path relative to the api url and decode the json response into v
+func (c *Client) get(ctx context.Context, path string, v any) error {
+
+	u := c.BaseURL.ResolveReference(&url.URL{Path: path})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &ErrorResponse{StatusCode: resp.StatusCode, URL: u.String()}
+		body, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(body, e) != nil || e.Message == "" {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,77 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetGiteaClient(t *testing.T) {
+	for _, base := range []string{"https://codeberg.org", "https://codeberg.org/", "https://codeberg.org/api/v1", "https://codeberg.org/api/v1/"} {
+		if got := GetGiteaClient(base, "", nil).BaseURL.String(); got != "https://codeberg.org/api/v1/" {
+			t.Fatalf("Expected https://codeberg.org/api/v1/ from %s got %s", base, got)
+		}
+	}
+}
+
+func giteaServer(t *testing.T) *httptest.Server {
+	release := `{"id": 7, "tag_name": "%s", "body": "notes", "created_at": "2024-01-02T03:04:05Z", "assets": [{"id": 1, "name": "tool_linux_amd64.tar.gz", "size": 10, "browser_download_url": "https://codeberg.org/org/tool/releases/download/%s/tool_linux_amd64.tar.gz"}]}`
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token secret" {
+			t.Errorf("Unexpected authorization header %s", got)
+		}
+
+		switch r.URL.Path {
+		case "/api/v1/repos/org/tool/releases/latest":
+			fmt.Fprintf(w, release, "v1.0.0", "v1.0.0")
+		case "/api/v1/repos/org/tool/releases/tags/v0.9.0":
+			fmt.Fprintf(w, release, "v0.9.0", "v0.9.0")
+		case "/api/v1/repos/org/tool/releases/7/assets":
+			fmt.Fprint(w, `[{"id": 1, "name": "tool_linux_amd64.tar.gz"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "The target couldn't be found."}`)
+		}
+	}))
+}
+
+func TestReleases(t *testing.T) {
+
+	srv := giteaServer(t)
+	defer srv.Close()
+
+	c := GetGiteaClient(srv.URL, "secret", nil)
+	ctx := context.Background()
+
+	rel, err := c.GetLatestRelease(ctx, "org/tool")
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if rel.TagName != "v1.0.0" || rel.Body != "notes" || rel.CreatedAt.Unix() != 1704164645 || len(rel.Assets) != 1 {
+		t.Fatalf("Unexpected release %+v", rel)
+	}
+
+	rel, err = c.GetReleaseByTag(ctx, "org/tool", "v0.9.0")
+	if err != nil || rel.TagName != "v0.9.0" {
+		t.Fatalf("Unexpected release %+v, %v", rel, err)
+	}
+
+	assets, err := c.ListReleaseAssets(ctx, "org/tool", 7)
+	if err != nil || len(assets) != 1 || assets[0].Name != "tool_linux_amd64.tar.gz" {
+		t.Fatalf("Unexpected assets %+v, %v", assets, err)
+	}
+
+	_, err = c.GetLatestRelease(ctx, "org/missing")
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.StatusCode != http.StatusNotFound || e.Message != "The target couldn't be found." {
+		t.Fatalf("Expected not found error got %v", err)
+	}
+
+	if _, err = c.GetLatestRelease(ctx, "badrepo"); err == nil {
+		t.Fatalf("Expected error for malformed repo")
+	}
+}
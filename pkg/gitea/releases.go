@@ -0,0 +1,78 @@
+package gitea
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Release is a gitea release
+type Release struct {
+	ID        int64     `json:"id"`
+	TagName   string    `json:"tag_name"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Assets    []*Asset  `json:"assets"`
+}
+
+// Asset is a file attached to a gitea release
+type Asset struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// repoPath returns the api path for repo. repo must be in the format org/project
+func repoPath(repo string) (string, error) {
+	org, project, found := strings.Cut(repo, "/")
+	if !found || org == "" || project == "" {
+		return "", fmt.Errorf("%s should be in the format org/repo", repo)
+	}
+	return fmt.Sprintf("repos/%s/%s", url.PathEscape(org), url.PathEscape(project)), nil
+}
+
+// GetLatestRelease returns the most recent non draft, non prerelease release of repo
+func (c *Client) GetLatestRelease(ctx context.Context, repo string) (*Release, error) {
+	p, err := repoPath(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var rel Release
+	if err := c.get(ctx, p+"/releases/latest", &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// GetReleaseByTag returns the release of repo for tag
+func (c *Client) GetReleaseByTag(ctx context.Context, repo string, tag string) (*Release, error) {
+	p, err := repoPath(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var rel Release
+	if err := c.get(ctx, p+"/releases/tags/"+url.PathEscape(tag), &rel); err != nil {
+		return nil, err
+	}
+	return &rel, nil
+}
+
+// ListReleaseAssets returns the assets attached to release id of repo
+func (c *Client) ListReleaseAssets(ctx context.Context, repo string, id int64) ([]*Asset, error) {
+	p, err := repoPath(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []*Asset
+	if err := c.get(ctx, fmt.Sprintf("%s/releases/%d/assets", p, id), &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
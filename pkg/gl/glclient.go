@@ -1,6 +1,7 @@
 package gl
 
 import (
+	"net/http"
 	"os"
 
 	"net/url"
@@ -10,16 +11,36 @@ import (
 )
 
 func GetGLClient(baseUrl string, tokenvar string) *gitlab.Client {
+	// if tokenvar is unset we will use anonymous auth
+	return GetGLClientWithToken(baseUrl, os.Getenv(tokenvar), tokenvar == "CI_JOB_TOKEN")
+}
+
+// GetGLClientWithToken will get a gitlab client using the supplied token. Set job if the token is a gitlab CI job token
+func GetGLClientWithToken(baseUrl string, glToken string, job bool) *gitlab.Client {
+	return GetGLClientWithTransport(baseUrl, glToken, job, nil)
+}
+
+// GetGLClientWithTransport will get a gitlab client that sends requests through base. If base is nil the default transport is used
+func GetGLClientWithTransport(baseUrl string, glToken string, job bool, base http.RoundTripper) *gitlab.Client {
 
 	glUrl, err := url.Parse(baseUrl)
 	if err != nil {
 		log.Fatalf("Unable to parse configured gitlab url %s", baseUrl)
 	}
 
-	// if tokenvar is unset we will use anonymous auth
-	glToken := os.Getenv(tokenvar)
+	var gl *gitlab.Client
+
+	opts := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(glUrl.String())}
+	if base != nil {
+		opts = append(opts, gitlab.WithHTTPClient(&http.Client{Transport: base}))
+	}
 
-	gl, err := gitlab.NewClient(glToken, gitlab.WithBaseURL(glUrl.String()))
+	// Job tokens from gitlab CI are sent with a different header than personal access tokens
+	if job {
+		gl, err = gitlab.NewJobClient(glToken, opts...)
+	} else {
+		gl, err = gitlab.NewClient(glToken, opts...)
+	}
 
 	if err != nil {
 		log.Fatalf("Error getting gitlab client for %s\n", baseUrl)
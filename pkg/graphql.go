@@ -0,0 +1,73 @@
+package binman
+
+import (
+	"context"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/rjbrown57/binman/pkg/gh"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// graphqlEnabled returns true if the release should be looked up in a graphql batch. Applied releases are never looked up
+func (r *BinmanRelease) graphqlEnabled() bool {
+	if r.source == nil || !r.source.Graphql || r.source.Apitype != "github" || r.VersionUrl != "" || r.applyEntry != nil {
+		return false
+	}
+
+	return r.QueryType == "release" || r.QueryType == "releasebytag"
+}
+
+// prefetchReleases looks up releases from github sources with graphql enabled in batches. The result is keyed by release index,
+// releases missing from the result are looked up individually by their action chain
+func (config *BMConfig) prefetchReleases() map[int]*github.RepositoryRelease {
+
+	prefetched := make(map[int]*github.RepositoryRelease)
+
+	// Group releases by source so each batch uses the source's shared client
+	indexes := make(map[string][]int)
+	var sources []string
+
+	for index := range config.Releases {
+		rel := &config.Releases[index]
+		if !rel.graphqlEnabled() {
+			continue
+		}
+		if _, ok := indexes[rel.source.Name]; !ok {
+			sources = append(sources, rel.source.Name)
+		}
+		indexes[rel.source.Name] = append(indexes[rel.source.Name], index)
+	}
+
+	for _, name := range sources {
+
+		first := &config.Releases[indexes[name][0]]
+
+		// The github graphql api does not allow anonymous access
+		if !first.source.hasCredentials() {
+			log.Warnf("graphql is enabled for source %s but no credentials are configured, falling back to rest lookups", name)
+			continue
+		}
+
+		queries := make([]gh.ReleaseQuery, 0, len(indexes[name]))
+		for _, index := range indexes[name] {
+			q := gh.ReleaseQuery{Repo: config.Releases[index].Repo}
+			if config.Releases[index].QueryType == "releasebytag" {
+				q.Tag = config.Releases[index].Version
+			}
+			queries = append(queries, q)
+		}
+
+		log.Debugf("Looking up %d releases from %s with graphql", len(queries), name)
+
+		releases, err := gh.GetReleasesGraphQL(context.Background(), first.source.ghClient(first.apiCache()), queries)
+		if err != nil {
+			log.Warnf("graphql lookup for source %s failed, falling back to rest lookups - %s", name, err)
+		}
+
+		for i, rel := range releases {
+			prefetched[indexes[name][i]] = rel
+		}
+	}
+
+	return prefetched
+}
@@ -0,0 +1,51 @@
+package binman
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v50/github"
+)
+
+func TestGraphqlEnabled(t *testing.T) {
+
+	var tests = []struct {
+		caseName string
+		rel      BinmanRelease
+		expected bool
+	}{
+		{"enabled", BinmanRelease{QueryType: "release", source: &Source{Apitype: "github", Graphql: true}}, true},
+		{"bytag", BinmanRelease{QueryType: "releasebytag", source: &Source{Apitype: "github", Graphql: true}}, true},
+		{"disabled", BinmanRelease{QueryType: "release", source: &Source{Apitype: "github"}}, false},
+		{"gitlab", BinmanRelease{QueryType: "release", source: &Source{Apitype: "gitlab", Graphql: true}}, false},
+		{"nosource", BinmanRelease{QueryType: "release"}, false},
+		{"applied", BinmanRelease{QueryType: "releasebytag", source: &Source{Apitype: "github", Graphql: true}, applyEntry: &PlanEntry{}}, false},
+	}
+
+	for _, test := range tests {
+		if got := test.rel.graphqlEnabled(); got != test.expected {
+			t.Fatalf("%s: expected %t got %t", test.caseName, test.expected, got)
+		}
+	}
+}
+
+func TestGetGHReleaseActionPrefetched(t *testing.T) {
+
+	rel := BinmanRelease{
+		Repo:      "rjbrown57/binman",
+		QueryType: "release",
+		relData: &github.RepositoryRelease{
+			TagName:   github.String("v1.2.3"),
+			Body:      github.String("notes"),
+			CreatedAt: &github.Timestamp{},
+		},
+	}
+
+	// A nil client would panic if a rest lookup was attempted
+	if err := rel.AddGetGHReleaseAction(nil).execute(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	if rel.Version != "v1.2.3" || rel.relNotes != "notes" {
+		t.Fatalf("Expected prefetched release data, got version %s notes %s", rel.Version, rel.relNotes)
+	}
+}
@@ -0,0 +1,153 @@
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"net/http"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// Store persists cached responses. Get should return an error if key is not present
+type Store interface {
+	Get(key string) ([]byte, error)
+	Set(key string, data []byte) error
+}
+
+// entry is a cached response
+type entry struct {
+	ETag         string
+	LastModified string
+	Response     []byte // The full response as written by http.Response.Write
+}
+
+// Transport sends conditional requests for GET requests with a cached response. When the upstream responds
+// with 304 Not Modified the cached response is returned instead. Github does not count 304s against the rate limit.
+type Transport struct {
+	Base  http.RoundTripper
+	Store Store
+}
+
+// NewTransport will return a Transport wrapping base. If base is nil http.DefaultTransport is used
+func NewTransport(base http.RoundTripper, store Store) *Transport {
+	return &Transport{Base: base, Store: store}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// Key returns the cache key for a request. Requests are cached per url and Accept header
+func Key(req *http.Request) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(req.Method+" "+req.URL.String()+" "+req.Header.Get("Accept"))))
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.Method != http.MethodGet || t.Store == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	key := Key(req)
+	cached, hit := t.load(key)
+
+	if hit {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && hit:
+		log.Debugf("%s not modified, using cached response", req.URL)
+		notModified := resp
+		resp, err = http.ReadResponse(bufio.NewReader(bytes.NewReader(cached.Response)), req)
+		if err != nil {
+			return notModified, nil
+		}
+		// Keep the fresh headers, rate limit info etc, from the 304 response
+		for k, v := range notModified.Header {
+			resp.Header[k] = v
+		}
+		notModified.Body.Close()
+		return resp, nil
+	case resp.StatusCode == http.StatusOK:
+		return t.save(key, resp), nil
+	}
+
+	return resp, nil
+}
+
+// load returns the cached entry for key if present
+func (t *Transport) load(key string) (entry, bool) {
+	var e entry
+
+	data, err := t.Store.Get(key)
+	if err != nil || len(data) == 0 {
+		return e, false
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		log.Debugf("Unable to decode cached response %s - %s", key, err)
+		return e, false
+	}
+
+	return e, true
+}
+
+// save caches resp if it can be revalidated. The body is consumed so a replacement response is returned
+func (t *Transport) save(key string, resp *http.Response) *http.Response {
+
+	e := entry{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if e.ETag == "" && e.LastModified == "" {
+		return resp
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		log.Debugf("Unable to read response body for caching - %s", err)
+		return resp
+	}
+
+	// Write the response as if it had not been read so it can be replayed with http.ReadResponse
+	var buf bytes.Buffer
+	stored := *resp
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+	if err := stored.Write(&buf); err != nil {
+		log.Debugf("Unable to serialize response for caching - %s", err)
+		return resp
+	}
+	e.Response = buf.Bytes()
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(e); err != nil {
+		log.Debugf("Unable to encode cached response - %s", err)
+		return resp
+	}
+
+	if err := t.Store.Set(key, data.Bytes()); err != nil {
+		log.Debugf("Unable to store cached response %s - %s", key, err)
+	}
+
+	return resp
+}
@@ -0,0 +1,115 @@
+package httpcache
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (m *memStore) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.data[key]
+	if !ok {
+		return nil, fmt.Errorf("%s not found", key)
+	}
+	return d, nil
+}
+
+func (m *memStore) Set(key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = data
+	return nil
+}
+
+func get(t *testing.T, c *http.Client, url string) (int, string) {
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestTransportRevalidates(t *testing.T) {
+	var full, notModified atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "10")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "11")
+		fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, &memStore{data: make(map[string][]byte)})}
+
+	for i := 0; i < 3; i++ {
+		status, body := get(t, c, srv.URL)
+		if status != http.StatusOK || body != `{"tag_name":"v1.0.0"}` {
+			t.Fatalf("Unexpected response %d %s", status, body)
+		}
+	}
+
+	if full.Load() != 1 || notModified.Load() != 2 {
+		t.Fatalf("Expected 1 full and 2 not modified responses, got %d and %d", full.Load(), notModified.Load())
+	}
+
+	// Headers from the 304 should replace the cached ones
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-RateLimit-Remaining") != "10" {
+		t.Fatalf("Expected fresh rate limit header got %s", resp.Header.Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestTransportSkipsUncacheable(t *testing.T) {
+	var requests atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("Unexpected conditional request")
+		}
+		fmt.Fprint(w, "no validators")
+	}))
+	defer srv.Close()
+
+	store := &memStore{data: make(map[string][]byte)}
+	c := &http.Client{Transport: NewTransport(nil, store)}
+
+	for i := 0; i < 2; i++ {
+		if status, body := get(t, c, srv.URL); status != http.StatusOK || body != "no validators" {
+			t.Fatalf("Unexpected response %d %s", status, body)
+		}
+	}
+
+	if len(store.data) != 0 {
+		t.Fatalf("Expected nothing cached got %d entries", len(store.data))
+	}
+}
@@ -0,0 +1,327 @@
+package httpindex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	semver "github.com/Masterminds/semver/v3"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// versionRx finds a version in an index entry name. e.g terraform_1.9.0, v3.1.0/ or tool-1.2.3-linux-amd64.tar.gz
+var versionRx = regexp.MustCompile(`(?i)v?\d+\.\d+(\.\d+)?(-(alpha|beta|rc|pre|preview|dev)[0-9a-z.]*)?(\+[0-9a-z.]+)?`)
+
+// hrefRx finds links in an html index page. Query strings and fragments such as autoindex sort links are ignored
+var hrefRx = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)["']`)
+
+// StatusError is returned when an index answers with anything other than 200
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %d", e.URL, e.StatusCode)
+}
+
+// Entry is a single file or directory listed by an index
+type Entry struct {
+	Name    string // last path segment of the entry
+	URL     string // absolute url of the entry
+	Dir     bool
+	Version *semver.Version // nil if the entry name does not contain a version
+}
+
+// Release is a version found in an index along with the files published for it
+type Release struct {
+	Version string
+	URL     string // url of the version directory, or the index if files are listed flat
+	Assets  []Entry
+}
+
+// Client lists http directory indexes
+type Client struct {
+	httpClient *http.Client
+	token      string
+}
+
+// GetIndexClient will get a client for http indexes. If token is set it is sent as a bearer token, if base is nil the default transport is used
+func GetIndexClient(token string, base http.RoundTripper) *Client {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Client{httpClient: &http.Client{Transport: base}, token: token}
+}
+
+// List returns the entries of an index. Html pages, json lists of names or objects with a name field (nginx autoindex_format json),
+// and json objects with a versions map (releases.hashicorp.com index.json) are supported
+func (c *Client) List(ctx context.Context, indexURL string) ([]Entry, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: indexURL, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve links against the url we were redirected to. e.g nginx will redirect /dir to /dir/
+	base := resp.Request.URL
+
+	trimmed := strings.TrimSpace(string(body))
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") || strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		return parseJSON(base, body)
+	}
+
+	return parseHTML(base, body), nil
+}
+
+// newEntry returns the entry for ref resolved against base, or false if it is not below base
+func newEntry(base *url.URL, ref string, dir bool) (Entry, bool) {
+
+	r, err := url.Parse(ref)
+	if err != nil {
+		return Entry{}, false
+	}
+
+	u := base.ResolveReference(r)
+
+	// Only entries below the index are considered. This drops parent, sibling and external links
+	basePath := base.Path
+	if !strings.HasSuffix(basePath, "/") {
+		basePath = path.Dir(basePath) + "/"
+	}
+	if u.Host != base.Host || !strings.HasPrefix(u.Path, basePath) || u.Path == basePath {
+		return Entry{}, false
+	}
+
+	dir = dir || strings.HasSuffix(u.Path, "/")
+	if dir && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	e := Entry{Name: path.Base(u.Path), URL: u.String(), Dir: dir}
+
+	if m := versionRx.FindString(e.Name); m != "" {
+		if v, err := semver.NewVersion(m); err == nil {
+			e.Version = v
+		}
+	}
+
+	return e, true
+}
+
+func parseHTML(base *url.URL, body []byte) []Entry {
+
+	var entries []Entry
+	seen := make(map[string]bool)
+
+	for _, m := range hrefRx.FindAllSubmatch(body, -1) {
+		e, ok := newEntry(base, string(m[1]), false)
+		if !ok || seen[e.URL] {
+			continue
+		}
+		seen[e.URL] = true
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func parseJSON(base *url.URL, body []byte) ([]Entry, error) {
+
+	var entries []Entry
+
+	add := func(name string, dir bool) {
+		if e, ok := newEntry(base, url.PathEscape(name), dir); ok {
+			entries = append(entries, e)
+		}
+	}
+
+	var names []string
+	if err := json.Unmarshal(body, &names); err == nil {
+		for _, n := range names {
+			add(n, false)
+		}
+		return entries, nil
+	}
+
+	var objects []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &objects); err == nil {
+		for _, o := range objects {
+			add(o.Name, o.Type == "directory")
+		}
+		return entries, nil
+	}
+
+	var versions struct {
+		Versions map[string]json.RawMessage `json:"versions"`
+	}
+	if err := json.Unmarshal(body, &versions); err != nil {
+		return nil, fmt.Errorf("unsupported json index - %w", err)
+	}
+	for v := range versions.Versions {
+		add(v, true)
+	}
+
+	return entries, nil
+}
+
+// releases groups entries by version, newest first. Directories for a version are listed to find its files
+func (c *Client) releases(ctx context.Context, indexURL string) ([]*semver.Version, map[string][]Entry, error) {
+
+	entries, err := c.List(ctx, indexURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make(map[string][]Entry)
+	var versions []*semver.Version
+
+	for _, e := range entries {
+		if e.Version == nil {
+			continue
+		}
+		key := e.Version.Original()
+		if _, exists := groups[key]; !exists {
+			versions = append(versions, e.Version)
+		}
+		groups[key] = append(groups[key], e)
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+
+	return versions, groups, nil
+}
+
+// release returns the release for a group of entries. A version directory is listed for its files
+func (c *Client) release(ctx context.Context, indexURL string, v *semver.Version, group []Entry) (*Release, error) {
+
+	rel := &Release{Version: v.Original(), URL: indexURL}
+
+	for _, e := range group {
+		if e.Dir {
+			log.Debugf("Listing version directory %s", e.URL)
+			assets, err := c.List(ctx, e.URL)
+			if err != nil {
+				return nil, err
+			}
+			rel.URL = e.URL
+			for _, a := range assets {
+				if !a.Dir {
+					rel.Assets = append(rel.Assets, a)
+				}
+			}
+			return rel, nil
+		}
+		rel.Assets = append(rel.Assets, e)
+	}
+
+	return rel, nil
+}
+
+// ListTags returns every version listed by the index
+func (c *Client) ListTags(ctx context.Context, indexURL string) ([]string, error) {
+
+	versions, _, err := c.releases(ctx, indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(versions))
+	for _, v := range versions {
+		tags = append(tags, v.Original())
+	}
+
+	return tags, nil
+}
+
+// GetLatestRelease returns the highest stable version listed by the index. Prereleases and versions with build metadata are skipped
+func (c *Client) GetLatestRelease(ctx context.Context, indexURL string) (*Release, error) {
+
+	versions, groups, err := c.releases(ctx, indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		if v.Prerelease() != "" || v.Metadata() != "" {
+			continue
+		}
+		return c.release(ctx, indexURL, v, groups[v.Original()])
+	}
+
+	return nil, fmt.Errorf("no versions found in %s", indexURL)
+}
+
+// GetReleaseByTag returns the release for tag. Tags match with or without a leading v
+func (c *Client) GetReleaseByTag(ctx context.Context, indexURL string, tag string) (*Release, error) {
+
+	want, err := semver.NewVersion(tag)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid version - %w", tag, err)
+	}
+
+	versions, groups, err := c.releases(ctx, indexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		if v.Equal(want) && v.Metadata() == want.Metadata() {
+			return c.release(ctx, indexURL, v, groups[v.Original()])
+		}
+	}
+
+	return nil, fmt.Errorf("version %s not found in %s", tag, indexURL)
+}
+
+// GetAssetbyName returns the name and url of the asset matching relFileName
+func GetAssetbyName(relFileName string, assets []Entry) (string, string) {
+	for _, asset := range assets {
+		if asset.Name == relFileName {
+			log.Debugf("Selected asset == %+v\n", asset.Name)
+			return asset.Name, asset.URL
+		}
+	}
+
+	return "", ""
+}
+
+// GetAssetData will create a map of names + download urls
+func GetAssetData(assets []Entry) map[string]string {
+	m := make(map[string]string)
+
+	for _, asset := range assets {
+		m[strings.ToLower(asset.Name)] = asset.URL
+	}
+
+	return m
+}
@@ -0,0 +1,116 @@
+package httpindex
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// indexServer serves a hashicorp style html index with version directories, a flat listing, an nginx json autoindex and an index.json
+func indexServer() *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/terraform/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/terraform/":
+			fmt.Fprint(w, `<html><body><ul>
+<li><a href="../">../</a></li>
+<li><a href="/terraform/1.10.0-rc1/">terraform_1.10.0-rc1</a></li>
+<li><a href="/terraform/1.9.2/">terraform_1.9.2</a></li>
+<li><a href="/terraform/1.9.2+ent/">terraform_1.9.2+ent</a></li>
+<li><a href="/terraform/1.8.5/">terraform_1.8.5</a></li>
+<li><a href="https://www.hashicorp.com/">HashiCorp</a></li>
+</ul></body></html>`)
+		case "/terraform/1.9.2/", "/terraform/1.8.5/":
+			v := strings.Split(r.URL.Path, "/")[2]
+			fmt.Fprintf(w, `<a href="?C=N;O=D">Name</a> <a href="terraform_%[1]s_linux_amd64.zip">linux</a> <a href="terraform_%[1]s_darwin_arm64.zip">darwin</a> <a href="terraform_%[1]s_SHA256SUMS">sums</a>`, v)
+		case "/terraform/index.json":
+			fmt.Fprint(w, `{"name": "terraform", "versions": {"1.8.5": {}, "1.9.2": {}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("/flat/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<a href="tool-v2.0.0-linux-amd64.tar.gz">a</a><a href="tool-v2.0.0-linux-amd64.tar.gz.sha256">b</a><a href="tool-v2.1.0-linux-amd64.tar.gz">c</a><a href="README">d</a>`)
+	})
+
+	mux.HandleFunc("/nginx/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nginx/":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"name": "v0.1.0", "type": "directory"}, {"name": "v0.2.0", "type": "directory"}, {"name": "notes.txt", "type": "file"}]`)
+		case "/nginx/v0.2.0/":
+			fmt.Fprint(w, `["tool_linux_amd64", "tool_darwin_arm64"]`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestGetLatestRelease(t *testing.T) {
+
+	srv := indexServer()
+	defer srv.Close()
+
+	c := GetIndexClient("", nil)
+	ctx := context.Background()
+
+	var tests = []struct {
+		caseName        string
+		index           string
+		expectedVersion string
+		expectedAsset   string
+		expectedAssets  int
+	}{
+		{"directories", "/terraform/", "1.9.2", "/terraform/1.9.2/terraform_1.9.2_linux_amd64.zip", 3},
+		{"indexjson", "/terraform/index.json", "1.9.2", "/terraform/1.9.2/terraform_1.9.2_linux_amd64.zip", 3},
+		{"flat", "/flat/", "v2.1.0", "/flat/tool-v2.1.0-linux-amd64.tar.gz", 1},
+		{"nginxjson", "/nginx/", "v0.2.0", "/nginx/v0.2.0/tool_linux_amd64", 2},
+	}
+
+	for _, test := range tests {
+		rel, err := c.GetLatestRelease(ctx, srv.URL+test.index)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", test.caseName, err)
+		}
+
+		if rel.Version != test.expectedVersion || len(rel.Assets) != test.expectedAssets {
+			t.Fatalf("%s: unexpected release %+v", test.caseName, rel)
+		}
+
+		found := false
+		for _, url := range GetAssetData(rel.Assets) {
+			found = found || url == srv.URL+test.expectedAsset
+		}
+		if !found {
+			t.Fatalf("%s: expected asset %s in %+v", test.caseName, test.expectedAsset, rel.Assets)
+		}
+	}
+}
+
+func TestGetReleaseByTag(t *testing.T) {
+
+	srv := indexServer()
+	defer srv.Close()
+
+	c := GetIndexClient("", nil)
+
+	rel, err := c.GetReleaseByTag(context.Background(), srv.URL+"/terraform/", "v1.8.5")
+	if err != nil || rel.Version != "1.8.5" {
+		t.Fatalf("Unexpected release %+v, %v", rel, err)
+	}
+
+	if name, url := GetAssetbyName("terraform_1.8.5_darwin_arm64.zip", rel.Assets); url != srv.URL+"/terraform/1.8.5/"+name {
+		t.Fatalf("Unexpected asset %s %s", name, url)
+	}
+
+	if _, err = c.GetReleaseByTag(context.Background(), srv.URL+"/terraform/", "2.0.0"); err == nil {
+		t.Fatalf("Expected error for missing version")
+	}
+}
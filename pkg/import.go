@@ -0,0 +1,683 @@
+package binman
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pelletier/go-toml/v2"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rodaine/table"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest formats Import understands
+const (
+	ImportAqua = "aqua" // aqua.yaml
+	ImportEget = "eget" // eget.toml
+	ImportAsdf = "asdf" // .tool-versions
+	ImportMise = "mise" // mise.toml
+)
+
+// ImportFormats lists the formats Import understands
+var ImportFormats = []string{ImportAqua, ImportEget, ImportAsdf, ImportMise}
+
+// Import statuses
+const (
+	ImportAdded   = "added"   // the release is added to the config
+	ImportExists  = "exists"  // the release is already in the config
+	ImportSkipped = "skipped" // the entry could not be translated
+)
+
+// ImportEntry is the translation of a single tool from a manifest
+type ImportEntry struct {
+	Name    string   `json:"name"` // package, plugin or repo as it is named in the manifest
+	Repo    string   `json:"repo,omitempty"`
+	Version string   `json:"version,omitempty"` // pinned version
+	Status  string   `json:"status"`
+	Notes   []string `json:"notes,omitempty"` // settings that were not carried over, or why the entry was skipped
+
+	release BinmanRelease
+}
+
+// ImportReport is the result of translating a manifest
+type ImportReport struct {
+	From    string        `json:"from"`
+	File    string        `json:"file"`
+	Entries []ImportEntry `json:"entries"`
+	Notes   []string      `json:"notes,omitempty"` // manifest wide settings that were not carried over
+}
+
+// ImportOptions configures Import
+type ImportOptions struct {
+	Map    map[string]string // plugin or package names to repos. Takes precedence over the bundled mapping table
+	DryRun bool
+}
+
+// Releases returns the releases Import adds to the config
+func (rep *ImportReport) Releases() []BinmanRelease {
+	var releases []BinmanRelease
+	for _, e := range rep.Entries {
+		if e.Status == ImportAdded {
+			releases = append(releases, e.release)
+		}
+	}
+	return releases
+}
+
+// importer translates a manifest into the entries of rep
+type importer struct {
+	rep   *ImportReport
+	dir   string            // directory of the manifest. aqua imports are relative to it
+	repos map[string]string // user supplied name to repo mappings
+}
+
+// Import translates the manifest at file, written for the installer named by from, into releases. Releases that are not
+// already configured are added to the config unless o.DryRun is set. Anything that could not be translated is reported
+func Import(from, file string, o ImportOptions, config string) (*ImportReport, error) {
+
+	im := &importer{rep: &ImportReport{From: from, File: file}, dir: filepath.Dir(file), repos: o.Map}
+
+	data, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return nil, err
+	}
+
+	switch from {
+	case ImportAqua:
+		err = im.aqua(data, true)
+	case ImportEget:
+		err = im.eget(data)
+	case ImportAsdf:
+		err = im.asdf(data)
+	case ImportMise:
+		err = im.mise(data)
+	default:
+		return nil, fmt.Errorf("unknown format %s. Use one of %v", from, ImportFormats)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s - %w", file, err)
+	}
+
+	c := NewBMConfig(config).SetConfig(false)
+	seen := make(map[string]bool)
+
+	for index := range im.rep.Entries {
+		e := &im.rep.Entries[index]
+		if e.Status == ImportSkipped {
+			continue
+		}
+
+		switch _, err := c.findRelease(e.Repo); {
+		case seen[e.Repo]:
+			e.Status = ImportSkipped
+			e.Notes = append(e.Notes, "duplicate of an earlier entry")
+		case err == nil:
+			e.Status = ImportExists
+		default:
+			e.Status = ImportAdded
+		}
+		seen[e.Repo] = true
+	}
+
+	releases := im.rep.Releases()
+	if o.DryRun || len(releases) == 0 {
+		return im.rep, nil
+	}
+
+	return im.rep, c.addReleasesToConfig(releases...)
+}
+
+// add records a translated release
+func (im *importer) add(name string, rel BinmanRelease, notes ...string) {
+	im.rep.Entries = append(im.rep.Entries, ImportEntry{Name: name, Repo: rel.Repo, Version: rel.Version, Notes: notes, release: rel})
+}
+
+// skip records an entry that could not be translated
+func (im *importer) skip(name string, reason string) {
+	im.rep.Entries = append(im.rep.Entries, ImportEntry{Name: name, Status: ImportSkipped, Notes: []string{reason}})
+}
+
+// aquaConfig is the part of aqua.yaml binman understands
+type aquaConfig struct {
+	Registries []struct {
+		Name string `yaml:"name"`
+		Type string `yaml:"type"`
+	} `yaml:"registries"`
+	Packages []struct {
+		Name     string `yaml:"name"`
+		Version  string `yaml:"version"`
+		Registry string `yaml:"registry"`
+		Import   string `yaml:"import"`
+	} `yaml:"packages"`
+}
+
+// aqua translates the packages of an aqua.yaml. Packages are only understood when they come from the standard registry.
+// Files named by import are read too when root is set
+func (im *importer) aqua(data []byte, root bool) error {
+
+	var cfg aquaConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	standard := map[string]bool{"": true, "standard": true}
+	for _, r := range cfg.Registries {
+		if r.Type == "standard" {
+			standard[r.Name] = true
+		}
+	}
+
+	for _, pkg := range cfg.Packages {
+
+		if pkg.Import != "" {
+			if !root {
+				im.skip(pkg.Import, "imports are only followed from the top level aqua.yaml")
+				continue
+			}
+			if err := im.aquaImport(pkg.Import); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, version, _ := strings.Cut(pkg.Name, "@")
+		if version == "" {
+			version = pkg.Version
+		}
+
+		if !standard[pkg.Registry] {
+			im.skip(name, fmt.Sprintf("registry %s is not the aqua standard registry", pkg.Registry))
+			continue
+		}
+
+		rel, notes, err := im.aquaRelease(name)
+		if err != nil {
+			im.skip(name, err.Error())
+			continue
+		}
+
+		rel.Version = version
+		im.add(name, rel, notes...)
+	}
+
+	return nil
+}
+
+// aquaImport reads every file matching the aqua import pattern
+func (im *importer) aquaImport(pattern string) error {
+
+	files, err := filepath.Glob(filepath.Join(im.dir, pattern))
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		im.rep.Notes = append(im.rep.Notes, fmt.Sprintf("import %s matched no files", pattern))
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Clean(f))
+		if err != nil {
+			return err
+		}
+		if err := im.aqua(data, false); err != nil {
+			return fmt.Errorf("%s - %w", f, err)
+		}
+	}
+
+	return nil
+}
+
+// aquaRelease returns the release for an aqua standard registry package. Packages are named after their repo, so
+// anything after org/repo names one of several tools published by the repo
+func (im *importer) aquaRelease(name string) (BinmanRelease, []string, error) {
+
+	if repo, ok := im.repos[name]; ok {
+		return BinmanRelease{Repo: repo}, nil, nil
+	}
+
+	if reason, ok := aquaUnreleased[name]; ok {
+		return BinmanRelease{}, nil, errors.New(reason)
+	}
+
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 {
+		return BinmanRelease{}, nil, fmt.Errorf("%s is not an org/repo package", name)
+	}
+
+	rel := BinmanRelease{Repo: parts[0] + "/" + parts[1]}
+	if len(parts) == 2 {
+		return rel, nil, nil
+	}
+
+	rel.LinkName = parts[len(parts)-1]
+	return rel, []string{fmt.Sprintf("%s is one of the tools released by %s, check the asset binman picks", name, rel.Repo)}, nil
+}
+
+// eget translates the repos of an eget.toml
+func (im *importer) eget(data []byte) error {
+
+	var cfg map[string]map[string]any
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	if global, ok := cfg["global"]; ok {
+		if target, _ := global["target"].(string); target != "" {
+			im.rep.Notes = append(im.rep.Notes, fmt.Sprintf("global target %s was not carried over. Set config.binpath to link binaries there", target))
+		}
+		if _, ok := global["github_token"]; ok {
+			im.rep.Notes = append(im.rep.Notes, "global github_token was not carried over. Set tokenvar on the github.com source")
+		}
+	}
+
+	// toml tables are unordered, so repos are imported in name order
+	var names []string
+	for name := range cfg {
+		if name != "global" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		repo := strings.TrimPrefix(strings.TrimPrefix(name, "https://"), "github.com/")
+		if strings.Count(repo, "/") != 1 || strings.Contains(repo, ":") {
+			im.skip(name, "only github repos are supported, not direct downloads")
+			continue
+		}
+
+		rel := BinmanRelease{Repo: repo}
+		var notes []string
+
+		opts := cfg[name]
+		keys := make([]string, 0, len(opts))
+		for k := range opts {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			switch v := opts[k]; k {
+			case "tag":
+				rel.Version = fmt.Sprint(v)
+			case "asset_filters":
+				rel.AssetFilters = append(rel.AssetFilters, egetFilters(v)...)
+			case "file":
+				file := fmt.Sprint(v)
+				if strings.ContainsAny(file, "*?[") {
+					notes = append(notes, fmt.Sprintf("file glob %s was not carried over", file))
+					continue
+				}
+				rel.ExtractFileName = file
+			case "target":
+				target := fmt.Sprint(v)
+				if info, err := os.Stat(expandHome(target)); strings.HasSuffix(target, "/") || err == nil && info.IsDir() {
+					notes = append(notes, fmt.Sprintf("target directory %s was not carried over", target))
+					continue
+				}
+				if link := filepath.Base(target); link != filepath.Base(repo) {
+					rel.LinkName = link
+				}
+			case "system":
+				rel.Os, rel.Arch, _ = strings.Cut(fmt.Sprint(v), "/")
+			case "download_only":
+				rel.DownloadOnly, _ = v.(bool)
+			case "upgrade_only", "quiet", "show_hash":
+				// binman always upgrades and has no equivalent output settings
+			default:
+				notes = append(notes, fmt.Sprintf("%s was not carried over", k))
+			}
+		}
+
+		im.add(name, rel, notes...)
+	}
+
+	return nil
+}
+
+// egetFilters translates eget asset filters into assetfilters. eget filters are substrings, and ^ excludes matches
+func egetFilters(v any) []string {
+
+	var raw []string
+	switch f := v.(type) {
+	case string:
+		raw = []string{f}
+	case []any:
+		for _, s := range f {
+			raw = append(raw, fmt.Sprint(s))
+		}
+	}
+
+	var filters []string
+	for _, f := range raw {
+		if after, found := strings.CutPrefix(f, "^"); found {
+			filters = append(filters, "!"+regexp.QuoteMeta(after))
+			continue
+		}
+		filters = append(filters, regexp.QuoteMeta(f))
+	}
+
+	return filters
+}
+
+// asdf translates the tools of a .tool-versions file. Only the first version of each tool is imported
+func (im *importer) asdf(data []byte) error {
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := fields[0]
+		if len(fields) < 2 {
+			im.skip(name, "no version is set")
+			continue
+		}
+
+		var notes []string
+		if len(fields) > 2 {
+			notes = append(notes, fmt.Sprintf("only %s is imported, %v are fallbacks", fields[1], fields[2:]))
+		}
+
+		im.plugin(name, fields[1], false, notes)
+	}
+
+	return scanner.Err()
+}
+
+// plugin translates an asdf or mise plugin to a release using the bundled mapping table. If fuzzy is set versions that
+// are a prefix, like 1.2 meaning the newest 1.2.x, are not pinned
+func (im *importer) plugin(name string, version string, fuzzy bool, notes []string) {
+
+	p, ok := pluginRepos[name]
+	if repo, mapped := im.repos[name]; mapped {
+		p, ok = pluginRepo{repo: repo}, true
+	}
+
+	if !ok {
+		im.skip(name, fmt.Sprintf("no repo is known for %s. Map it with --map %s=org/repo", name, name))
+		return
+	}
+
+	rel := BinmanRelease{Repo: p.repo, LinkName: p.link}
+
+	pin, note, skip := pluginVersion(version, p.prefix, fuzzy)
+	if skip {
+		im.skip(name, note)
+		return
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	rel.Version = pin
+	im.add(name, rel, notes...)
+}
+
+// fuzzyRx matches versions that only name a major or major.minor
+var fuzzyRx = regexp.MustCompile(`^v?\d+(\.\d+)?$`)
+
+// pluginVersion returns the tag to pin an asdf or mise version to. Versions that do not name a release are not pinned,
+// the note says why. skip is set for tools that are not installed by the plugin at all
+func pluginVersion(version string, prefix string, fuzzy bool) (pin string, note string, skip bool) {
+
+	switch {
+	case version == "system":
+		return "", "uses the system install", true
+	case version == "latest":
+		return "", "", false
+	case strings.HasPrefix(version, "latest:"), strings.HasPrefix(version, "prefix:"):
+		return "", fmt.Sprintf("%s was not pinned, binman syncs the latest release", version), false
+	case strings.HasPrefix(version, "ref:"), strings.HasPrefix(version, "path:"), strings.HasPrefix(version, "sub-"):
+		return "", fmt.Sprintf("%s is not a release and was not pinned", version), false
+	case fuzzy && fuzzyRx.MatchString(version):
+		return "", fmt.Sprintf("%s matches several releases and was not pinned", version), false
+	}
+
+	if strings.HasPrefix(version, prefix) {
+		return version, "", false
+	}
+
+	return prefix + version, "", false
+}
+
+// miseConfig is the part of mise.toml binman understands
+type miseConfig struct {
+	Tools map[string]any `toml:"tools"`
+}
+
+// miseBackendRx splits inline tool options from the name, e.g ubi:BurntSushi/ripgrep[exe=rg]
+var miseBackendRx = regexp.MustCompile(`^([^\[]+)(?:\[(.*)\])?$`)
+
+// mise translates the tools of a mise.toml. Plugin names use the bundled mapping table, the aqua, ubi, github and gitlab
+// backends name their repo directly
+func (im *importer) mise(data []byte) error {
+
+	var cfg miseConfig
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	// toml tables are unordered, so tools are imported in name order
+	var keys []string
+	for k := range cfg.Tools {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+
+		m := miseBackendRx.FindStringSubmatch(key)
+		if m == nil {
+			im.skip(key, "unable to parse the tool name")
+			continue
+		}
+
+		backend, name, found := strings.Cut(m[1], ":")
+		if !found {
+			backend, name = "", m[1]
+		}
+
+		opts := make(map[string]any)
+		for _, opt := range strings.Split(m[2], ",") {
+			if k, v, ok := strings.Cut(opt, "="); ok {
+				opts[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+
+		var notes []string
+		var version string
+
+		switch v := cfg.Tools[key].(type) {
+		case string:
+			version = v
+		case []any:
+			if len(v) == 0 {
+				im.skip(key, "no version is set")
+				continue
+			}
+			version = fmt.Sprint(v[0])
+			if len(v) > 1 {
+				notes = append(notes, fmt.Sprintf("only %s is imported, %v are fallbacks", version, v[1:]))
+			}
+		case map[string]any:
+			for k, o := range v {
+				opts[k] = o
+			}
+			if _, ok := opts["version"]; !ok {
+				im.skip(key, "no version is set")
+				continue
+			}
+			version = fmt.Sprint(opts["version"])
+			delete(opts, "version")
+		default:
+			im.skip(key, "unable to read the version")
+			continue
+		}
+
+		switch backend {
+		case "", "core", "asdf", "vfox":
+			// asdf and vfox plugins can be named by their plugin repo, e.g asdf:mise-plugins/asdf-jq
+			plugin := filepath.Base(name)
+			plugin = strings.TrimPrefix(strings.TrimPrefix(plugin, "asdf-"), "vfox-")
+			for k := range opts {
+				notes = append(notes, fmt.Sprintf("%s was not carried over", k))
+			}
+			slices.Sort(notes)
+			im.plugin(plugin, version, true, notes)
+		case "aqua", "ubi", "github", "gitlab":
+			im.miseRepo(key, backend, name, version, opts, notes)
+		default:
+			im.skip(key, fmt.Sprintf("the mise %s backend is not supported", backend))
+		}
+	}
+
+	return nil
+}
+
+// miseRepo translates a tool from a mise backend that names its repo
+func (im *importer) miseRepo(key, backend, name, version string, opts map[string]any, notes []string) {
+
+	var rel BinmanRelease
+
+	switch backend {
+	case "aqua":
+		r, n, err := im.aquaRelease(name)
+		if err != nil {
+			im.skip(key, err.Error())
+			return
+		}
+		rel, notes = r, append(notes, n...)
+	case "gitlab":
+		rel.Repo = "gitlab.com/" + name
+	default:
+		rel.Repo = name
+	}
+
+	if strings.Count(strings.TrimPrefix(rel.Repo, "gitlab.com/"), "/") < 1 {
+		im.skip(key, fmt.Sprintf("%s is not an org/repo", name))
+		return
+	}
+
+	// mise finds the tag itself so its versions usually drop the v prefix
+	pin, note, skip := pluginVersion(version, "", true)
+	if skip {
+		im.skip(key, note)
+		return
+	}
+	if note != "" {
+		notes = append(notes, note)
+	}
+	if pin != "" && pin[0] >= '0' && pin[0] <= '9' {
+		notes = append(notes, fmt.Sprintf("pinned to %s as written, the upstream tag may need a v prefix", pin))
+	}
+	rel.Version = pin
+
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v := fmt.Sprint(opts[k])
+		switch k {
+		case "exe":
+			rel.ExtractFileName = v
+		case "bin":
+			rel.LinkName = v
+		case "matching":
+			rel.AssetFilters = append(rel.AssetFilters, regexp.QuoteMeta(v))
+		case "asset_pattern":
+			rel.AssetFilters = append(rel.AssetFilters, globRegex(v))
+		case "os":
+			rel.ExcludeOs = excludedOs(opts[k])
+		default:
+			notes = append(notes, fmt.Sprintf("%s was not carried over", k))
+		}
+	}
+
+	im.add(key, rel, notes...)
+}
+
+// globRegex translates a glob into an anchored regex
+func globRegex(glob string) string {
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
+
+// excludedOs returns the operating systems missing from a mise os list
+func excludedOs(v any) []string {
+
+	list, ok := v.([]any)
+	if !ok {
+		list = []any{v}
+	}
+
+	supported := make(map[string]bool)
+	for _, o := range list {
+		name := fmt.Sprint(o)
+		if name == "macos" {
+			name = "darwin"
+		}
+		supported[name] = true
+	}
+
+	var excluded []string
+	for _, o := range []string{"darwin", "linux", "windows"} {
+		if !supported[o] {
+			excluded = append(excluded, o)
+		}
+	}
+
+	return excluded
+}
+
+// OutputImportReport prints a table of each translated entry followed by the manifest wide notes
+func OutputImportReport(rep *ImportReport) {
+
+	headerFmt := color.New(color.FgBlue, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Name", "Repo", "Version", "Status", "Notes")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, e := range rep.Entries {
+		tbl.AddRow(e.Name, e.Repo, e.Version, e.Status, strings.Join(e.Notes, "; "))
+	}
+
+	tbl.Print()
+
+	for _, note := range rep.Notes {
+		log.Warnf("%s", note)
+	}
+}
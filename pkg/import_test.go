@@ -0,0 +1,304 @@
+package binman
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/templating"
+)
+
+const importConfig = `
+config:
+  releasepath: {{ .releasePath }}
+releases:
+  # already managed
+  - repo: junegunn/fzf
+`
+
+// importTestSetup writes a config with junegunn/fzf configured and the manifest named file
+func importTestSetup(t *testing.T, file string, manifest string) (string, string) {
+
+	dir := t.TempDir()
+
+	cf := filepath.Join(dir, "config")
+	if err := WriteStringtoFile(cf, templating.TemplateString(importConfig, map[string]any{"releasePath": dir})); err != nil {
+		t.Fatalf("Unable to write test config")
+	}
+
+	mf := filepath.Join(dir, file)
+	if err := WriteStringtoFile(mf, manifest); err != nil {
+		t.Fatalf("Unable to write %s", mf)
+	}
+
+	return cf, mf
+}
+
+// importEntries returns the entries of rep by name
+func importEntries(rep *ImportReport) map[string]ImportEntry {
+	entries := make(map[string]ImportEntry)
+	for _, e := range rep.Entries {
+		entries[e.Name] = e
+	}
+	return entries
+}
+
+func checkImportEntry(t *testing.T, entries map[string]ImportEntry, name, repo, version, status string) ImportEntry {
+	e, ok := entries[name]
+	if !ok {
+		t.Fatalf("Expected an entry for %s got %+v", name, entries)
+	}
+	if e.Repo != repo || e.Version != version || e.Status != status {
+		t.Fatalf("Expected %s to be %s(%s) %s got %+v", name, repo, version, status, e)
+	}
+	return e
+}
+
+const aquaManifest = `
+registries:
+  - type: standard
+    ref: v4.155.1
+  - name: local
+    type: local
+    path: registry.yaml
+packages:
+  - name: cli/cli@v2.40.0
+  - name: junegunn/fzf
+    version: v0.44.1
+  - name: kubernetes/kubectl@v1.28.0
+  - name: gohugoio/hugo/hugo-extended@v0.120.0
+  - name: org/private@v1.0.0
+    registry: local
+  - import: aqua/*.yaml
+`
+
+func TestImportAqua(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	cf, mf := importTestSetup(t, "aqua.yaml", aquaManifest)
+
+	imported := filepath.Join(filepath.Dir(mf), "aqua", "tools.yaml")
+	if err := os.MkdirAll(filepath.Dir(imported), 0750); err != nil {
+		t.Fatalf("Unable to create %s", filepath.Dir(imported))
+	}
+	if err := WriteStringtoFile(imported, "packages:\n  - name: sharkdp/bat@v0.24.0\n"); err != nil {
+		t.Fatalf("Unable to write %s", imported)
+	}
+
+	rep, err := Import(ImportAqua, mf, ImportOptions{}, cf)
+	if err != nil {
+		t.Fatalf("Import failed - %s", err)
+	}
+
+	entries := importEntries(rep)
+
+	checkImportEntry(t, entries, "cli/cli", "cli/cli", "v2.40.0", ImportAdded)
+	checkImportEntry(t, entries, "junegunn/fzf", "junegunn/fzf", "v0.44.1", ImportExists)
+	checkImportEntry(t, entries, "sharkdp/bat", "sharkdp/bat", "v0.24.0", ImportAdded)
+	checkImportEntry(t, entries, "org/private", "", "", ImportSkipped)
+
+	// kubectl is not released as an asset so it can't be translated
+	checkImportEntry(t, entries, "kubernetes/kubectl", "", "", ImportSkipped)
+
+	if e := checkImportEntry(t, entries, "gohugoio/hugo/hugo-extended", "gohugoio/hugo", "v0.120.0", ImportAdded); len(e.Notes) == 0 {
+		t.Fatalf("Expected a note for a package that is not a whole repo")
+	}
+
+	c := NewBMConfig(cf).SetConfig(false)
+	for _, repo := range []string{"junegunn/fzf", "cli/cli", "gohugoio/hugo", "sharkdp/bat"} {
+		if _, err := c.GetRelease(repo); err != nil {
+			t.Fatalf("Expected %s in the config", repo)
+		}
+	}
+
+	if len(c.Releases) != 4 {
+		t.Fatalf("Expected 4 releases got %d", len(c.Releases))
+	}
+
+	data, _ := os.ReadFile(cf)
+	if !strings.Contains(string(data), "# already managed") {
+		t.Fatalf("Expected comments in the config to be kept got\n%s", data)
+	}
+}
+
+const egetManifest = `
+[global]
+target = "~/bin"
+
+["zyedidia/micro"]
+tag = "v2.0.13"
+asset_filters = ["static", "^musl"]
+upgrade_only = true
+
+["https://github.com/BurntSushi/ripgrep"]
+target = "~/bin/ripgrep-bin/rgx"
+file = "rg"
+verify_sha256 = "abc"
+
+["https://go.dev/dl/go1.21.0.linux-amd64.tar.gz"]
+`
+
+func TestImportEget(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	cf, mf := importTestSetup(t, "eget.toml", egetManifest)
+
+	rep, err := Import(ImportEget, mf, ImportOptions{DryRun: true}, cf)
+	if err != nil {
+		t.Fatalf("Import failed - %s", err)
+	}
+
+	entries := importEntries(rep)
+
+	micro := checkImportEntry(t, entries, "zyedidia/micro", "zyedidia/micro", "v2.0.13", ImportAdded)
+	if want := []string{"static", "!musl"}; !reflect.DeepEqual(micro.release.AssetFilters, want) {
+		t.Fatalf("Expected assetfilters %v got %v", want, micro.release.AssetFilters)
+	}
+
+	rg := checkImportEntry(t, entries, "https://github.com/BurntSushi/ripgrep", "BurntSushi/ripgrep", "", ImportAdded)
+	if rg.release.ExtractFileName != "rg" || rg.release.LinkName != "rgx" {
+		t.Fatalf("Expected ripgrep to extract rg and link rgx got %+v", rg.release)
+	}
+	if len(rg.Notes) != 1 || !strings.Contains(rg.Notes[0], "verify_sha256") {
+		t.Fatalf("Expected a note for verify_sha256 got %v", rg.Notes)
+	}
+
+	checkImportEntry(t, entries, "https://go.dev/dl/go1.21.0.linux-amd64.tar.gz", "", "", ImportSkipped)
+
+	if len(rep.Notes) != 1 {
+		t.Fatalf("Expected a note for the global target got %v", rep.Notes)
+	}
+
+	// A dry run leaves the config alone
+	if c := NewBMConfig(cf).SetConfig(false); len(c.Releases) != 1 {
+		t.Fatalf("Expected the config to be unchanged got %d releases", len(c.Releases))
+	}
+}
+
+const asdfManifest = `
+# team tools
+terraform 1.5.7
+jq 1.7.1 1.6
+kubectl v1.28.2
+nodejs 20.9.0
+golang system
+fzf 0.44.1
+`
+
+func TestImportAsdf(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	cf, mf := importTestSetup(t, ".tool-versions", asdfManifest)
+
+	rep, err := Import(ImportAsdf, mf, ImportOptions{Map: map[string]string{"nodejs": "nodejs/node"}}, cf)
+	if err != nil {
+		t.Fatalf("Import failed - %s", err)
+	}
+
+	entries := importEntries(rep)
+
+	checkImportEntry(t, entries, "terraform", "hashicorp/terraform", "v1.5.7", ImportAdded)
+	checkImportEntry(t, entries, "kubectl", "", "", ImportSkipped)
+	checkImportEntry(t, entries, "nodejs", "nodejs/node", "20.9.0", ImportAdded)
+	checkImportEntry(t, entries, "golang", "", "", ImportSkipped)
+	checkImportEntry(t, entries, "fzf", "junegunn/fzf", "v0.44.1", ImportExists)
+
+	if e := checkImportEntry(t, entries, "jq", "jqlang/jq", "jq-1.7.1", ImportAdded); len(e.Notes) != 1 {
+		t.Fatalf("Expected a note for the jq fallback version got %v", e.Notes)
+	}
+
+	c := NewBMConfig(cf).SetConfig(false)
+	if rel, err := c.GetRelease("jqlang/jq"); err != nil || rel.Version != "jq-1.7.1" {
+		t.Fatalf("Expected jqlang/jq pinned to jq-1.7.1 in the config got %+v", rel)
+	}
+}
+
+const miseManifest = `
+[env]
+FOO = "bar"
+
+[tools]
+terraform = "1.5"
+jq = ["1.7.1", "1.6"]
+node = "20"
+"ubi:BurntSushi/ripgrep[exe=rg]" = "14.1.0"
+"github:cli/cli" = { version = "v2.40.0", asset_pattern = "gh_*_linux_amd64.tar.gz", bin = "gh", os = ["linux", "macos"], postinstall = "echo" }
+"aqua:junegunn/fzf" = "latest"
+"cargo:eza" = "0.18.0"
+`
+
+func TestImportMise(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	cf, mf := importTestSetup(t, "mise.toml", miseManifest)
+
+	rep, err := Import(ImportMise, mf, ImportOptions{DryRun: true}, cf)
+	if err != nil {
+		t.Fatalf("Import failed - %s", err)
+	}
+
+	entries := importEntries(rep)
+
+	// a prefix version is not pinned
+	if e := checkImportEntry(t, entries, "terraform", "hashicorp/terraform", "", ImportAdded); len(e.Notes) != 1 {
+		t.Fatalf("Expected a note for the fuzzy terraform version got %v", e.Notes)
+	}
+
+	checkImportEntry(t, entries, "jq", "jqlang/jq", "jq-1.7.1", ImportAdded)
+	checkImportEntry(t, entries, "node", "", "", ImportSkipped)
+	checkImportEntry(t, entries, "aqua:junegunn/fzf", "junegunn/fzf", "", ImportExists)
+	checkImportEntry(t, entries, "cargo:eza", "", "", ImportSkipped)
+
+	rg := checkImportEntry(t, entries, "ubi:BurntSushi/ripgrep[exe=rg]", "BurntSushi/ripgrep", "14.1.0", ImportAdded)
+	if rg.release.ExtractFileName != "rg" || len(rg.Notes) != 1 {
+		t.Fatalf("Expected ripgrep to extract rg with a note about the tag got %+v %v", rg.release, rg.Notes)
+	}
+
+	gh := checkImportEntry(t, entries, "github:cli/cli", "cli/cli", "v2.40.0", ImportAdded)
+	if gh.release.LinkName != "gh" || !reflect.DeepEqual(gh.release.ExcludeOs, []string{"windows"}) {
+		t.Fatalf("Expected gh linked as gh excluded from windows got %+v", gh.release)
+	}
+	if want := []string{`^gh_.*_linux_amd64\.tar\.gz$`}; !reflect.DeepEqual(gh.release.AssetFilters, want) {
+		t.Fatalf("Expected assetfilters %v got %v", want, gh.release.AssetFilters)
+	}
+	if len(gh.Notes) != 1 || !strings.Contains(gh.Notes[0], "postinstall") {
+		t.Fatalf("Expected a note for postinstall got %v", gh.Notes)
+	}
+}
+
+func TestPluginVersion(t *testing.T) {
+
+	var tests = []struct {
+		version string
+		prefix  string
+		fuzzy   bool
+		pin     string
+		note    bool
+		skip    bool
+	}{
+		{"1.2.3", "v", false, "v1.2.3", false, false},
+		{"v1.2.3", "v", false, "v1.2.3", false, false},
+		{"1.7.1", "jq-", false, "jq-1.7.1", false, false},
+		{"5.2.1", "kustomize/v", false, "kustomize/v5.2.1", false, false},
+		{"latest", "v", false, "", false, false},
+		{"latest:1.2", "v", false, "", true, false},
+		{"ref:main", "v", false, "", true, false},
+		{"system", "v", false, "", true, true},
+		{"1.2", "v", false, "v1.2", false, false},
+		{"1.2", "v", true, "", true, false},
+	}
+
+	for _, test := range tests {
+		pin, note, skip := pluginVersion(test.version, test.prefix, test.fuzzy)
+		if pin != test.pin || (note != "") != test.note || skip != test.skip {
+			t.Fatalf("pluginVersion(%s, %s, %v) expected %s/%v/%v got %s/%s/%v", test.version, test.prefix, test.fuzzy, test.pin, test.note, test.skip, pin, note, skip)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package binman
+
+// pluginRepo is where the tool installed by an asdf or mise plugin is released
+type pluginRepo struct {
+	repo   string
+	prefix string // prefix of the upstream tags. asdf and mise versions have none, e.g v for v1.2.3
+	link   string // linkname, for tools not named after their repo
+}
+
+// pluginRepos maps asdf and mise plugin names to the repo binman syncs the tool from. Plugins for language runtimes and
+// tools that are not published as release binaries are left out, Import reports them so they can be mapped with --map
+var pluginRepos = map[string]pluginRepo{
+	"act":           {"nektos/act", "v", ""},
+	"age":           {"FiloSottile/age", "v", ""},
+	"argocd":        {"argoproj/argo-cd", "v", ""},
+	"bat":           {"sharkdp/bat", "v", ""},
+	"binman":        {"rjbrown57/binman", "v", ""},
+	"buf":           {"bufbuild/buf", "v", ""},
+	"cilium-cli":    {"cilium/cilium-cli", "v", ""},
+	"cosign":        {"sigstore/cosign", "v", ""},
+	"delta":         {"dandavison/delta", "", ""},
+	"deno":          {"denoland/deno", "v", ""},
+	"direnv":        {"direnv/direnv", "v", ""},
+	"dive":          {"wagoodman/dive", "v", ""},
+	"fd":            {"sharkdp/fd", "v", ""},
+	"flux2":         {"fluxcd/flux2", "v", ""},
+	"fzf":           {"junegunn/fzf", "v", ""},
+	"gh":            {"cli/cli", "v", ""},
+	"github-cli":    {"cli/cli", "v", ""},
+	"gitleaks":      {"gitleaks/gitleaks", "v", ""},
+	"glab":          {"gitlab.com/gitlab-org/cli", "v", ""},
+	"golangci-lint": {"golangci/golangci-lint", "v", ""},
+	"goreleaser":    {"goreleaser/goreleaser", "v", ""},
+	"grype":         {"anchore/grype", "v", ""},
+	"hadolint":      {"hadolint/hadolint", "v", ""},
+	"helm":          {"helm/helm", "v", ""},
+	"helmfile":      {"helmfile/helmfile", "v", ""},
+	"hugo":          {"gohugoio/hugo", "v", ""},
+	"jq":            {"jqlang/jq", "jq-", ""},
+	"just":          {"casey/just", "", ""},
+	"k3d":           {"k3d-io/k3d", "v", ""},
+	"k9s":           {"derailed/k9s", "v", ""},
+	"kind":          {"kubernetes-sigs/kind", "v", ""},
+	"kubectx":       {"ahmetb/kubectx", "v", ""},
+	"kubeseal":      {"bitnami-labs/sealed-secrets", "v", ""},
+	"kustomize":     {"kubernetes-sigs/kustomize", "kustomize/v", ""},
+	"lazygit":       {"jesseduffield/lazygit", "v", ""},
+	"minikube":      {"kubernetes/minikube", "v", ""},
+	"neovim":        {"neovim/neovim", "v", ""},
+	"opa":           {"open-policy-agent/opa", "v", ""},
+	"ripgrep":       {"BurntSushi/ripgrep", "", ""},
+	"shellcheck":    {"koalaman/shellcheck", "v", ""},
+	"shfmt":         {"mvdan/sh", "v", ""},
+	"skaffold":      {"GoogleContainerTools/skaffold", "v", ""},
+	"sops":          {"getsops/sops", "v", ""},
+	"starship":      {"starship/starship", "v", ""},
+	"stern":         {"stern/stern", "v", ""},
+	"syft":          {"anchore/syft", "v", ""},
+	"task":          {"go-task/task", "v", ""},
+	"terraform":     {"hashicorp/terraform", "v", ""},
+	"terragrunt":    {"gruntwork-io/terragrunt", "v", ""},
+	"tflint":        {"terraform-linters/tflint", "v", ""},
+	"tilt":          {"tilt-dev/tilt", "v", ""},
+	"trivy":         {"aquasecurity/trivy", "v", ""},
+	"vault":         {"hashicorp/vault", "v", ""},
+	"velero":        {"vmware-tanzu/velero", "v", ""},
+	"yq":            {"mikefarah/yq", "v", ""},
+	"zoxide":        {"ajeetdsouza/zoxide", "v", ""},
+}
+
+// aquaUnreleased lists aqua standard registry packages named after a repo that does not publish them as release assets
+var aquaUnreleased = map[string]string{
+	"kubernetes/kubectl": "kubectl is not a release asset of kubernetes/kubernetes. Add it with a url, see docs/external_urls.md",
+}
@@ -15,6 +15,12 @@ func IsDebug() bool {
 	return true
 }
 
+// IsJSON reports whether json style logging has been configured
+func IsJSON() bool {
+	_, ok := log.Formatter.(*logrus.JSONFormatter)
+	return ok
+}
+
 func ConfigureLog(jsonLog bool, logLevel int) {
 	// logging
 	if jsonLog {
@@ -0,0 +1,322 @@
+package oci
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	semver "github.com/Masterminds/semver/v3"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// TitleAnnotation is set by ORAS on artifact layers to the name of the file they contain
+const TitleAnnotation = "org.opencontainers.image.title"
+
+// maxSymlinks is the number of symlinks followed when copying a file out of an image
+const maxSymlinks = 10
+
+var ErrFileNotFound = errors.New("file not found in image")
+
+// Layer is a file stored as a layer of an ORAS style artifact
+type Layer struct {
+	Name   string // from the title annotation
+	Digest string // layer reference in the form registry/repo@sha256:...
+}
+
+// Release is a resolved image or artifact version
+type Release struct {
+	Ref     string // image reference in the form registry/repo:tag
+	Version string
+	Layers  []Layer // titled layers of the manifest for the requested platform. Empty for images that are not artifacts
+}
+
+// Puller queries and pulls from a single registry
+type Puller struct {
+	opts []remote.Option
+}
+
+// NewPuller will return a puller for os/arch. If token is set it is used as the registry password, otherwise credentials
+// come from the docker config. If rt is nil the default transport is used
+func NewPuller(osName, arch, token string, rt http.RoundTripper) *Puller {
+
+	opts := []remote.Option{remote.WithPlatform(v1.Platform{OS: osName, Architecture: arch})}
+
+	if token != "" {
+		opts = append(opts, remote.WithAuth(&authn.Basic{Username: "binman", Password: token}))
+	} else {
+		opts = append(opts, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	}
+
+	if rt != nil {
+		opts = append(opts, remote.WithTransport(rt))
+	}
+
+	return &Puller{opts: opts}
+}
+
+// ListVersions returns the semver tags of repo newest first. Prereleases are skipped unless prerelease is true
+func (p *Puller) ListVersions(repo string, prerelease bool) ([]string, error) {
+
+	tags, err := p.ListTags(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []*semver.Version
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil || (v.Prerelease() != "" && !prerelease) {
+			continue
+		}
+		vs = append(vs, v)
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(vs)))
+
+	versions := make([]string, 0, len(vs))
+	for _, v := range vs {
+		versions = append(versions, v.Original())
+	}
+
+	return versions, nil
+}
+
+// ListTags returns every tag of repo in the order the registry lists them
+func (p *Puller) ListTags(repo string) ([]string, error) {
+
+	r, err := name.NewRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return remote.List(r, p.opts...)
+}
+
+// GetRelease returns the release of repo for tag. If tag is empty the newest stable semver tag is used
+func (p *Puller) GetRelease(repo string, tag string) (*Release, error) {
+
+	if tag == "" {
+		versions, err := p.ListVersions(repo, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no semver tags found for %s", repo)
+		}
+		tag = versions[0]
+	}
+
+	ref, err := name.NewTag(fmt.Sprintf("%s:%s", repo, tag))
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := remote.Image(ref, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &Release{Ref: ref.String(), Version: tag}
+
+	for _, l := range manifest.Layers {
+		if title := l.Annotations[TitleAnnotation]; title != "" {
+			rel.Layers = append(rel.Layers, Layer{Name: title, Digest: ref.Context().Digest(l.Digest.String()).String()})
+		}
+	}
+
+	return rel, nil
+}
+
+// PullLayer writes the blob of an artifact layer to dst
+func (p *Puller) PullLayer(digest string, dst string) error {
+
+	ref, err := name.NewDigest(digest)
+	if err != nil {
+		return err
+	}
+
+	layer, err := remote.Layer(ref, p.opts...)
+	if err != nil {
+		return err
+	}
+
+	// Artifact layers contain the file as is, so the blob is written without decompression
+	rc, err := layer.Compressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return writeFile(rc, dst, 0644)
+}
+
+// ExtractFile copies filePath out of the flattened filesystem of image ref to dst. Symlinks are followed, including links
+// to directories in filePath. Every link in the image is recorded while looking for filePath, so a chain of links takes at most one more pass to copy
+func (p *Puller) ExtractFile(imageRef string, filePath string, dst string) error {
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return err
+	}
+
+	img, err := remote.Image(ref, p.opts...)
+	if err != nil {
+		return err
+	}
+
+	target := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+
+	links, found, err := extractFile(img, target, dst)
+	if err != nil || found {
+		return err
+	}
+
+	resolved, err := resolveLinks(links, target)
+	if err != nil {
+		return err
+	}
+
+	if resolved == target {
+		return fmt.Errorf("%s - %w", target, ErrFileNotFound)
+	}
+
+	if _, found, err = extractFile(img, resolved, dst); err != nil || found {
+		return err
+	}
+
+	return fmt.Errorf("%s - %w", resolved, ErrFileNotFound)
+}
+
+// resolveLinks resolves every component of target against links, so files under a linked directory such as bin -> usr/bin
+// are found as well as links to files
+func resolveLinks(links map[string]string, target string) (string, error) {
+
+	parts := strings.Split(target, "/")
+	resolved := ""
+
+	for i, hops := 0, 0; i < len(parts); i++ {
+		p := path.Join(resolved, parts[i])
+
+		link, ok := links[p]
+		if !ok {
+			resolved = p
+			continue
+		}
+
+		if hops == maxSymlinks {
+			return "", fmt.Errorf("too many levels of symlinks resolving %s", target)
+		}
+		hops++
+
+		log.Debugf("%s is a link to %s", p, link)
+
+		// Start over from the link target with the rest of the path, the target may itself go through links
+		parts = append(strings.Split(link, "/"), parts[i+1:]...)
+		resolved = ""
+		i = -1
+	}
+
+	return resolved, nil
+}
+
+// extractFile writes target from the image filesystem to dst and reports whether it was found. The links in the image,
+// resolved to the path they point at, are returned so a link can be followed without reading the image again
+func extractFile(img v1.Image, target string, dst string) (map[string]string, bool, error) {
+
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	tr := tar.NewReader(rc)
+	links := make(map[string]string)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return links, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+hdr.Name), "/")
+
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			// Resolve the link relative to the directory containing it
+			link := hdr.Linkname
+			if !path.IsAbs(link) {
+				link = path.Join(path.Dir("/"+name), link)
+			}
+			links[name] = strings.TrimPrefix(path.Clean(link), "/")
+			continue
+		case tar.TypeLink:
+			// Hard links point at another entry by path
+			links[name] = strings.TrimPrefix(path.Clean("/"+hdr.Linkname), "/")
+			continue
+		}
+
+		if name != target {
+			continue
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, false, fmt.Errorf("%s is not a regular file", target)
+		}
+
+		return nil, true, writeFile(tr, dst, os.FileMode(hdr.Mode).Perm())
+	}
+}
+
+func writeFile(r io.Reader, dst string, mode os.FileMode) error {
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// GetLayerbyName returns the name and digest of the artifact layer matching relFileName
+func GetLayerbyName(relFileName string, layers []Layer) (string, string) {
+	for _, l := range layers {
+		if l.Name == relFileName {
+			log.Debugf("Selected layer == %+v\n", l.Name)
+			return l.Name, l.Digest
+		}
+	}
+
+	return "", ""
+}
+
+// GetLayerData will create a map of names + layer digests
+func GetLayerData(layers []Layer) map[string]string {
+	m := make(map[string]string)
+
+	for _, l := range layers {
+		m[strings.ToLower(l.Name)] = l.Digest
+	}
+
+	return m
+}
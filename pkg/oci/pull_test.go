@@ -0,0 +1,138 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// tarLayer returns a layer containing a tool binary, symlinks to it and symlinks to the directories holding it
+func tarLayer(t *testing.T, version string) v1.Layer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	content := []byte("tool " + version)
+	tw.WriteHeader(&tar.Header{Name: "usr/local/bin/tool", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(content))})
+	tw.Write(content)
+	tw.WriteHeader(&tar.Header{Name: "usr/bin/tool", Typeflag: tar.TypeSymlink, Linkname: "../local/bin/tool"})
+	tw.WriteHeader(&tar.Header{Name: "bin/tool", Typeflag: tar.TypeSymlink, Linkname: "/usr/bin/tool"})
+	tw.WriteHeader(&tar.Header{Name: "bin/loop", Typeflag: tar.TypeSymlink, Linkname: "loop"})
+	tw.WriteHeader(&tar.Header{Name: "sbin", Typeflag: tar.TypeSymlink, Linkname: "usr/local/bin"})
+	tw.WriteHeader(&tar.Header{Name: "lib", Typeflag: tar.TypeSymlink, Linkname: "/usr"})
+	tw.Close()
+
+	layer, err := tarball.LayerFromReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Unable to create layer %s", err)
+	}
+	return layer
+}
+
+func pushImage(t *testing.T, repo string, tag string, img v1.Image) {
+	ref, err := name.NewTag(fmt.Sprintf("%s:%s", repo, tag))
+	if err != nil {
+		t.Fatalf("Unable to parse tag %s", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("Unable to push %s %s", ref, err)
+	}
+}
+
+func TestPuller(t *testing.T) {
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	imageRepo := host + "/org/tool"
+	artifactRepo := host + "/org/artifact"
+
+	// Images with the tool in their filesystem
+	for _, tag := range []string{"v1.0.0", "v1.2.0", "v2.0.0-rc1", "latest"} {
+		img, err := mutate.ConfigFile(empty.Image, &v1.ConfigFile{OS: "linux", Architecture: "amd64"})
+		if err != nil {
+			t.Fatalf("Unable to set config %s", err)
+		}
+		img, err = mutate.AppendLayers(img, tarLayer(t, tag))
+		if err != nil {
+			t.Fatalf("Unable to create image %s", err)
+		}
+		pushImage(t, imageRepo, tag, img)
+	}
+
+	// An ORAS style artifact with one titled layer per platform
+	artifact := empty.Image
+	for _, asset := range []string{"tool_linux_amd64", "tool_darwin_arm64"} {
+		var err error
+		artifact, err = mutate.Append(artifact, mutate.Addendum{
+			Layer:       static.NewLayer([]byte(asset+" content"), types.MediaType("application/octet-stream")),
+			Annotations: map[string]string{TitleAnnotation: asset},
+		})
+		if err != nil {
+			t.Fatalf("Unable to create artifact %s", err)
+		}
+	}
+	pushImage(t, artifactRepo, "0.3.0", artifact)
+
+	p := NewPuller("linux", "amd64", "", nil)
+
+	versions, err := p.ListVersions(imageRepo, false)
+	if err != nil || strings.Join(versions, ",") != "v1.2.0,v1.0.0" {
+		t.Fatalf("Unexpected versions %v, %v", versions, err)
+	}
+
+	rel, err := p.GetRelease(imageRepo, "")
+	if err != nil || rel.Version != "v1.2.0" || len(rel.Layers) != 0 {
+		t.Fatalf("Unexpected release %+v, %v", rel, err)
+	}
+
+	dir := t.TempDir()
+
+	for _, filePath := range []string{"/usr/local/bin/tool", "/usr/bin/tool", "/bin/tool", "/sbin/tool", "/lib/bin/tool"} {
+		dst := filepath.Join(dir, "tool")
+		if err := p.ExtractFile(rel.Ref, filePath, dst); err != nil {
+			t.Fatalf("Unable to extract %s %s", filePath, err)
+		}
+		if b, _ := os.ReadFile(dst); string(b) != "tool v1.2.0" {
+			t.Fatalf("Unexpected content %s from %s", b, filePath)
+		}
+	}
+
+	if err := p.ExtractFile(rel.Ref, "/missing", filepath.Join(dir, "missing")); err == nil {
+		t.Fatalf("Expected error for missing file")
+	}
+
+	if err := p.ExtractFile(rel.Ref, "/bin/loop", filepath.Join(dir, "loop")); err == nil {
+		t.Fatalf("Expected error for a symlink loop")
+	}
+
+	rel, err = p.GetRelease(artifactRepo, "0.3.0")
+	if err != nil || len(rel.Layers) != 2 || rel.Layers[0].Name != "tool_linux_amd64" {
+		t.Fatalf("Unexpected artifact %+v, %v", rel, err)
+	}
+
+	dst := filepath.Join(dir, rel.Layers[0].Name)
+	if err := p.PullLayer(rel.Layers[0].Digest, dst); err != nil {
+		t.Fatalf("Unable to pull layer %s", err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "tool_linux_amd64 content" {
+		t.Fatalf("Unexpected layer content %s", b)
+	}
+}
@@ -24,9 +24,9 @@ func getAssets(r []BinmanRelease) ([]string, error) {
 			continue
 		}
 
-		rel.versions, err = sortSemvers(rel.versions)
+		rel.versions, err = sortVersions(rel.VersionScheme, rel.versions, rel.createdIn(bdb))
 		if err != nil {
-			log.Warnf("Unable to sort semvers for %s %s", rel.Repo, err)
+			log.Warnf("Unable to sort versions for %s %s", rel.Repo, err)
 			continue
 		}
 		if len(rel.versions) == 0 {
@@ -56,14 +56,14 @@ func getAssets(r []BinmanRelease) ([]string, error) {
 	return assets, nil
 }
 
-func BuildOciImage(config, repo, targetImageName, baseImage, imagePath string) error {
+func BuildOciImage(config, repo, targetImageName, baseImage, imagePath string, sel ReleaseSelector) error {
 
 	img, err := oci.MakeBinmanImageBuild(targetImageName, imagePath, baseImage)
 	if err != nil {
 		return err
 	}
 
-	c := NewBMConfig(config).SetConfig(false)
+	c := NewBMConfig(config).SetConfig(false).Select(sel)
 
 	switch repo {
 	case "":
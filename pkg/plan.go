@@ -0,0 +1,289 @@
+package binman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Plan statuses
+const (
+	PlanUpdate   = "update"   // a new version will be synced
+	PlanCurrent  = "current"  // the release is up to date
+	PlanExcluded = "excluded" // the release is excluded on this os
+	PlanDeferred = "deferred" // the source rate limit is exhausted
+	PlanError    = "error"    // the release could not be resolved
+)
+
+// PlanEntry is the planned change for a single release
+type PlanEntry struct {
+	Repo      string   `json:"repo"`
+	Source    string   `json:"source"`              // source identifier versions are stored under
+	ServedBy  string   `json:"servedBy,omitempty"`  // source the release was found on
+	Installed string   `json:"installed,omitempty"` // version currently linked or stored
+	Version   string   `json:"version,omitempty"`   // resolved upstream version
+	Status    string   `json:"status"`
+	Asset     string   `json:"asset,omitempty"`
+	URL       string   `json:"url,omitempty"`       // url the asset will be downloaded from
+	SourceURL string   `json:"sourceUrl,omitempty"` // url before download rewrites were applied
+	Accept    string   `json:"accept,omitempty"`
+	CreatedAt int64    `json:"createdAt,omitempty"`
+	Actions   []string `json:"actions,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Plan is the set of changes a sync would make
+type Plan struct {
+	Config   string      `json:"config"`
+	Created  time.Time   `json:"created"`
+	Releases []PlanEntry `json:"releases"`
+}
+
+// Pending returns the number of releases with an update planned
+func (p *Plan) Pending() int {
+	n := 0
+	for _, e := range p.Releases {
+		if e.Status == PlanUpdate {
+			n++
+		}
+	}
+	return n
+}
+
+// Errors returns the number of releases that could not be resolved
+func (p *Plan) Errors() int {
+	n := 0
+	for _, e := range p.Releases {
+		if e.Status == PlanError {
+			n++
+		}
+	}
+	return n
+}
+
+// Write saves the plan as json to path
+func (p *Plan) Write(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Clean(path), append(b, '\n'), 0600)
+}
+
+// ReadPlan loads a plan written by Plan.Write
+func ReadPlan(path string) (*Plan, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("unable to parse plan %s - %w", path, err)
+	}
+
+	return p, nil
+}
+
+// For running the plan command. Releases are resolved but nothing is downloaded
+func NewBMPlan(configPath string) *BMConfig {
+	return NewBMConfig(configPath).WithDb().WithPlanning().WithOutput(false, false).SetConfig(true)
+}
+
+// WithPlanning will stop each release once its asset has been selected so the changes a sync would make can be reported
+func (config *BMConfig) WithPlanning() *BMConfig {
+	config.planning = true
+	return config
+}
+
+// Plan builds a plan from the results of CollectData
+func (config *BMConfig) Plan() *Plan {
+
+	p := &Plan{Config: config.ConfigPath, Created: time.Now().UTC()}
+
+	for _, msg := range config.Msgs {
+		rel := msg.Rel
+
+		e := PlanEntry{
+			Repo:      rel.Repo,
+			Source:    rel.SourceIdentifier,
+			Installed: rel.installedVersion(),
+			Version:   rel.Version,
+		}
+
+		switch msg.Err.(type) {
+		case nil:
+			e.Status = PlanUpdate
+			e.ServedBy = rel.servedBy()
+			e.Asset = rel.assetName
+			e.URL = rel.dlUrl
+			e.SourceURL = rel.preRewriteUrl
+			e.Accept = rel.dlAccept
+			e.CreatedAt = rel.createdAtTime
+			e.Actions = rel.plannedActions()
+		case *NoUpdateError:
+			e.Status = PlanCurrent
+		case *ExcludeError:
+			e.Status = PlanExcluded
+		case *DeferredError:
+			e.Status = PlanDeferred
+			e.Error = msg.Err.Error()
+		default:
+			e.Status = PlanError
+			e.Error = msg.Err.Error()
+		}
+
+		p.Releases = append(p.Releases, e)
+	}
+
+	return p
+}
+
+// ApplyPlan limits the releases of config to the updates in p. Each release is synced to exactly the version and asset that was planned
+func (config *BMConfig) ApplyPlan(p *Plan) error {
+
+	var releases []BinmanRelease
+	var errs []error
+
+	for index := range p.Releases {
+		e := p.Releases[index]
+		if e.Status != PlanUpdate {
+			continue
+		}
+
+		found := false
+		for _, rel := range config.Releases {
+			if rel.Repo != e.Repo || rel.SourceIdentifier != e.Source {
+				continue
+			}
+
+			rel.applyEntry = &e
+			if source, exists := config.Config.SourceMap[e.ServedBy]; exists {
+				rel.source = source
+			}
+
+			releases = append(releases, rel)
+			found = true
+			break
+		}
+
+		if !found {
+			errs = append(errs, fmt.Errorf("%s(%s) is in the plan but not in the config", e.Repo, e.Source))
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	config.Releases = releases
+	return nil
+}
+
+// installedVersion returns the version the link of a release points at. If the release is not linked the newest version on disk is returned
+func (r *BinmanRelease) installedVersion() string {
+
+	linkName := r.LinkName
+	if linkName == "" {
+		linkName = r.project
+	}
+
+	if target, err := os.Readlink(filepath.Join(r.BinPath, linkName)); err == nil {
+		if rel, err := filepath.Rel(r.repoPath(), target); err == nil && !strings.HasPrefix(rel, "..") {
+			return strings.Split(rel, string(filepath.Separator))[0]
+		}
+	}
+
+	versions, err := r.sortVersions(GetVersionFromPath(r.repoPath()))
+	if err != nil || len(versions) == 0 {
+		return ""
+	}
+
+	return versions[len(versions)-1]
+}
+
+// plannedActions describes the post actions a sync of the release would perform
+func (r *BinmanRelease) plannedActions() []string {
+
+	var actions []string
+
+	if !r.PostOnly {
+		if r.source != nil && r.source.Apitype == "oci" {
+			actions = append(actions, fmt.Sprintf("pull %s", r.assetName))
+		} else {
+			actions = append(actions, fmt.Sprintf("download %s", r.assetName))
+		}
+
+		if !r.DownloadOnly {
+			if t := findfType(r.assetName); t == "tar" || t == "zip" {
+				actions = append(actions, fmt.Sprintf("extract %s", r.assetName))
+			}
+			if r.UpxConfig.Enabled == "true" {
+				actions = append(actions, "upx")
+			}
+		}
+	}
+
+	for _, c := range r.PostCommands {
+		actions = append(actions, strings.TrimSpace(fmt.Sprintf("run %s %s", c.Command, strings.Join(c.Args, " "))))
+	}
+
+	if !r.PostOnly && !r.DownloadOnly {
+		linkName := r.LinkName
+		if linkName == "" {
+			linkName = r.project
+		}
+		actions = append(actions, fmt.Sprintf("link %s to %s", filepath.Join(r.BinPath, linkName), r.Version))
+	}
+
+	return actions
+}
+
+type PlanAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddPlanAction() Action {
+	return &PlanAction{
+		r,
+	}
+}
+
+// PlanAction ends a planned release once its asset has been selected
+func (action *PlanAction) execute() error {
+	action.r.actions = nil
+	return nil
+}
+
+type ApplyPlanAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddApplyPlanAction() Action {
+	return &ApplyPlanAction{
+		r,
+	}
+}
+
+// ApplyPlanAction sets the version and asset of a release from its plan entry instead of querying the source
+func (action *ApplyPlanAction) execute() error {
+
+	e := action.r.applyEntry
+
+	action.r.Version = e.Version
+	action.r.assetName = e.Asset
+	action.r.dlAccept = e.Accept
+	action.r.createdAtTime = e.CreatedAt
+
+	// Rewrites are applied again so rewritten downloads get the credentials of their rule
+	action.r.dlUrl = e.URL
+	if e.SourceURL != "" {
+		action.r.dlUrl = e.SourceURL
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package binman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/templating"
+)
+
+func writePlanTestSource(t *testing.T, sourcePath string, versions ...string) {
+	for _, version := range versions {
+		dir := filepath.Join(sourcePath, "org1", "tool", version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		asset := fmt.Sprintf("tool_%s_%s_%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+		writeTestTarGz(t, filepath.Join(dir, asset), "tool", "#!/bin/sh\necho "+version+"\n")
+	}
+}
+
+func planTestConfig(t *testing.T, cf string, releasePath string, planning bool) *BMConfig {
+	var dwg sync.WaitGroup
+	dbOptions := db.DbConfig{
+		Dwg:       &dwg,
+		DbChan:    make(chan db.DbMsg),
+		Path:      filepath.Join(releasePath, "binman.db"),
+		Overwrite: true,
+	}
+
+	c := NewBMConfig(cf).WithDb(dbOptions)
+	if planning {
+		return c.WithPlanning().WithOutput(false, false).SetConfig(false)
+	}
+
+	return c.WithDownloader().WithOutput(false, false).SetConfig(false)
+}
+
+// TestPlanApply plans a sync against a file source, then applies the plan after a newer version is published
+func TestPlanApply(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	sourcePath := t.TempDir()
+	releasePath := t.TempDir()
+
+	writePlanTestSource(t, sourcePath, "v1.0.0")
+
+	cf := filepath.Join(releasePath, "config")
+	cfg := templating.TemplateString(fileSourceConfig, map[string]any{"releasePath": releasePath, "sourcePath": sourcePath})
+	if err := WriteStringtoFile(cf, cfg); err != nil {
+		t.Fatalf("Unable to write test config")
+	}
+
+	c := planTestConfig(t, cf, releasePath, true)
+	c.CollectData()
+	c.BMClose()
+
+	p := c.Plan()
+	if p.Pending() != 1 || p.Errors() != 0 {
+		t.Fatalf("Expected 1 pending update and no errors got %+v", p)
+	}
+
+	e := p.Releases[0]
+	if e.Version != "v1.0.0" || e.Installed != "" || e.URL == "" {
+		t.Fatalf("Unexpected plan entry %+v", e)
+	}
+
+	if len(e.Actions) != 3 {
+		t.Fatalf("Expected download, extract and link actions got %v", e.Actions)
+	}
+
+	// Nothing is downloaded by a plan
+	if _, err := os.Stat(filepath.Join(releasePath, "tool")); err == nil {
+		t.Fatalf("plan should not link tool")
+	}
+
+	planFile := filepath.Join(releasePath, "binman.plan")
+	if err := p.Write(planFile); err != nil {
+		t.Fatalf("Unable to write plan - %s", err)
+	}
+
+	// apply must sync the planned version even though a newer one exists now
+	writePlanTestSource(t, sourcePath, "v1.1.0")
+
+	read, err := ReadPlan(planFile)
+	if err != nil {
+		t.Fatalf("Unable to read plan - %s", err)
+	}
+
+	c = planTestConfig(t, cf, releasePath, false)
+	if err := c.ApplyPlan(read); err != nil {
+		t.Fatalf("Unable to apply plan - %s", err)
+	}
+	c.CollectData()
+	c.BMClose()
+
+	if len(c.Msgs) != 1 || c.Msgs[0].Err != nil {
+		t.Fatalf("Expected a successful apply got %+v", c.Msgs)
+	}
+
+	target, err := filepath.EvalSymlinks(filepath.Join(releasePath, "tool"))
+	if err != nil {
+		t.Fatalf("Expected link to tool - %s", err)
+	}
+
+	if filepath.Base(filepath.Dir(target)) != "v1.0.0" {
+		t.Fatalf("Expected link into v1.0.0 got %s", target)
+	}
+
+	// A new plan reports the installed version and the pending update
+	c = planTestConfig(t, cf, releasePath, true)
+	c.CollectData()
+	c.BMClose()
+
+	p = c.Plan()
+	if p.Pending() != 1 || p.Releases[0].Installed != "v1.0.0" || p.Releases[0].Version != "v1.1.0" {
+		t.Fatalf("Expected v1.0.0 -> v1.1.0 got %+v", p.Releases)
+	}
+}
+
+func TestApplyPlanMissingRelease(t *testing.T) {
+
+	c := &BMConfig{Releases: []BinmanRelease{{Repo: "org/tool", SourceIdentifier: "github.com"}}}
+
+	p := &Plan{Releases: []PlanEntry{
+		{Repo: "org/tool", Source: "github.com", Status: PlanCurrent},
+		{Repo: "org/other", Source: "github.com", Status: PlanUpdate},
+	}}
+
+	if err := c.ApplyPlan(p); err == nil {
+		t.Fatalf("Expected an error for a release missing from the config")
+	}
+
+	p.Releases = p.Releases[:1]
+	if err := c.ApplyPlan(p); err != nil || len(c.Releases) != 0 {
+		t.Fatalf("Expected no releases to apply got %+v - %v", c.Releases, err)
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+
+	p := &Plan{Releases: []PlanEntry{{Repo: "org/a", Status: PlanUpdate}, {Repo: "org/b", Status: PlanError}, {Repo: "org/c", Status: PlanError}}}
+
+	if p.Pending() != 1 || p.Errors() != 2 {
+		t.Fatalf("Expected 1 pending and 2 errors got %d %d", p.Pending(), p.Errors())
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/rjbrown57/binman/pkg/downloader"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/oci"
 	"github.com/rjbrown57/binman/pkg/templating"
 )
 
@@ -32,10 +33,15 @@ func (action *DownloadAction) execute() error {
 	rWg.Add(1)
 
 	action.r.downloadChan <- downloader.DlMsg{Url: action.r.dlUrl,
-		Filepath:    action.r.filepath,
-		Wg:          &rWg,
-		ConfirmChan: confirmChan,
-		DlAuth:      &downloader.DlAuth{Token: action.r.source.Tokenvar, Header: "Authorization"},
+		Filepath:     action.r.filepath,
+		Name:         fmt.Sprintf("%s(%s)", action.r.Repo, action.r.Version),
+		Wg:           &rWg,
+		ConfirmChan:  confirmChan,
+		Accept:       action.r.dlAccept,
+		DlAuth:       action.r.dlAuth(),
+		ProgressChan: action.r.output.ProgressChan,
+		Transport:    action.r.dlTransport(),
+		FileRoot:     action.r.dlFileRoot(),
 	}
 
 	action.r.output.SendSpin(fmt.Sprintf("Downloading %s(%s)", action.r.Repo, action.r.Version))
@@ -54,6 +60,43 @@ func (action *DownloadAction) execute() error {
 	return nil
 }
 
+type OCIPullAction struct {
+	r      *BinmanRelease
+	puller *oci.Puller
+}
+
+func (r *BinmanRelease) AddOCIPullAction(puller *oci.Puller) Action {
+	return &OCIPullAction{
+		r,
+		puller,
+	}
+}
+
+// OCIPullAction copies the target file out of an image, or pulls the selected artifact layer
+func (action *OCIPullAction) execute() error {
+
+	var err error
+
+	action.r.output.SendSpin(fmt.Sprintf("Pulling %s(%s)", action.r.Repo, action.r.Version))
+
+	switch action.r.ImagePath {
+	case "":
+		err = action.puller.PullLayer(action.r.dlUrl, action.r.filepath)
+	default:
+		imagePath := templating.TemplateString(action.r.ImagePath, action.r.getDataMap())
+		err = action.puller.ExtractFile(action.r.dlUrl, imagePath, action.r.filepath)
+	}
+
+	if err != nil {
+		action.r.output.SendSpin(fmt.Sprintf("Error Pulling %s(%s)", action.r.Repo, action.r.Version))
+		return action.r.deferOnRateLimit(err)
+	}
+
+	action.r.output.SendSpin(fmt.Sprintf("Pull of %s(%s) finished", action.r.Repo, action.r.Version))
+
+	return nil
+}
+
 // link action
 
 type LinkFileAction struct {
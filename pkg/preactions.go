@@ -10,9 +10,13 @@ import (
 	"runtime"
 
 	"github.com/google/go-github/v50/github"
+	"github.com/rjbrown57/binman/pkg/filesource"
 	"github.com/rjbrown57/binman/pkg/gh"
+	"github.com/rjbrown57/binman/pkg/gitea"
 	"github.com/rjbrown57/binman/pkg/gl"
+	"github.com/rjbrown57/binman/pkg/httpindex"
 	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/oci"
 	"github.com/rjbrown57/binman/pkg/templating"
 	"gitlab.com/gitlab-org/api/client-go"
 )
@@ -105,22 +109,9 @@ func (action *SetUrlAction) execute() error {
 		return nil
 	}
 
-	// Attempt to apply templating to os and arch as well. Since we're looking to template these fields we can't rely on
-	// getting them directly from action.r.getDataMap() as it may return a templated string instead of what we expect.
-	// Instead we rely on setting the data map back to the defaults for the environment to allow the user to template
-	// them.
-	dataMapWithDefaults := action.r.getDataMap()
-	dataMapWithDefaults["os"] = runtime.GOOS
-	dataMapWithDefaults["arch"] = runtime.GOARCH
-	if action.r.Arch != "" {
-		action.r.Arch = templating.TemplateString(action.r.Arch, dataMapWithDefaults)
-		log.Debugf("Architecture set to: %s", action.r.Arch)
-	}
-	if action.r.Os != "" {
-		log.Debugf("OS before transition: %s", action.r.Os)
-		action.r.Os = templating.TemplateString(action.r.Os, dataMapWithDefaults)
-		log.Debugf("OS set to: %s", action.r.Os)
-	}
+	log.Debugf("OS before transition: %s", action.r.Os)
+	action.r.Os, action.r.Arch = action.r.platform()
+	log.Debugf("OS set to: %s Architecture set to: %s", action.r.Os, action.r.Arch)
 
 	switch data := action.r.relData.(type) {
 	case *github.RepositoryRelease:
@@ -132,7 +123,16 @@ func (action *SetUrlAction) execute() error {
 		} else {
 			// Attempt to find the asset via arch/os
 			log.Debugf("Attempt to find github asset for %s", action.r.project)
-			action.r.assetName, action.r.dlUrl = selectAsset(action.r.Arch, action.r.Os, action.r.Version, action.r.project, gh.GHGetAssetData(data.Assets))
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(gh.GHGetAssetData(data.Assets))
+		}
+
+		// Private assets can only be fetched with auth through the release asset api
+		if action.r.source.Token() != "" {
+			if apiUrl := gh.GetAssetAPIUrl(action.r.assetName, data.Assets); apiUrl != "" {
+				log.Debugf("Using release asset api %s for %s", apiUrl, action.r.assetName)
+				action.r.dlUrl = apiUrl
+				action.r.dlAccept = "application/octet-stream"
+			}
 		}
 	case []*gitlab.ReleaseLink:
 		// If the user has requested a specifc asset check for that
@@ -143,7 +143,55 @@ func (action *SetUrlAction) execute() error {
 		} else {
 			// Attempt to find the asset via arch/os
 			log.Debugf("Attempt to find gitlab asset for %s\n", action.r.project)
-			action.r.assetName, action.r.dlUrl = selectAsset(action.r.Arch, action.r.Os, action.r.Version, action.r.project, gl.GLGetAssetData(data))
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(gl.GLGetAssetData(data))
+		}
+	case *gitea.Release:
+		// If the user has requested a specifc asset check for that
+		if action.r.ReleaseFileName != "" {
+			rFilename := templating.TemplateString(action.r.ReleaseFileName, action.r.getDataMap())
+			log.Debugf("Get gitea asset by name %s", rFilename)
+			action.r.assetName, action.r.dlUrl = gitea.GetAssetbyName(rFilename, data.Assets)
+		} else {
+			// Attempt to find the asset via arch/os
+			log.Debugf("Attempt to find gitea asset for %s", action.r.project)
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(gitea.GiteaGetAssetData(data.Assets))
+		}
+	case *httpindex.Release:
+		// If the user has requested a specifc asset check for that
+		if action.r.ReleaseFileName != "" {
+			rFilename := templating.TemplateString(action.r.ReleaseFileName, action.r.getDataMap())
+			log.Debugf("Get index asset by name %s", rFilename)
+			action.r.assetName, action.r.dlUrl = httpindex.GetAssetbyName(rFilename, data.Assets)
+		} else {
+			// Attempt to find the asset via arch/os
+			log.Debugf("Attempt to find index asset for %s under %s", action.r.project, data.URL)
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(httpindex.GetAssetData(data.Assets))
+		}
+	case *oci.Release:
+		switch {
+		case action.r.ImagePath != "":
+			// The file is copied out of the image filesystem
+			imagePath := templating.TemplateString(action.r.ImagePath, action.r.getDataMap())
+			action.r.assetName, action.r.dlUrl = path.Base(imagePath), data.Ref
+		case action.r.ReleaseFileName != "":
+			rFilename := templating.TemplateString(action.r.ReleaseFileName, action.r.getDataMap())
+			log.Debugf("Get oci artifact layer by name %s", rFilename)
+			action.r.assetName, action.r.dlUrl = oci.GetLayerbyName(rFilename, data.Layers)
+		default:
+			// Attempt to find the layer via arch/os
+			log.Debugf("Attempt to find oci artifact layer for %s", action.r.project)
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(oci.GetLayerData(data.Layers))
+		}
+	case *filesource.Release:
+		// If the user has requested a specifc asset check for that
+		if action.r.ReleaseFileName != "" {
+			rFilename := templating.TemplateString(action.r.ReleaseFileName, action.r.getDataMap())
+			log.Debugf("Get file asset by name %s", rFilename)
+			action.r.assetName, action.r.dlUrl = filesource.GetAssetbyName(rFilename, data.Assets)
+		} else {
+			// Attempt to find the asset via arch/os
+			log.Debugf("Attempt to find file asset for %s in %s", action.r.project, data.Path)
+			action.r.assetName, action.r.dlUrl = action.r.selectAsset(filesource.GetAssetData(data.Assets))
 		}
 	// TODO should we use a pointer here like the above from better devs than myself?
 	case BinmanQueryResponse:
@@ -0,0 +1,215 @@
+package binman
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rjbrown57/binman/pkg/downloader"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/theckman/yacspin"
+)
+
+const (
+	progressRefresh     = 200 * time.Millisecond // how often the tty view is redrawn
+	progressLogInterval = 5 * time.Second        // how often plain progress lines are logged
+)
+
+// downloadState tracks a single active download for the progress view
+type downloadState struct {
+	name    string
+	current int64
+	total   int64
+	started time.Time
+}
+
+// rate returns the average bytes per second since the download started
+func (d *downloadState) rate(now time.Time) float64 {
+	elapsed := now.Sub(d.started).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(d.current) / elapsed
+}
+
+// format renders a single progress line. e.g "rjbrown57/binman(v0.1.0)  42.0%  3.1 MiB/7.4 MiB  1.2 MiB/s  ETA 4s"
+func (d *downloadState) format(now time.Time) string {
+	rate := d.rate(now)
+
+	if d.total <= 0 {
+		return fmt.Sprintf("%s  %s  %s/s", d.name, formatBytes(d.current), formatBytes(int64(rate)))
+	}
+
+	percent := float64(d.current) / float64(d.total) * 100
+
+	eta := "-"
+	if rate > 0 {
+		remaining := time.Duration(float64(d.total-d.current)/rate) * time.Second
+		eta = remaining.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s  %5.1f%%  %s/%s  %s/s  ETA %s", d.name, percent, formatBytes(d.current), formatBytes(d.total), formatBytes(int64(rate)), eta)
+}
+
+// formatBytes returns a human readable IEC representation of b
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// progressView renders active downloads. When tty is true a multi-line view with one line per download is drawn,
+// otherwise plain log lines are emitted periodically
+type progressView struct {
+	out     io.Writer
+	tty     bool
+	spinner *yacspin.Spinner // paused while the tty view owns the terminal
+
+	active  map[string]*downloadState
+	order   []string // preserve the order downloads started in
+	lines   int      // number of lines drawn by the last render
+	lastLog time.Time
+	paused  bool
+}
+
+func newProgressView(out io.Writer, tty bool, spinner *yacspin.Spinner) *progressView {
+	return &progressView{
+		out:     out,
+		tty:     tty,
+		spinner: spinner,
+		active:  make(map[string]*downloadState),
+		lastLog: time.Now(),
+	}
+}
+
+// update records a progress message, retiring the download if it has completed
+func (p *progressView) update(msg downloader.Progress) {
+
+	d, exists := p.active[msg.Name]
+	if !exists && !msg.Done {
+		d = &downloadState{name: msg.Name, started: time.Now()}
+		p.active[msg.Name] = d
+		p.order = append(p.order, msg.Name)
+	}
+
+	if msg.Done {
+		p.retire(msg)
+		return
+	}
+
+	d.current, d.total = msg.Current, msg.Total
+
+	if p.tty {
+		p.render()
+	}
+}
+
+// retire removes a finished download from the view
+func (p *progressView) retire(msg downloader.Progress) {
+
+	d, exists := p.active[msg.Name]
+
+	if !p.tty {
+		switch {
+		case msg.Err != nil:
+			log.Infof("%s download failed after %s - %s", msg.Name, formatBytes(msg.Current), msg.Err)
+		case exists:
+			log.Infof("%s downloaded %s in %s", msg.Name, formatBytes(msg.Current), time.Since(d.started).Round(time.Millisecond))
+		}
+	}
+
+	delete(p.active, msg.Name)
+	for i, name := range p.order {
+		if name == msg.Name {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+
+	if p.tty {
+		p.render()
+	}
+}
+
+// tick is called periodically to refresh rates and emit plain log lines
+func (p *progressView) tick() {
+	if p.tty {
+		if len(p.active) > 0 {
+			p.render()
+		}
+		return
+	}
+
+	if len(p.active) == 0 || time.Since(p.lastLog) < progressLogInterval {
+		return
+	}
+
+	p.lastLog = time.Now()
+	for _, line := range p.currentLines() {
+		log.Infof("%s", line)
+	}
+}
+
+func (p *progressView) currentLines() []string {
+	now := time.Now()
+	lines := make([]string, 0, len(p.order))
+	for _, name := range p.order {
+		lines = append(lines, p.active[name].format(now))
+	}
+	return lines
+}
+
+// render redraws the tty view in place
+func (p *progressView) render() {
+
+	// The spinner and the view can't share a line, so the spinner is paused while downloads are active
+	if len(p.active) > 0 && !p.paused && p.spinner != nil {
+		if err := p.spinner.Pause(); err == nil {
+			p.paused = true
+			fmt.Fprint(p.out, "\r\033[K")
+		}
+	}
+
+	var b strings.Builder
+
+	// Move back to the start of the previous render and clear it
+	if p.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dA", p.lines)
+	}
+	b.WriteString("\r\033[J")
+
+	lines := p.currentLines()
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	fmt.Fprint(p.out, b.String())
+	p.lines = len(lines)
+
+	if len(p.active) == 0 && p.paused {
+		p.paused = false
+		if err := p.spinner.Unpause(); err != nil {
+			log.Debugf("Unable to unpause spinner - %s", err)
+		}
+	}
+}
+
+// finish clears any remaining output so the spinner can print its stop message
+func (p *progressView) finish() {
+	if p.tty && p.lines > 0 {
+		p.active = make(map[string]*downloadState)
+		p.order = nil
+		p.render()
+	}
+}
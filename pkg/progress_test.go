@@ -0,0 +1,72 @@
+package binman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjbrown57/binman/pkg/downloader"
+)
+
+func TestFormatBytes(t *testing.T) {
+	var tests = []struct {
+		bytes    int64
+		expected string
+	}{
+		{512, "512 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GiB"},
+	}
+
+	for _, test := range tests {
+		if got := formatBytes(test.bytes); got != test.expected {
+			t.Fatalf("Expected %s got %s", test.expected, got)
+		}
+	}
+}
+
+func TestDownloadStateFormat(t *testing.T) {
+	now := time.Now()
+
+	d := downloadState{name: "org/repo(v1.0.0)", current: 512, total: 1024, started: now.Add(-1 * time.Second)}
+	got := d.format(now)
+	for _, expected := range []string{"org/repo(v1.0.0)", "50.0%", "512 B/1.0 KiB", "512 B/s", "ETA 1s"} {
+		if !strings.Contains(got, expected) {
+			t.Fatalf("Expected %q to contain %q", got, expected)
+		}
+	}
+
+	// Unknown length downloads should not report percent or eta
+	d.total = -1
+	if got = d.format(now); strings.Contains(got, "%") || strings.Contains(got, "ETA") {
+		t.Fatalf("Unexpected percent/eta in %q", got)
+	}
+}
+
+func TestProgressView(t *testing.T) {
+	var out bytes.Buffer
+
+	view := newProgressView(&out, true, nil)
+
+	view.update(downloader.Progress{Name: "one", Current: 1, Total: 2})
+	view.update(downloader.Progress{Name: "two", Current: 1, Total: 2})
+
+	if view.lines != 2 || len(view.active) != 2 {
+		t.Fatalf("Expected 2 active lines got %d", view.lines)
+	}
+
+	view.update(downloader.Progress{Name: "one", Current: 2, Total: 2, Done: true})
+
+	if view.lines != 1 || view.order[0] != "two" {
+		t.Fatalf("Expected only two to remain active, got %s", view.order)
+	}
+
+	view.finish()
+
+	if view.lines != 0 || len(view.active) != 0 {
+		t.Fatalf("Expected view to be cleared")
+	}
+}
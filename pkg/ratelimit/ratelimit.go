@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+const (
+	DefaultConcurrency = 4
+	DefaultMaxWait     = 60 * time.Second
+)
+
+// LimitedError is returned when a request can not be made until the rate limit resets, and the reset is further away than we are willing to wait
+type LimitedError struct {
+	Host  string
+	Reset time.Time
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("api rate limit for %s exhausted until %s", e.Host, e.Reset.Format(time.DateTime))
+}
+
+// limitState is the last known rate limit state for a single api resource
+type limitState struct {
+	remaining int
+	reset     time.Time
+}
+
+// Transport caps the number of concurrent requests to an api and tracks rate limits from response headers.
+// When the limit is exhausted requests wait for the reset, or fail with LimitedError if the reset is more than MaxWait away.
+// Both github (X-RateLimit-*) and gitlab (RateLimit-*) style headers are understood.
+type Transport struct {
+	Base    http.RoundTripper
+	MaxWait time.Duration
+
+	sem    chan struct{}
+	mu     sync.Mutex
+	limits map[string]*limitState
+}
+
+// NewTransport will return a Transport wrapping base. If base is nil http.DefaultTransport is used
+func NewTransport(base http.RoundTripper, concurrency int, maxWait time.Duration) *Transport {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	return &Transport{
+		Base:    base,
+		MaxWait: maxWait,
+		sem:     make(chan struct{}, concurrency),
+		limits:  make(map[string]*limitState),
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base == nil {
+		return http.DefaultTransport
+	}
+	return t.Base
+}
+
+// resource returns the rate limit bucket a request counts against. Github tracks graphql and search separately from core
+func resource(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	default:
+		return "core"
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { <-t.sem }()
+
+	res := resource(req)
+
+	if err := t.wait(req, res); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.update(res, resp)
+
+	return resp, nil
+}
+
+// wait blocks until the resource has remaining requests. A request slot is reserved before returning
+func (t *Transport) wait(req *http.Request, res string) error {
+	for {
+		t.mu.Lock()
+		state, known := t.limits[res]
+		if !known || state.remaining > 0 || !time.Now().Before(state.reset) {
+			// Reserve a request so concurrent callers don't all spend the last remaining request
+			if known && state.remaining > 0 {
+				state.remaining--
+			}
+			t.mu.Unlock()
+			return nil
+		}
+		reset := state.reset
+		t.mu.Unlock()
+
+		d := time.Until(reset)
+		if d > t.MaxWait {
+			return &LimitedError{Host: req.URL.Host, Reset: reset}
+		}
+
+		log.Infof("api rate limit for %s exhausted, waiting %s for reset", req.URL.Host, d.Round(time.Second))
+
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return req.Context().Err()
+		}
+	}
+}
+
+// update records rate limit state from response headers
+func (t *Transport) update(res string, resp *http.Response) {
+
+	remaining, reset, ok := parseHeaders(resp)
+	if !ok {
+		return
+	}
+
+	// github reports the resource a request counted against
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" && r != res {
+		res = r
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.limits[res] = &limitState{remaining: remaining, reset: reset}
+	log.Tracef("api rate limit for %s(%s) remaining %d reset %s", resp.Request.URL.Host, res, remaining, reset.Format(time.DateTime))
+}
+
+// parseHeaders will return the remaining requests and reset time from a response
+func parseHeaders(resp *http.Response) (int, time.Time, bool) {
+
+	remaining, reset := -1, time.Time{}
+
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		r := resp.Header.Get(prefix + "Remaining")
+		if r == "" {
+			continue
+		}
+
+		var err error
+		if remaining, err = strconv.Atoi(r); err != nil {
+			return 0, reset, false
+		}
+
+		if s, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64); err == nil {
+			reset = time.Unix(s, 0)
+		}
+		break
+	}
+
+	// Secondary limits and 429s may only supply Retry-After
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden {
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return 0, time.Now().Add(time.Duration(s) * time.Second), true
+		}
+	}
+
+	if remaining < 0 {
+		return 0, reset, false
+	}
+
+	return remaining, reset, true
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// limitServer responds with the supplied remaining count and a reset resetIn from now
+func limitServer(remaining *atomic.Int64, resetIn time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining.Load()))
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(resetIn).Unix()))
+		fmt.Fprint(w, "ok")
+	}))
+}
+
+func TestTransportDefersPastMaxWait(t *testing.T) {
+	var remaining atomic.Int64
+
+	srv := limitServer(&remaining, time.Hour)
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, 1, time.Second)}
+
+	// The first request learns that no requests remain
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	resp.Body.Close()
+
+	_, err = c.Get(srv.URL)
+
+	var limited *LimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("Expected LimitedError got %v", err)
+	}
+
+	if time.Until(limited.Reset) < 59*time.Minute {
+		t.Fatalf("Unexpected reset time %s", limited.Reset)
+	}
+}
+
+func TestTransportWaitsForReset(t *testing.T) {
+	var remaining atomic.Int64
+
+	srv := limitServer(&remaining, 2*time.Second)
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, 1, 5*time.Second)}
+
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	resp.Body.Close()
+
+	remaining.Store(10)
+
+	start := time.Now()
+	resp, err = c.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected request to wait for reset, got %s", err)
+	}
+	resp.Body.Close()
+
+	if time.Since(start) < 100*time.Millisecond {
+		t.Fatalf("Expected request to wait for the reset")
+	}
+}
+
+func TestTransportConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, 2, time.Second)}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := c.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight.Load() > 2 {
+		t.Fatalf("Expected at most 2 concurrent requests got %d", maxInFlight.Load())
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	var tests = []struct {
+		headers   map[string]string
+		status    int
+		remaining int
+		ok        bool
+	}{
+		{map[string]string{"X-RateLimit-Remaining": "42", "X-RateLimit-Reset": "1700000000"}, 200, 42, true},
+		{map[string]string{"RateLimit-Remaining": "7", "RateLimit-Reset": "1700000000"}, 200, 7, true},
+		{map[string]string{"Retry-After": "30"}, http.StatusTooManyRequests, 0, true},
+		{map[string]string{}, 200, 0, false},
+	}
+
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+		for k, v := range test.headers {
+			resp.Header.Set(k, v)
+		}
+
+		remaining, _, ok := parseHeaders(resp)
+		if ok != test.ok || remaining != test.remaining {
+			t.Fatalf("%v: expected %d %t got %d %t", test.headers, test.remaining, test.ok, remaining, ok)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package binman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	bolt "go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+// Remove will uninstall repo. The release is removed from the config, its versions and links are deleted from disk and its
+// versions are deleted from the db. If keepFiles is set files on disk are left in place. If dryrun is set nothing is changed
+func Remove(repo string, keepFiles, dryrun bool, dbPath, config string) error {
+
+	c := NewBMConfig(config).SetConfig(false)
+
+	rel, err := c.findRelease(repo)
+	if err != nil {
+		return fmt.Errorf("%s - %w", repo, err)
+	}
+
+	// The config is edited first so a release that can't be removed from it keeps its files
+	if err := c.removeReleaseFromConfig(rel, dryrun); err != nil {
+		return err
+	}
+
+	if dryrun {
+		log.Infof("%s will be removed from %s", rel.Repo, c.ConfigPath)
+	}
+
+	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: false})
+
+	err = rel.remove(keepFiles, dryrun, bdb)
+
+	if closeErr := bdb.Close(); closeErr != nil {
+		log.Fatalf("Unable to close db %s", closeErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s was removed from %s but its files were not - %w", rel.Repo, c.ConfigPath, err)
+	}
+
+	if !dryrun {
+		log.Infof("%s removed", rel.Repo)
+	}
+
+	return nil
+}
+
+// findRelease returns the configured release for repo. repo can include a source prefix. e.g gitlab.com/org/project
+func (config *BMConfig) findRelease(repo string) (BinmanRelease, error) {
+
+	if rel, err := config.GetRelease(repo); err == nil {
+		return rel, nil
+	}
+
+	want := BinmanRelease{Repo: repo}
+	want.SetSource(config.Config.SourceMap)
+
+	for _, rel := range config.Releases {
+		if rel.Repo == want.Repo && rel.SourceIdentifier == want.SourceIdentifier {
+			return rel, nil
+		}
+	}
+
+	return want, ErrReleaseNotFound
+}
+
+// repoPath returns the directory all versions of a release are stored under
+func (r *BinmanRelease) repoPath() string {
+	return filepath.Join(strings.TrimSuffix(r.ReleasePath, "/"), "repos", r.SourceIdentifier, r.org, r.project)
+}
+
+// storedLinks returns the link paths recorded in the db for each stored version of a release
+func (r *BinmanRelease) storedLinks(bdb *bolt.DB) ([]string, error) {
+
+	var links []string
+
+	err := bdb.View(func(tx *bolt.Tx) error {
+		b := r.projectBucket(tx)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEachBucket(func(version []byte) error {
+			data := b.Bucket(version).Get([]byte("data"))
+			if data == nil {
+				return nil
+			}
+			if linkPath, _ := bytesToData(data)["linkPath"].(string); linkPath != "" {
+				links = append(links, linkPath)
+			}
+			return nil
+		})
+	})
+
+	return links, err
+}
+
+// releaseLinks returns every symlink pointing into the stored versions of a release. Links recorded in the db are checked
+// along with the contents of binpath, so links left behind by a changed linkname are found too
+func (r *BinmanRelease) releaseLinks(bdb *bolt.DB) ([]string, error) {
+
+	candidates, err := r.storedLinks(bdb)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries, err := os.ReadDir(r.BinPath); err == nil {
+		for _, e := range entries {
+			candidates = append(candidates, filepath.Join(r.BinPath, e.Name()))
+		}
+	}
+
+	repoPath := r.repoPath() + string(filepath.Separator)
+
+	var links []string
+	seen := make(map[string]bool)
+
+	for _, link := range candidates {
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		info, err := os.Lstat(link)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(link), target)
+		}
+
+		if strings.HasPrefix(filepath.Clean(target), repoPath) {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+func (r *BinmanRelease) remove(keepFiles, dryrun bool, bdb *bolt.DB) error {
+
+	if !keepFiles {
+		links, err := r.releaseLinks(bdb)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			log.Infof("%s: link %s will be deleted", r.Repo, link)
+			if dryrun {
+				continue
+			}
+			if err := os.Remove(link); err != nil {
+				return err
+			}
+		}
+
+		log.Infof("%s: %s will be deleted", r.Repo, r.repoPath())
+		if !dryrun {
+			if err := os.RemoveAll(r.repoPath()); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Infof("%s: stored versions will be deleted from the db", r.Repo)
+	if dryrun {
+		return nil
+	}
+
+	// Deleting the project bucket removes every version, the rollback marker and anything else stored for the release
+	return db.DeleteData(fmt.Sprintf("%s/%s", r.SourceIdentifier, r.Repo), bdb)
+}
+
+// removeReleaseFromConfig deletes rel from the releases of the config file. If dryrun is set the entry is only looked up. The config is edited as a yaml node tree so
+// comments and the order of keys are preserved
+func (config *BMConfig) removeReleaseFromConfig(rel BinmanRelease, dryrun bool) error {
+
+	doc, releases, err := readConfigReleases(config.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	var kept []*yaml.Node
+	removed := false
+
+	for _, node := range releases.Content {
+		var candidate BinmanRelease
+		if err := node.Decode(&candidate); err != nil {
+			return err
+		}
+
+		// Sources are resolved the same way as populateReleases so the entry matches however it names its source
+		if !candidate.hasSource(config.Config.SourceMap) {
+			candidate.Sources = config.Defaults.Sources
+		}
+		candidate.SetSource(config.Config.SourceMap)
+
+		if candidate.Repo == rel.Repo && candidate.SourceIdentifier == rel.SourceIdentifier {
+			removed = true
+			continue
+		}
+		kept = append(kept, node)
+	}
+
+	if !removed {
+		return fmt.Errorf("%s - %w", rel.Repo, ErrReleaseNotFound)
+	}
+
+	if dryrun {
+		return nil
+	}
+
+	releases.Content = kept
+
+	return writeConfigDoc(config.ConfigPath, doc)
+}
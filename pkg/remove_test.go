@@ -0,0 +1,106 @@
+package binman
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+const testRemoveConfig = `
+config:
+  releasepath: {{ .releasePath }} # where releases live
+releases:
+  # the tool we are removing
+  - repo: org1/repo1
+  # keep this one
+  - repo: org1/repo2
+    linkname: r2 # short name
+`
+
+func TestRemove(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testRemoveConfig, "remove")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	linkPath := writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2"})
+
+	// A link left behind by an old linkname should be found by scanning binpath
+	extraLink := filepath.Join(releasePath, "r1")
+	if err := createLink(filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", "v0.0.1", "repo1"), extraLink); err != nil {
+		t.Fatalf("Unable to create extra link - %s", err)
+	}
+
+	repoPath := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1")
+
+	if err := Remove("org1/repo1", false, true, dbPath, testConfig); err != nil {
+		t.Fatalf("Unexpected dry run error %s", err)
+	}
+
+	for _, p := range []string{linkPath, extraLink, repoPath} {
+		if _, err := os.Lstat(p); err != nil {
+			t.Fatalf("Dry run removed %s", p)
+		}
+	}
+
+	if err := Remove("github.com/org1/repo1", false, false, dbPath, testConfig); err != nil {
+		t.Fatalf("Unexpected remove error %s", err)
+	}
+
+	for _, p := range []string{linkPath, extraLink, repoPath} {
+		if _, err := os.Lstat(p); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed", p)
+		}
+	}
+
+	testDb := db.GetDB(dbPath)
+	if _, err := db.GetData("github.com/org1/repo1/v0.0.1/data", testDb); !errors.Is(err, db.ErrNilReadResponse) {
+		t.Fatalf("Expected versions to be removed from the db")
+	}
+	testDb.Close()
+
+	cfg, err := os.ReadFile(testConfig)
+	if err != nil {
+		t.Fatalf("Unable to read config %s", err)
+	}
+
+	got := string(cfg)
+	if strings.Contains(got, "org1/repo1") {
+		t.Fatalf("Expected org1/repo1 to be removed from config\n%s", got)
+	}
+	for _, want := range []string{"org1/repo2", "# keep this one", "# short name", "# where releases live"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Expected config to keep %q\n%s", want, got)
+		}
+	}
+
+	if err := Remove("org1/repo1", false, false, dbPath, testConfig); !errors.Is(err, ErrReleaseNotFound) {
+		t.Fatalf("Expected release not found, got %v", err)
+	}
+}
+
+func TestRemoveKeepFiles(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testRemoveConfig, "removekeep")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	linkPath := writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1"})
+
+	if err := Remove("org1/repo1", true, false, dbPath, testConfig); err != nil {
+		t.Fatalf("Unexpected remove error %s", err)
+	}
+
+	if _, err := os.Stat(linkPath); err != nil {
+		t.Fatalf("Expected files to be kept")
+	}
+}
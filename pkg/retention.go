@@ -0,0 +1,138 @@
+package binman
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention controls which synced versions binman clean removes. It can be set globally in the config and per release,
+// fields set on a release override the global value
+type Retention struct {
+	Keep    int    `yaml:"keep,omitempty"`    // number of newest versions to keep
+	MaxAge  string `yaml:"maxage,omitempty"`  // remove versions older than this. e.g 90d, 2w, 36h
+	MaxSize string `yaml:"maxsize,omitempty"` // remove the oldest versions until the store is smaller than this. e.g 2GiB, 500MB
+}
+
+// retentionPolicy is a parsed Retention
+type retentionPolicy struct {
+	keep    int
+	maxAge  time.Duration
+	maxSize int64
+}
+
+// merge returns r with the fields set in override replaced
+func (r Retention) merge(override *Retention) Retention {
+	if override == nil {
+		return r
+	}
+	if override.Keep != 0 {
+		r.Keep = override.Keep
+	}
+	if override.MaxAge != "" {
+		r.MaxAge = override.MaxAge
+	}
+	if override.MaxSize != "" {
+		r.MaxSize = override.MaxSize
+	}
+	return r
+}
+
+// policy parses r. If keep is not set defaultKeep is used
+func (r Retention) policy(defaultKeep int) (retentionPolicy, error) {
+
+	var err error
+
+	p := retentionPolicy{keep: r.Keep}
+	if p.keep == 0 {
+		p.keep = defaultKeep
+	}
+
+	if p.keep < 0 {
+		return p, fmt.Errorf("keep must be positive, got %d", p.keep)
+	}
+
+	if r.MaxAge != "" {
+		if p.maxAge, err = parseAge(r.MaxAge); err != nil {
+			return p, err
+		}
+	}
+
+	if r.MaxSize != "" {
+		if p.maxSize, err = parseSize(r.MaxSize); err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// parseAge parses a go duration with added support for days and weeks. e.g 90d
+func parseAge(s string) (time.Duration, error) {
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, found := strings.CutSuffix(s, suffix); found {
+			if v, err := strconv.ParseFloat(n, 64); err == nil && v > 0 {
+				return time.Duration(v * float64(unit)), nil
+			}
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid maxage %q. Use a duration such as 90d, 2w or 36h", s)
+	}
+
+	return d, nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	// Longest suffixes first so KiB is not read as B
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// parseSize parses a size in bytes. Binary (KiB, MiB, GiB, TiB) and decimal (KB, MB, GB, TB) units are supported
+func parseSize(s string) (int64, error) {
+
+	str := strings.TrimSpace(s)
+	unit := int64(1)
+
+	for _, u := range sizeUnits {
+		if n, found := strings.CutSuffix(str, u.suffix); found {
+			str, unit = strings.TrimSpace(n), u.bytes
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid maxsize %q. Use a size such as 2GiB or 500MB", s)
+	}
+
+	return int64(v * float64(unit)), nil
+}
+
+// formatSize returns a human readable size using binary units
+func formatSize(b int64) string {
+
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+	v := float64(b)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
@@ -0,0 +1,72 @@
+package binman
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+
+	var tests = []struct {
+		age      string
+		expected time.Duration
+		err      bool
+	}{
+		{age: "90d", expected: 90 * 24 * time.Hour},
+		{age: "2w", expected: 14 * 24 * time.Hour},
+		{age: "36h", expected: 36 * time.Hour},
+		{age: "1.5d", expected: 36 * time.Hour},
+		{age: "-1d", err: true},
+		{age: "soon", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseAge(test.age)
+		if (err != nil) != test.err || got != test.expected {
+			t.Fatalf("%s: expected %s (error %t) got %s %v", test.age, test.expected, test.err, got, err)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+
+	var tests = []struct {
+		size     string
+		expected int64
+		err      bool
+	}{
+		{size: "2GiB", expected: 2 << 30},
+		{size: "500MB", expected: 500 * 1000 * 1000},
+		{size: "1.5 KiB", expected: 1536},
+		{size: "12B", expected: 12},
+		{size: "1024", expected: 1024},
+		{size: "2XB", err: true},
+		{size: "0GiB", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := parseSize(test.size)
+		if (err != nil) != test.err || got != test.expected {
+			t.Fatalf("%s: expected %d (error %t) got %d %v", test.size, test.expected, test.err, got, err)
+		}
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+
+	global := Retention{Keep: 5, MaxAge: "30d"}
+
+	p, err := global.merge(&Retention{MaxSize: "1KiB"}).policy(3)
+	if err != nil || p.keep != 5 || p.maxAge != 30*24*time.Hour || p.maxSize != 1024 {
+		t.Fatalf("Unexpected merged policy %+v %v", p, err)
+	}
+
+	p, err = Retention{}.merge(nil).policy(3)
+	if err != nil || p.keep != 3 || p.maxAge != 0 || p.maxSize != 0 {
+		t.Fatalf("Expected keep to default to the threshold got %+v %v", p, err)
+	}
+
+	if _, err := global.merge(&Retention{MaxAge: "later"}).policy(3); err == nil {
+		t.Fatalf("Expected an invalid maxage to fail")
+	}
+}
@@ -0,0 +1,141 @@
+package binman
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rjbrown57/binman/pkg/downloader"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// Rewrite changes download urls before they are sent to the downloader, e.g to fetch assets through an artifact proxy.
+// Api queries are not rewritten. Exactly one of prefix or regex must be set
+type Rewrite struct {
+	Prefix  string `yaml:"prefix,omitempty"` // Url prefix to replace. e.g https://github.com/
+	Regex   string `yaml:"regex,omitempty"`  // Regex to replace. Capture groups can be used in replace as ${1}
+	Replace string `yaml:"replace"`          // Replacement for the matched prefix or regex
+
+	// Credentials sent to the rewritten host. Without credentials rewritten downloads are anonymous
+	Tokenvar     string   `yaml:"tokenvar,omitempty"`
+	Tokenfile    string   `yaml:"tokenfile,omitempty"`
+	Tokencommand []string `yaml:"tokencommand,omitempty"`
+	Netrc        bool     `yaml:"netrc,omitempty"`
+	Header       string   `yaml:"header,omitempty"` // Header the token is sent in. Default is Authorization
+	Scheme       string   `yaml:"scheme,omitempty"` // Prefix for the token. Default is Bearer when header is Authorization
+
+	rx *regexp.Regexp
+}
+
+// compile validates a rule and prepares its regex
+func (rw *Rewrite) compile() error {
+
+	switch {
+	case rw.Prefix != "" && rw.Regex != "":
+		return errors.New("only one of prefix or regex can be set")
+	case rw.Prefix == "" && rw.Regex == "":
+		return errors.New("one of prefix or regex must be set")
+	case rw.Replace == "":
+		return errors.New("replace must be set")
+	case rw.Regex != "":
+		rx, err := regexp.Compile(rw.Regex)
+		if err != nil {
+			return err
+		}
+		rw.rx = rx
+	}
+
+	return nil
+}
+
+// apply returns u rewritten by the rule, or false if the rule does not match
+func (rw *Rewrite) apply(u string) (string, bool) {
+
+	if rw.rx != nil {
+		if !rw.rx.MatchString(u) {
+			return u, false
+		}
+		return rw.rx.ReplaceAllString(u, rw.Replace), true
+	}
+
+	if rw.Prefix == "" || !strings.HasPrefix(u, rw.Prefix) {
+		return u, false
+	}
+
+	return rw.Replace + strings.TrimPrefix(u, rw.Prefix), true
+}
+
+// dlAuth returns the credential for a url rewritten by the rule. It is only ever sent to the host of the rewritten url
+func (rw *Rewrite) dlAuth(dlUrl string) *downloader.DlAuth {
+
+	u, err := url.Parse(dlUrl)
+	if err != nil {
+		return nil
+	}
+
+	// Credential resolution and caching are shared with sources
+	s := Source{Name: "rewrite " + rw.Replace, URL: dlUrl, Tokenvar: rw.Tokenvar, Tokenfile: rw.Tokenfile, Tokencommand: rw.Tokencommand, Netrc: rw.Netrc}
+
+	header, scheme := rw.Header, rw.Scheme
+	if header == "" {
+		header = "Authorization"
+		if scheme == "" {
+			scheme = "Bearer"
+		}
+	}
+
+	return downloader.NewDlAuth(s.Token(), header, scheme, u.Host)
+}
+
+// setRewrites validates the configured rewrite rules
+func (config *BMConfig) setRewrites() {
+	for index := range config.Config.Rewrites {
+		if err := config.Config.Rewrites[index].compile(); err != nil {
+			log.Fatalf("Invalid rewrite %d - %s", index, err)
+		}
+	}
+}
+
+type RewriteUrlAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddRewriteUrlAction() Action {
+	return &RewriteUrlAction{
+		r,
+	}
+}
+
+// RewriteUrlAction applies the first matching rewrite rule to the download url
+func (action *RewriteUrlAction) execute() error {
+
+	action.r.rewrite = nil
+	action.r.preRewriteUrl = ""
+
+	// oci downloads are image references pulled through the registry client, not urls
+	if action.r.source != nil && action.r.source.Apitype == "oci" {
+		return nil
+	}
+
+	for index := range action.r.rewrites {
+		rewritten, ok := action.r.rewrites[index].apply(action.r.dlUrl)
+		if !ok {
+			continue
+		}
+
+		log.Debugf("Rewrote download url %s to %s", action.r.dlUrl, rewritten)
+
+		if _, err := url.ParseRequestURI(rewritten); err != nil {
+			return fmt.Errorf("rewrite of %s produced an invalid url %s - %w", action.r.dlUrl, rewritten, err)
+		}
+
+		action.r.preRewriteUrl = action.r.dlUrl
+		action.r.dlUrl = rewritten
+		action.r.rewrite = &action.r.rewrites[index]
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package binman
+
+import (
+	"testing"
+)
+
+func TestRewriteApply(t *testing.T) {
+
+	var tests = []struct {
+		rule     Rewrite
+		url      string
+		expected string
+		match    bool
+	}{
+		{
+			rule:     Rewrite{Prefix: "https://github.com/", Replace: "https://artifactory.example.com/artifactory/github/"},
+			url:      "https://github.com/rjbrown57/binman/releases/download/v1.0.0/binman_linux_amd64.tar.gz",
+			expected: "https://artifactory.example.com/artifactory/github/rjbrown57/binman/releases/download/v1.0.0/binman_linux_amd64.tar.gz",
+			match:    true,
+		},
+		{
+			rule:     Rewrite{Prefix: "https://github.com/", Replace: "https://artifactory.example.com/artifactory/github/"},
+			url:      "https://gitlab.com/org/project/-/releases/v1.0.0/downloads/tool",
+			expected: "https://gitlab.com/org/project/-/releases/v1.0.0/downloads/tool",
+			match:    false,
+		},
+		{
+			rule:     Rewrite{Regex: `^https://([^/]+)/`, Replace: "https://proxy.example.com/${1}/"},
+			url:      "https://dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl",
+			expected: "https://proxy.example.com/dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl",
+			match:    true,
+		},
+	}
+
+	for caseNum, test := range tests {
+		if err := test.rule.compile(); err != nil {
+			t.Fatalf("%d unexpected compile error %s", caseNum, err)
+		}
+		got, match := test.rule.apply(test.url)
+		if got != test.expected || match != test.match {
+			t.Fatalf("%d expected %s,%t got %s,%t", caseNum, test.expected, test.match, got, match)
+		}
+	}
+}
+
+func TestRewriteCompile(t *testing.T) {
+
+	var invalid = []Rewrite{
+		{Replace: "https://proxy.example.com/"},
+		{Prefix: "https://github.com/", Regex: "github", Replace: "https://proxy.example.com/"},
+		{Prefix: "https://github.com/"},
+		{Regex: "(", Replace: "https://proxy.example.com/"},
+	}
+
+	for caseNum, rule := range invalid {
+		if err := rule.compile(); err == nil {
+			t.Fatalf("%d expected compile error for %+v", caseNum, rule)
+		}
+	}
+}
+
+func TestRewriteDlAuth(t *testing.T) {
+
+	t.Setenv("BINMAN_TEST_REWRITE_TOKEN", "proxysecret")
+
+	rule := Rewrite{Prefix: "https://github.com/", Replace: "https://artifactory.example.com/github/", Tokenvar: "BINMAN_TEST_REWRITE_TOKEN"}
+	auth := rule.dlAuth("https://artifactory.example.com/github/org/tool/tool.tar.gz")
+	if auth == nil || auth.Host != "artifactory.example.com" || auth.Header != "Authorization" || auth.Scheme != "Bearer" || auth.Token != "proxysecret" {
+		t.Fatalf("Unexpected rewrite credential %+v", auth)
+	}
+
+	rule = Rewrite{Prefix: "https://github.com/", Replace: "https://artifactory.example.com/github/", Tokenvar: "BINMAN_TEST_REWRITE_TOKEN", Header: "X-JFrog-Art-Api"}
+	if auth = rule.dlAuth("https://artifactory.example.com/github/tool"); auth == nil || auth.Header != "X-Jfrog-Art-Api" || auth.Scheme != "" {
+		t.Fatalf("Expected custom header without a scheme, got %+v", auth)
+	}
+
+	if auth = (&Rewrite{Prefix: "https://github.com/", Replace: "https://proxy.example.com/"}).dlAuth("https://proxy.example.com/tool"); auth != nil {
+		t.Fatalf("Expected anonymous download for a rule without credentials, got %+v", auth)
+	}
+}
+
+func TestRewriteUrlAction(t *testing.T) {
+
+	t.Setenv("BINMAN_TEST_SOURCE_TOKEN", "githubsecret")
+
+	rules := []Rewrite{
+		{Prefix: "https://example.com/", Replace: "https://unused.example.com/"},
+		{Prefix: "https://github.com/", Replace: "https://proxy.example.com/github/"},
+	}
+	for index := range rules {
+		if err := rules[index].compile(); err != nil {
+			t.Fatalf("unexpected compile error %s", err)
+		}
+	}
+
+	source := &Source{Name: "github.com", URL: "https://github.com", Apitype: "github", Tokenvar: "BINMAN_TEST_SOURCE_TOKEN"}
+
+	rel := BinmanRelease{
+		Repo:     "org/tool",
+		source:   source,
+		rewrites: rules,
+		dlUrl:    "https://github.com/org/tool/releases/download/v1.0.0/tool.tar.gz",
+	}
+
+	if err := rel.AddRewriteUrlAction().execute(); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if rel.dlUrl != "https://proxy.example.com/github/org/tool/releases/download/v1.0.0/tool.tar.gz" || rel.rewrite != &rel.rewrites[1] {
+		t.Fatalf("Expected second rule to rewrite url, got %s", rel.dlUrl)
+	}
+
+	// The source credential must not be sent to the proxy
+	if auth := rel.dlAuth(); auth != nil {
+		t.Fatalf("Expected anonymous proxy download, got %+v", auth)
+	}
+
+	// oci references are left alone
+	rel = BinmanRelease{
+		Repo:     "org/tool",
+		source:   &Source{Name: "ghcr.io", Apitype: "oci"},
+		rewrites: rules,
+		dlUrl:    "ghcr.io/org/tool:v1.0.0",
+	}
+	if err := rel.AddRewriteUrlAction().execute(); err != nil || rel.dlUrl != "ghcr.io/org/tool:v1.0.0" || rel.rewrite != nil {
+		t.Fatalf("Expected oci reference to be left alone, got %s %v", rel.dlUrl, err)
+	}
+}
@@ -0,0 +1,194 @@
+package binman
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+// rollbackDbKey is stored in the project bucket of a release that has been rolled back. Its value is the version the release is held at
+const rollbackDbKey = "rollback"
+
+// rollbackKey returns the db key holding the rollback version of a release
+func (r *BinmanRelease) rollbackKey() string {
+	return fmt.Sprintf("%s/%s/%s", r.SourceIdentifier, r.Repo, rollbackDbKey)
+}
+
+// Rollback will point the link of repo at version, or at the version before the active one if version is empty. Syncs will not
+// move the release forward again until it is released. Releasing links the newest stored version and resumes syncs
+func Rollback(repo, version string, release bool, dbPath, config string) error {
+
+	c := NewBMConfig(config).SetConfig(false)
+
+	rel, err := c.GetRelease(repo)
+	if err != nil {
+		return fmt.Errorf("%s - %w", repo, err)
+	}
+
+	bdb := db.GetDB(dbPath, bolt.Options{Timeout: 1 * time.Second, ReadOnly: false})
+
+	err = rel.rollback(version, release, bdb)
+
+	if closeErr := bdb.Close(); closeErr != nil {
+		log.Fatalf("Unable to close db %s", closeErr)
+	}
+
+	return err
+}
+
+func (r *BinmanRelease) rollback(version string, release bool, bdb *bolt.DB) error {
+
+	if err := r.getVersions(bdb); err != nil {
+		return err
+	}
+
+	if len(r.versions) == 0 {
+		return ErrNoVersionsFound
+	}
+
+	data := make(map[string]map[string]any)
+	for _, v := range r.versions {
+		byteData, err := db.GetData(fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, v), bdb)
+		if err != nil {
+			return fmt.Errorf("issue getting data for %s/%s: %w", r.Repo, v, err)
+		}
+		data[v] = bytesToData(byteData)
+	}
+
+	versions, err := sortVersions(r.VersionScheme, r.versions, func(v string) time.Time { return dataCreated(data[v]) })
+	if err != nil {
+		return err
+	}
+
+	active := activeVersion(versions, data)
+
+	var target string
+
+	switch {
+	case release:
+		target = versions[len(versions)-1]
+	case version != "":
+		if !slices.Contains(versions, version) {
+			return fmt.Errorf("%s(%s) is not stored, available versions are %v", r.Repo, version, versions)
+		}
+		target = version
+	default:
+		index := slices.Index(versions, active)
+		if index < 1 {
+			return fmt.Errorf("%s has no version older than %s stored", r.Repo, active)
+		}
+		target = versions[index-1]
+	}
+
+	artifactPath, _ := data[target]["artifactPath"].(string)
+	linkPath, _ := data[target]["linkPath"].(string)
+	if artifactPath == "" || linkPath == "" {
+		return fmt.Errorf("%s(%s) has no link recorded in the db. Versions found by a scan can not be linked", r.Repo, target)
+	}
+
+	if _, err := os.Stat(artifactPath); err != nil {
+		return fmt.Errorf("%s(%s) is missing from disk - %w", r.Repo, target, err)
+	}
+
+	if err := createLink(artifactPath, linkPath); err != nil {
+		return err
+	}
+
+	if release {
+		if err := db.DeleteData(r.rollbackKey(), bdb); err != nil {
+			return err
+		}
+		log.Infof("%s released and linked to %s, syncs will update it again", r.Repo, target)
+		return nil
+	}
+
+	if err := db.WriteData(true, r.rollbackKey(), []byte(target), bdb); err != nil {
+		return err
+	}
+
+	log.Infof("%s rolled back from %s to %s. Run `binman rollback --release %s` to resume updates", r.Repo, active, target, r.Repo)
+
+	return nil
+}
+
+// activeVersion returns the version the link of a release currently points at. If it can't be determined the newest version is returned
+func activeVersion(versions []string, data map[string]map[string]any) string {
+
+	latest := versions[len(versions)-1]
+
+	linkPath, _ := data[latest]["linkPath"].(string)
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		return latest
+	}
+
+	for _, v := range versions {
+		if artifactPath, _ := data[v]["artifactPath"].(string); artifactPath == target {
+			return v
+		}
+	}
+
+	return latest
+}
+
+// getRollback returns the version a release has been rolled back to, or an empty string if it has not been rolled back
+func (r *BinmanRelease) getRollback() (string, error) {
+
+	r.dwg.Add(1)
+
+	var rwg sync.WaitGroup
+
+	dbMsg := db.DbMsg{
+		Operation:  "read",
+		Key:        r.rollbackKey(),
+		ReturnChan: make(chan db.DBResponse, 1),
+		ReturnWg:   &rwg,
+	}
+
+	d := dbMsg.Send(r.dbChan)
+
+	if errors.Is(d.Err, db.ErrNilReadResponse) {
+		return "", nil
+	}
+
+	return string(d.Data), d.Err
+}
+
+type RollbackCheckAction struct {
+	r *BinmanRelease
+}
+
+func (r *BinmanRelease) AddRollbackCheckAction() Action {
+	return &RollbackCheckAction{
+		r,
+	}
+}
+
+// RollbackCheckAction holds a rolled back release at its rollback version
+func (action *RollbackCheckAction) execute() error {
+
+	version, err := action.r.getRollback()
+	if err != nil {
+		return err
+	}
+
+	if version == "" {
+		return nil
+	}
+
+	log.Debugf("%s is rolled back to %s, skipping", action.r.Repo, version)
+
+	action.r.Version = version
+
+	return &NoUpdateError{
+		RepoName: action.r.Repo,
+		Version:  version,
+	}
+}
@@ -0,0 +1,155 @@
+package binman
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// writeTestVersions stores synced versions of org1/repo1 on disk and in the db, and links the newest one
+func writeTestVersions(t *testing.T, releasePath, dbPath string, versions []string) string {
+
+	linkPath := filepath.Join(releasePath, "repo1")
+
+	testDb := db.GetDB(dbPath)
+	defer testDb.Close()
+
+	for _, v := range versions {
+		publishPath := filepath.Join(releasePath, "repos", "github.com", "org1", "repo1", v)
+		artifactPath := filepath.Join(publishPath, "repo1")
+		if err := os.MkdirAll(publishPath, 0755); err != nil {
+			t.Fatalf("Unable to create %s", publishPath)
+		}
+		if err := WriteStringtoFile(artifactPath, v); err != nil {
+			t.Fatalf("Unable to write %s", artifactPath)
+		}
+
+		data := map[string]any{"repo": "org1/repo1", "version": v, "publishPath": publishPath, "artifactPath": artifactPath, "linkPath": linkPath}
+		if err := db.WriteData(true, fmt.Sprintf("github.com/org1/repo1/%s/data", v), dataToBytes(data), testDb); err != nil {
+			t.Fatalf("Unable to write %s to db - %s", v, err)
+		}
+
+		if err := createLink(artifactPath, linkPath); err != nil {
+			t.Fatalf("Unable to link %s - %s", artifactPath, err)
+		}
+	}
+
+	return linkPath
+}
+
+func linkedVersion(t *testing.T, linkPath string) string {
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		t.Fatalf("Unable to read link %s", linkPath)
+	}
+	return filepath.Base(filepath.Dir(target))
+}
+
+func TestRollback(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testCleanConfig, "rollback")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	linkPath := writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3"})
+
+	var tests = []struct {
+		version  string
+		release  bool
+		expected string
+		err      bool
+	}{
+		{expected: "v0.0.2"},
+		{expected: "v0.0.1"},
+		{err: true, expected: "v0.0.1"},
+		{version: "v0.0.9", err: true, expected: "v0.0.1"},
+		{version: "v0.0.2", expected: "v0.0.2"},
+		{release: true, expected: "v0.0.3"},
+	}
+
+	for caseNum, test := range tests {
+		err := Rollback("org1/repo1", test.version, test.release, dbPath, testConfig)
+		if (err != nil) != test.err {
+			t.Fatalf("%d expected error %t got %v", caseNum, test.err, err)
+		}
+		if got := linkedVersion(t, linkPath); got != test.expected {
+			t.Fatalf("%d expected link to %s got %s", caseNum, test.expected, got)
+		}
+	}
+
+	if err := Rollback("org1/missing", "", false, dbPath, testConfig); !errors.Is(err, ErrReleaseNotFound) {
+		t.Fatalf("Expected release not found, got %v", err)
+	}
+}
+
+func TestRollbackCheckAction(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testCleanConfig, "rollbackcheck")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2"})
+
+	if err := Rollback("org1/repo1", "", false, dbPath, testConfig); err != nil {
+		t.Fatalf("Unable to roll back - %s", err)
+	}
+
+	var dwg sync.WaitGroup
+	dbOptions := db.DbConfig{Path: dbPath, Dwg: &dwg, DbChan: make(chan db.DbMsg)}
+	go db.RunDB(dbOptions)
+
+	rel := BinmanRelease{Repo: "org1/repo1", SourceIdentifier: "github.com", dbChan: dbOptions.DbChan, dwg: &dwg}
+
+	var noUpdate *NoUpdateError
+	if err := rel.AddRollbackCheckAction().execute(); !errors.As(err, &noUpdate) || rel.Version != "v0.0.1" {
+		t.Fatalf("Expected rolled back release to be held at v0.0.1, got %s %v", rel.Version, err)
+	}
+
+	other := BinmanRelease{Repo: "org1/repo2", SourceIdentifier: "github.com", dbChan: dbOptions.DbChan, dwg: &dwg}
+	if err := other.AddRollbackCheckAction().execute(); err != nil {
+		t.Fatalf("Expected release without a rollback to continue, got %v", err)
+	}
+
+	close(dbOptions.DbChan)
+	dwg.Wait()
+}
+
+func TestCleanKeepsRollbackVersion(t *testing.T) {
+
+	log.ConfigureLog(true, 2)
+
+	releasePath, testConfig := createTestDir(t, nil, testCleanConfig, "rollbackclean")
+	defer os.RemoveAll(releasePath)
+
+	dbPath := filepath.Join(releasePath, "binman.db")
+	writeTestVersions(t, releasePath, dbPath, []string{"v0.0.1", "v0.0.2", "v0.0.3"})
+
+	if err := Rollback("org1/repo1", "v0.0.1", false, dbPath, testConfig); err != nil {
+		t.Fatalf("Unable to roll back - %s", err)
+	}
+
+	if _, err := Clean(false, false, false, 1, dbPath, testConfig, ReleaseSelector{}); err != nil {
+		t.Fatalf("Unexpected clean error %s", err)
+	}
+
+	testDb := db.GetDB(dbPath)
+	defer testDb.Close()
+
+	if _, err := db.GetData("github.com/org1/repo1/v0.0.1/data", testDb); err != nil {
+		t.Fatalf("Expected rollback version to be kept")
+	}
+
+	if _, err := db.GetData("github.com/org1/repo1/v0.0.2/data", testDb); !errors.Is(err, db.ErrNilReadResponse) {
+		t.Fatalf("Expected v0.0.2 to be cleaned")
+	}
+}
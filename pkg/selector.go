@@ -0,0 +1,70 @@
+package binman
+
+import (
+	"slices"
+
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// ReleaseSelector limits a command to a subset of the configured releases.
+// Releases are named by repo (org/project) or by source and repo (gitlab.com/org/project)
+type ReleaseSelector struct {
+	Only   []string // only these releases
+	Skip   []string // never these releases
+	Labels []string // only releases with at least one of these labels
+}
+
+// IsEmpty returns true if the selector selects every release
+func (s ReleaseSelector) IsEmpty() bool {
+	return len(s.Only) == 0 && len(s.Skip) == 0 && len(s.Labels) == 0
+}
+
+// named returns true if name refers to the release
+func (r *BinmanRelease) named(name string) bool {
+	return name == r.Repo || name == r.SourceIdentifier+"/"+r.Repo
+}
+
+// matches returns true if the release is selected
+func (s ReleaseSelector) matches(r *BinmanRelease) bool {
+
+	if slices.ContainsFunc(s.Skip, r.named) {
+		return false
+	}
+
+	if len(s.Only) != 0 && !slices.ContainsFunc(s.Only, r.named) {
+		return false
+	}
+
+	if len(s.Labels) != 0 && !slices.ContainsFunc(s.Labels, func(l string) bool { return slices.Contains(r.Labels, l) }) {
+		return false
+	}
+
+	return true
+}
+
+// Select drops every release not matched by s. This must be done before CollectData
+func (config *BMConfig) Select(s ReleaseSelector) *BMConfig {
+
+	if s.IsEmpty() {
+		return config
+	}
+
+	var selected []BinmanRelease
+	for index := range config.Releases {
+		if s.matches(&config.Releases[index]) {
+			selected = append(selected, config.Releases[index])
+		}
+	}
+
+	// A typo in --only would otherwise silently sync nothing
+	for _, name := range s.Only {
+		if !slices.ContainsFunc(config.Releases, func(r BinmanRelease) bool { return r.named(name) }) {
+			log.Warnf("%s is not a configured release", name)
+		}
+	}
+
+	log.Debugf("Selected %d of %d releases", len(selected), len(config.Releases))
+
+	config.Releases = selected
+	return config
+}
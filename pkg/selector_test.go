@@ -0,0 +1,49 @@
+package binman
+
+import (
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+
+	releases := []BinmanRelease{
+		{Repo: "kubernetes/kubectl", SourceIdentifier: "github.com", Labels: []string{"k8s"}},
+		{Repo: "helm/helm", SourceIdentifier: "github.com", Labels: []string{"k8s", "ci"}},
+		{Repo: "anchore/grype", SourceIdentifier: "github.com", Labels: []string{"security"}},
+		{Repo: "gitlab-org/cli", SourceIdentifier: "gitlab.com"},
+	}
+
+	var tests = []struct {
+		name     string
+		sel      ReleaseSelector
+		expected []string
+	}{
+		{"empty", ReleaseSelector{}, []string{"kubernetes/kubectl", "helm/helm", "anchore/grype", "gitlab-org/cli"}},
+		{"only", ReleaseSelector{Only: []string{"helm/helm", "gitlab.com/gitlab-org/cli"}}, []string{"helm/helm", "gitlab-org/cli"}},
+		{"only wrong source", ReleaseSelector{Only: []string{"github.com/gitlab-org/cli"}}, nil},
+		{"skip", ReleaseSelector{Skip: []string{"anchore/grype", "gitlab-org/cli"}}, []string{"kubernetes/kubectl", "helm/helm"}},
+		{"label", ReleaseSelector{Labels: []string{"k8s"}}, []string{"kubernetes/kubectl", "helm/helm"}},
+		{"labels", ReleaseSelector{Labels: []string{"ci", "security"}}, []string{"helm/helm", "anchore/grype"}},
+		{"label and skip", ReleaseSelector{Labels: []string{"k8s"}, Skip: []string{"helm/helm"}}, []string{"kubernetes/kubectl"}},
+		{"label and only", ReleaseSelector{Labels: []string{"k8s"}, Only: []string{"anchore/grype", "helm/helm"}}, []string{"helm/helm"}},
+	}
+
+	for _, test := range tests {
+		c := &BMConfig{Releases: append([]BinmanRelease{}, releases...)}
+		c.Select(test.sel)
+
+		var got []string
+		for _, rel := range c.Releases {
+			got = append(got, rel.Repo)
+		}
+
+		if len(got) != len(test.expected) {
+			t.Fatalf("%s: expected %v got %v", test.name, test.expected, got)
+		}
+		for i := range got {
+			if got[i] != test.expected[i] {
+				t.Fatalf("%s: expected %v got %v", test.name, test.expected, got)
+			}
+		}
+	}
+}
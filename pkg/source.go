@@ -0,0 +1,204 @@
+package binman
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v50/github"
+	"github.com/rjbrown57/binman/pkg/downloader"
+	"github.com/rjbrown57/binman/pkg/gh"
+	"github.com/rjbrown57/binman/pkg/gitea"
+	"github.com/rjbrown57/binman/pkg/gl"
+	"github.com/rjbrown57/binman/pkg/httpcache"
+	"github.com/rjbrown57/binman/pkg/httpindex"
+	log "github.com/rjbrown57/binman/pkg/logging"
+	"github.com/rjbrown57/binman/pkg/oci"
+	"github.com/rjbrown57/binman/pkg/ratelimit"
+	"gitlab.com/gitlab-org/api/client-go"
+	"golang.org/x/oauth2"
+)
+
+// sourceClients holds one api client per source so rate limit state and concurrency caps are shared by every release using it
+var sourceClients = struct {
+	sync.Mutex
+	gh    map[string]*github.Client
+	gl    map[string]*gitlab.Client
+	gitea map[string]*gitea.Client
+	index map[string]*httpindex.Client
+	oci   map[string]*oci.Puller
+}{gh: make(map[string]*github.Client), gl: make(map[string]*gitlab.Client), gitea: make(map[string]*gitea.Client), index: make(map[string]*httpindex.Client), oci: make(map[string]*oci.Puller)}
+
+// gitlabJobTokenVar is the variable gitlab CI exposes job tokens in. Job tokens use a different header than personal tokens
+const gitlabJobTokenVar = "CI_JOB_TOKEN"
+
+// host returns the host of the source url. Credentials for a source are only ever sent to this host
+func (s *Source) host() string {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		log.Debugf("Unable to parse url %s for source %s - %s", s.URL, s.Name, err)
+		return ""
+	}
+	return u.Host
+}
+
+// registry returns the registry host for an oci source. The url host is used if set, otherwise the source name
+func (s *Source) registry() string {
+	if s.URL == "" {
+		return s.Name
+	}
+	return s.host()
+}
+
+// dlAuth returns the download credential for a source, or nil if downloads should be anonymous
+func (s *Source) dlAuth() *downloader.DlAuth {
+	return s.dlAuthFor(s.host())
+}
+
+// dlAuthFor returns the download credential for a source scoped to host
+func (s *Source) dlAuthFor(host string) *downloader.DlAuth {
+
+	token := s.Token()
+	if token == "" {
+		return nil
+	}
+
+	switch s.Apitype {
+	case "github":
+		return downloader.NewDlAuth(token, "Authorization", "Bearer", host)
+	case "gitlab":
+		if s.Tokenvar == gitlabJobTokenVar {
+			return downloader.NewDlAuth(token, "JOB-TOKEN", "", host)
+		}
+		return downloader.NewDlAuth(token, "PRIVATE-TOKEN", "", host)
+	case "gitea":
+		return downloader.NewDlAuth(token, "Authorization", "token", host)
+	case "http-index":
+		return downloader.NewDlAuth(token, "Authorization", "Bearer", host)
+	}
+
+	return nil
+}
+
+// clientKey identifies the api client for a source
+func (s *Source) clientKey(cache *apiCacheStore) string {
+	return fmt.Sprintf("%s|%d|%d|%s|%s", s.credentialKey(), s.Concurrency, s.MaxWait, s.transportKey(), cache.id())
+}
+
+// apiTransport returns the transport for api requests to the source. Requests are rate limited, and if a cache is supplied
+// conditional requests are sent for previously seen responses
+func (s *Source) apiTransport(cache *apiCacheStore) http.RoundTripper {
+	maxWait := ratelimit.DefaultMaxWait
+	if s.MaxWait > 0 {
+		maxWait = time.Duration(s.MaxWait) * time.Second
+	}
+
+	rt := ratelimit.NewTransport(s.transport(), s.Concurrency, maxWait)
+
+	if cache == nil {
+		return rt
+	}
+
+	return httpcache.NewTransport(rt, cache)
+}
+
+// ghClient returns the shared github client for the source
+func (s *Source) ghClient(cache *apiCacheStore) *github.Client {
+
+	key := s.clientKey(cache)
+
+	sourceClients.Lock()
+	defer sourceClients.Unlock()
+
+	if c, exists := sourceClients.gh[key]; exists {
+		return c
+	}
+
+	var c *github.Client
+
+	switch {
+	case s.isGitHubApp():
+		ts, err := s.appTokenSource()
+		if err != nil {
+			log.Warnf("Unable to authenticate as github app for source %s, using anonymous access - %s", s.Name, err)
+		}
+		c = gh.GetGHClientWithTransport(s.URL, ts, s.apiTransport(cache))
+	case s.Token() != "":
+		c = gh.GetGHClientWithTransport(s.URL, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: s.Token()}), s.apiTransport(cache))
+	default:
+		c = gh.GetGHClientWithTransport(s.URL, nil, s.apiTransport(cache))
+	}
+
+	sourceClients.gh[key] = c
+	return c
+}
+
+// glClient returns the shared gitlab client for the source
+func (s *Source) glClient(cache *apiCacheStore) *gitlab.Client {
+
+	key := s.clientKey(cache)
+
+	sourceClients.Lock()
+	defer sourceClients.Unlock()
+
+	if c, exists := sourceClients.gl[key]; exists {
+		return c
+	}
+
+	c := gl.GetGLClientWithTransport(s.URL, s.Token(), s.Tokenvar == gitlabJobTokenVar, s.apiTransport(cache))
+	sourceClients.gl[key] = c
+	return c
+}
+
+// giteaClient returns the shared gitea client for the source
+func (s *Source) giteaClient(cache *apiCacheStore) *gitea.Client {
+
+	key := s.clientKey(cache)
+
+	sourceClients.Lock()
+	defer sourceClients.Unlock()
+
+	if c, exists := sourceClients.gitea[key]; exists {
+		return c
+	}
+
+	c := gitea.GetGiteaClient(s.URL, s.Token(), s.apiTransport(cache))
+	sourceClients.gitea[key] = c
+	return c
+}
+
+// indexClient returns the shared http index client for the source
+func (s *Source) indexClient(cache *apiCacheStore) *httpindex.Client {
+
+	key := s.clientKey(cache)
+
+	sourceClients.Lock()
+	defer sourceClients.Unlock()
+
+	if c, exists := sourceClients.index[key]; exists {
+		return c
+	}
+
+	c := httpindex.GetIndexClient(s.Token(), s.apiTransport(cache))
+	sourceClients.index[key] = c
+	return c
+}
+
+// ociPuller returns the shared oci puller for the source and platform. Registry responses are never cached since blobs can be large
+func (s *Source) ociPuller(osName, arch string) *oci.Puller {
+
+	key := fmt.Sprintf("%s|%s/%s", s.clientKey(nil), osName, arch)
+
+	sourceClients.Lock()
+	defer sourceClients.Unlock()
+
+	if c, exists := sourceClients.oci[key]; exists {
+		return c
+	}
+
+	c := oci.NewPuller(osName, arch, s.Token(), s.apiTransport(nil))
+	sourceClients.oci[key] = c
+	return c
+}
@@ -0,0 +1,89 @@
+package binman
+
+import (
+	"testing"
+
+	"github.com/rjbrown57/binman/pkg/constants"
+)
+
+func TestSourceDlAuth(t *testing.T) {
+
+	t.Setenv("BINMAN_TEST_TOKEN", "secret")
+	t.Setenv("CI_JOB_TOKEN", "jobsecret")
+
+	var tests = []struct {
+		source         Source
+		expectedHeader string
+		expectedHost   string
+		expectedToken  string
+		expectNil      bool
+	}{
+		{
+			source:         Source{Name: "github.com", URL: constants.DefaultGHBaseURL, Apitype: "github", Tokenvar: "BINMAN_TEST_TOKEN"},
+			expectedHeader: "Authorization",
+			expectedHost:   "api.github.com",
+			expectedToken:  "secret",
+		},
+		{
+			source:         Source{Name: "gitlab.com", URL: constants.DefaultGLBaseURL, Apitype: "gitlab", Tokenvar: "BINMAN_TEST_TOKEN"},
+			expectedHeader: "Private-Token",
+			expectedHost:   "gitlab.com",
+			expectedToken:  "secret",
+		},
+		{
+			source:         Source{Name: "gitlab.com", URL: constants.DefaultGLBaseURL, Apitype: "gitlab", Tokenvar: "CI_JOB_TOKEN"},
+			expectedHeader: "Job-Token",
+			expectedHost:   "gitlab.com",
+			expectedToken:  "jobsecret",
+		},
+		{
+			source:         Source{Name: "codeberg.org", URL: "https://codeberg.org", Apitype: "gitea", Tokenvar: "BINMAN_TEST_TOKEN"},
+			expectedHeader: "Authorization",
+			expectedHost:   "codeberg.org",
+			expectedToken:  "secret",
+		},
+		{
+			source:    Source{Name: "github.com", URL: constants.DefaultGHBaseURL, Apitype: "github", Tokenvar: "none"},
+			expectNil: true,
+		},
+		{
+			source:    Source{Name: "binman", URL: "http://binman.local", Apitype: "binman", Tokenvar: "BINMAN_TEST_TOKEN"},
+			expectNil: true,
+		},
+	}
+
+	for _, test := range tests {
+		auth := test.source.dlAuth()
+
+		if test.expectNil {
+			if auth != nil {
+				t.Fatalf("Expected no auth for %s got %+v", test.source.Name, auth)
+			}
+			continue
+		}
+
+		if auth == nil || auth.Header != test.expectedHeader || auth.Host != test.expectedHost || auth.Token != test.expectedToken {
+			t.Fatalf("Unexpected auth for %s(%s) got %+v", test.source.Name, test.source.Tokenvar, auth)
+		}
+	}
+}
+
+func TestSourceSharedClients(t *testing.T) {
+	githubSource := Source{Name: "github.com", URL: constants.DefaultGHBaseURL, Apitype: "github", Tokenvar: "none"}
+	gitlabSource := Source{Name: "gitlab.com", URL: constants.DefaultGLBaseURL, Apitype: "gitlab"}
+
+	if githubSource.ghClient(nil) != githubSource.ghClient(nil) {
+		t.Fatalf("Expected github client to be shared for a source")
+	}
+
+	if gitlabSource.glClient(nil) != gitlabSource.glClient(nil) {
+		t.Fatalf("Expected gitlab client to be shared for a source")
+	}
+
+	// A source with different limits gets its own client
+	limited := githubSource
+	limited.Concurrency = 1
+	if limited.ghClient(nil) == githubSource.ghClient(nil) {
+		t.Fatalf("Expected distinct clients for sources with different settings")
+	}
+}
@@ -2,15 +2,20 @@ package binman
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/mattn/go-isatty"
+	"github.com/rjbrown57/binman/pkg/downloader"
 	log "github.com/rjbrown57/binman/pkg/logging"
 	"github.com/theckman/yacspin"
 )
 
-func getSpinner(debug bool, spinChan chan (string), swg *sync.WaitGroup) {
+// getSpinner owns terminal output for an operation. Spinner messages are read from spinChan and download progress from progChan.
+// When stdout is a tty and we are not debug/json logging, progress is drawn as a multi-line view, otherwise it is logged periodically
+func getSpinner(debug bool, spinChan chan (string), progChan chan downloader.Progress, swg *sync.WaitGroup) {
 
 	cfg := yacspin.Config{
 		Frequency:       100 * time.Millisecond,
@@ -31,17 +36,39 @@ func getSpinner(debug bool, spinChan chan (string), swg *sync.WaitGroup) {
 		spinner.Start()
 	}
 
-	for msg := range spinChan {
-
-		if !strings.Contains(msg, "spinstop") {
-			spinner.Message(msg)
-		} else {
-			spinner.StopMessage(strings.Trim(msg, "spinstop"))
+	tty := !debug && !log.IsJSON() && isatty.IsTerminal(os.Stdout.Fd())
+	view := newProgressView(os.Stdout, tty, spinner)
+
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	for spinChan != nil {
+		select {
+		case msg, ok := <-spinChan:
+			if !ok {
+				spinChan = nil
+				continue
+			}
+
+			if !strings.Contains(msg, "spinstop") {
+				spinner.Message(msg)
+			} else {
+				spinner.StopMessage(strings.Trim(msg, "spinstop"))
+			}
+			swg.Done()
+			time.Sleep(time.Millisecond * 500)
+		case p, ok := <-progChan:
+			if !ok {
+				progChan = nil
+				continue
+			}
+			view.update(p)
+		case <-ticker.C:
+			view.tick()
 		}
-		swg.Done()
-		time.Sleep(time.Millisecond * 500)
-
 	}
+
+	view.finish()
 	spinner.Suffix("")
 	spinner.Stop()
 	swg.Done()
@@ -66,6 +93,7 @@ func SetStopMessage(out map[string][]BinmanMsg) string {
 	syncedLength := len(out["Synced"])
 	noUpdateLength := len(out["Up to Date"])
 	errorLength := len(out["Error"])
+	deferredLength := len(out["Deferred"])
 
 	if noUpdateLength > 0 {
 		stopMsg = stopMsg + fmt.Sprintf("✓ %d repos are up to date ", noUpdateLength)
@@ -75,6 +103,10 @@ func SetStopMessage(out map[string][]BinmanMsg) string {
 		stopMsg = stopMsg + fmt.Sprintf("Δ %d repos %s pulled new versions ", syncedLength, repoList(out["Synced"]))
 	}
 
+	if deferredLength > 0 {
+		stopMsg = stopMsg + fmt.Sprintf("⏸ %d repos %s deferred by api rate limits ", deferredLength, repoList(out["Deferred"]))
+	}
+
 	if errorLength > 0 {
 		stopMsg = stopMsg + fmt.Sprintf("✕ %d repos errored %s during execution ", errorLength, repoList(out["Error"]))
 	}
@@ -0,0 +1,151 @@
+package binman
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjbrown57/binman/pkg/downloader"
+	log "github.com/rjbrown57/binman/pkg/logging"
+)
+
+// sourceTransports holds one transport per source transport configuration so connections are reused by api and download requests
+var sourceTransports = struct {
+	sync.Mutex
+	transports map[string]http.RoundTripper
+}{transports: make(map[string]http.RoundTripper)}
+
+// hasTransportOptions reports whether a source needs its own transport
+func (s *Source) hasTransportOptions() bool {
+	return s.Proxy != "" || s.CAFile != "" || s.ClientCert != "" || s.ClientKey != "" || s.InsecureSkipVerify || s.Timeout > 0
+}
+
+// transportKey identifies the transport configuration of a source
+func (s *Source) transportKey() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%t|%d", s.Proxy, s.CAFile, s.ClientCert, s.ClientKey, s.InsecureSkipVerify, s.Timeout)
+}
+
+// newTransport builds the transport for the transport options of a source
+func (s *Source) newTransport() (*http.Transport, error) {
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if s.Proxy != "" {
+		proxy, err := url.Parse(s.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %s", s.Proxy)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	if s.Timeout > 0 {
+		// The timeout covers connecting and waiting for a response. Large downloads are never cut off while the body is read
+		timeout := time.Duration(s.Timeout) * time.Second
+		t.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		t.TLSHandshakeTimeout = timeout
+		t.ResponseHeaderTimeout = timeout
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(expandHome(s.CAFile)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read cafile %s - %w", s.CAFile, err)
+		}
+
+		// Additional CAs are trusted alongside the system pool
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			log.Debugf("Unable to load system cert pool, only %s will be trusted - %s", s.CAFile, err)
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in cafile %s", s.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case s.ClientCert != "" && s.ClientKey != "":
+		cert, err := tls.LoadX509KeyPair(expandHome(s.ClientCert), expandHome(s.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s - %w", s.ClientCert, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case s.ClientCert != "" || s.ClientKey != "":
+		return nil, errors.New("clientcert and clientkey must be set together")
+	}
+
+	if s.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true // #nosec G402 -- explicitly requested by the user for this source
+	}
+
+	t.TLSClientConfig = tlsConfig
+
+	return t, nil
+}
+
+// transport returns the shared transport for the source, or nil if the source has no transport options and the default should be used
+func (s *Source) transport() http.RoundTripper {
+
+	if s == nil || !s.hasTransportOptions() {
+		return nil
+	}
+
+	key := s.transportKey()
+
+	sourceTransports.Lock()
+	defer sourceTransports.Unlock()
+
+	if t, exists := sourceTransports.transports[key]; exists {
+		return t
+	}
+
+	t, err := s.newTransport()
+	if err != nil {
+		// Options are validated when the config is loaded, so this only happens if files change while we run
+		log.Warnf("Unable to configure transport for source %s, using the default transport - %s", s.Name, err)
+		return nil
+	}
+
+	sourceTransports.transports[key] = t
+	return t
+}
+
+// downloadHost returns the host of the download url of a source, or an empty string if downloads come from the api host
+func (s *Source) downloadHost() string {
+	if s.DownloadURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(s.DownloadURL)
+	if err != nil {
+		log.Debugf("Unable to parse downloadurl %s for source %s - %s", s.DownloadURL, s.Name, err)
+		return ""
+	}
+	return u.Host
+}
+
+// downloadAuth returns the download credential of the source for dlUrl. Assets on the download host get credentials scoped to
+// that host, anything else gets credentials scoped to the api host
+func (s *Source) downloadAuth(dlUrl string) *downloader.DlAuth {
+
+	if host := s.downloadHost(); host != "" {
+		if u, err := url.Parse(dlUrl); err == nil && strings.EqualFold(u.Host, host) {
+			return s.dlAuthFor(host)
+		}
+	}
+
+	return s.dlAuth()
+}
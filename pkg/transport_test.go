@@ -0,0 +1,110 @@
+package binman
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rjbrown57/binman/pkg/downloader"
+)
+
+// writeServerCA writes the certificate of a tls test server to a pem file
+func writeServerCA(t *testing.T, srv *httptest.Server) string {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemBytes, 0600); err != nil {
+		t.Fatalf("Unable to write ca file - %s", err)
+	}
+	return caFile
+}
+
+func TestSourceTransport(t *testing.T) {
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "binman")
+	}))
+	defer srv.Close()
+
+	caFile := writeServerCA(t, srv)
+
+	if (&Source{Name: "default"}).transport() != nil {
+		t.Fatalf("Expected the default transport for a source without transport options")
+	}
+
+	var tests = []struct {
+		source Source
+		err    bool
+	}{
+		{source: Source{Name: "ca", CAFile: caFile}},
+		{source: Source{Name: "insecure", InsecureSkipVerify: true}},
+		{source: Source{Name: "untrusted", Timeout: 5}, err: true},
+	}
+
+	for _, test := range tests {
+		rt := test.source.transport()
+		if rt == nil {
+			t.Fatalf("%s expected a source transport", test.source.Name)
+		}
+
+		dir := t.TempDir()
+		dlMsg := downloader.DlMsg{Url: srv.URL, Filepath: filepath.Join(dir, "asset"), Transport: rt}
+
+		err := dlMsg.DownloadFile()
+		if (err != nil) != test.err {
+			t.Fatalf("%s expected error %t got %v", test.source.Name, test.err, err)
+		}
+	}
+}
+
+func TestSourceTransportInvalid(t *testing.T) {
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("Unable to write test file - %s", err)
+	}
+
+	var invalid = []Source{
+		{Name: "missing-ca", CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{Name: "empty-ca", CAFile: empty},
+		{Name: "cert-only", ClientCert: empty},
+		{Name: "bad-cert", ClientCert: empty, ClientKey: empty},
+		{Name: "bad-proxy", Proxy: "not a url"},
+	}
+
+	for _, s := range invalid {
+		if _, err := s.newTransport(); err == nil {
+			t.Fatalf("%s expected an error", s.Name)
+		}
+	}
+
+	s := Source{Name: "proxy", Proxy: "http://proxy.example.com:3128", Timeout: 10}
+	rt, err := s.newTransport()
+	if err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://ghe.example.com/api/v3/", nil)
+	proxy, err := rt.Proxy(req)
+	if err != nil || proxy.Host != "proxy.example.com:3128" {
+		t.Fatalf("Expected configured proxy, got %v %v", proxy, err)
+	}
+}
+
+func TestSourceDownloadAuth(t *testing.T) {
+
+	t.Setenv("BINMAN_TEST_TOKEN", "secret")
+
+	s := Source{Name: "ghe", URL: "https://ghe.example.com/api/v3/", DownloadURL: "https://objects.ghe.example.com/", Apitype: "github", Tokenvar: "BINMAN_TEST_TOKEN"}
+
+	if auth := s.downloadAuth("https://objects.ghe.example.com/org/tool/v1.0.0/tool.tar.gz"); auth == nil || auth.Host != "objects.ghe.example.com" {
+		t.Fatalf("Expected credentials scoped to the download host, got %+v", auth)
+	}
+
+	if auth := s.downloadAuth("https://ghe.example.com/api/v3/repos/org/tool/releases/assets/1"); auth == nil || auth.Host != "ghe.example.com" {
+		t.Fatalf("Expected credentials scoped to the api host, got %+v", auth)
+	}
+}
@@ -29,6 +29,8 @@ type BinmanConfig struct {
 	UpxConfig      UpxConfig `yaml:"upx,omitempty"`          // Allow upx to shrink extracted
 	Sources        []Source  `yaml:"sources,omitempty"`      // Sources to query. By default gitlab and github
 	Watch          Watch     `yaml:"watch,omitempty"`        // Watch config object
+	Rewrites       []Rewrite `yaml:"rewrites,omitempty"`     // Rules applied to download urls. The first matching rule is used
+	Retention      Retention `yaml:"retention,omitempty"`    // Versions binman clean keeps
 
 	SourceMap map[string]*Source `yaml:"-"` // map of names to struct pointers for sources
 }
@@ -44,16 +46,37 @@ type Watch struct {
 }
 
 type Source struct {
-	Name     string `yaml:"name"`
-	Tokenvar string `yaml:"tokenvar,omitempty"`
-	URL      string `yaml:"url"`
-	Apitype  string `yaml:"apitype"`
+	Name         string   `yaml:"name"`
+	Tokenvar     string   `yaml:"tokenvar,omitempty"`     // Environment variable containing a token
+	Tokenfile    string   `yaml:"tokenfile,omitempty"`    // File containing a token
+	Tokencommand []string `yaml:"tokencommand,omitempty"` // Command that prints a token to stdout. e.g ["gh", "auth", "token"]
+	Netrc        bool     `yaml:"netrc,omitempty"`        // Look up a token in ~/.netrc by source host
+	URL          string   `yaml:"url"`
+	Apitype      string   `yaml:"apitype"`
+	Concurrency  int      `yaml:"concurrency,omitempty"` // Maximum concurrent api requests to this source. Default is 4
+	MaxWait      int      `yaml:"maxwait,omitempty"`     // Maximum seconds to wait for an exhausted rate limit to reset before deferring a release. Default is 60
+	Graphql      bool     `yaml:"graphql,omitempty"`     // Look up github releases in batches with the graphql api
+	DownloadURL  string   `yaml:"downloadurl,omitempty"` // Base url assets are downloaded from when it differs from the api url. e.g a separate ghe asset host
+
+	// Transport options used for api queries and downloads from the source
+	Proxy              string `yaml:"proxy,omitempty"`              // Proxy url. By default HTTPS_PROXY/HTTP_PROXY/NO_PROXY are used
+	CAFile             string `yaml:"cafile,omitempty"`             // PEM bundle of CAs to trust in addition to the system pool
+	ClientCert         string `yaml:"clientcert,omitempty"`         // PEM client certificate for mutual TLS
+	ClientKey          string `yaml:"clientkey,omitempty"`          // PEM key for clientcert
+	InsecureSkipVerify bool   `yaml:"insecureskipverify,omitempty"` // Skip TLS certificate verification. Only use for testing
+	Timeout            int    `yaml:"timeout,omitempty"`            // Seconds to wait to connect and for a response to start
+
+	// Github App authentication. Installation tokens are minted and refreshed automatically
+	AppID          int64  `yaml:"appid,omitempty"`
+	InstallationID int64  `yaml:"installationid,omitempty"`
+	PrivateKeyFile string `yaml:"privatekeyfile,omitempty"`
 }
 
 // BinmanDefaults contains default config options. If a value is unset in releases array these will be used.
 // This should just be collapsed into BinmanConfig and this struct should be removed
 type BinmanDefaults struct {
-	Os     string `yaml:"os,omitempty"`     //OS to look for
-	Arch   string `yaml:"arch,omitempty"`   //architecture to look for
-	Source string `yaml:"source,omitempty"` //Set to binman to override all
+	Os      string   `yaml:"os,omitempty"`      //OS to look for
+	Arch    string   `yaml:"arch,omitempty"`    //architecture to look for
+	Source  string   `yaml:"source,omitempty"`  //Set to binman to override all
+	Sources []string `yaml:"sources,omitempty"` //Sources to try in priority order for releases that do not set a source
 }
@@ -0,0 +1,181 @@
+package binman
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	db "github.com/rjbrown57/binman/pkg/db"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Version schemes control how the stored versions of a release are ordered
+const (
+	VersionSemver  = "semver"  // v1.2.3. The default
+	VersionCalver  = "calver"  // date based versions, e.g 2024.01.15, release-2024.01, nightly-20240115
+	VersionLexical = "lexical" // plain string order
+	VersionCreated = "created" // the createdAt stored in the db when the version was synced
+)
+
+var versionSchemes = []string{VersionSemver, VersionCalver, VersionLexical, VersionCreated}
+
+var calverRegex = regexp.MustCompile(`\d+`)
+
+// validVersionScheme returns true if scheme is known. An empty scheme is semver
+func validVersionScheme(scheme string) bool {
+	return scheme == "" || slices.Contains(versionSchemes, scheme)
+}
+
+// sortVersions orders versions oldest first using scheme. created returns the stored creation time of a version, it is only
+// called by the created scheme
+func sortVersions(scheme string, versions []string, created func(version string) time.Time) ([]string, error) {
+
+	switch scheme {
+	case "", VersionSemver:
+		return sortSemvers(versions)
+	case VersionCalver:
+		return sortCalvers(versions)
+	case VersionLexical:
+		sorted := slices.Clone(versions)
+		sort.Strings(sorted)
+		return sorted, nil
+	case VersionCreated:
+		sorted := slices.Clone(versions)
+		times := make(map[string]time.Time, len(sorted))
+		for _, v := range sorted {
+			times[v] = created(v)
+		}
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if times[sorted[i]].Equal(times[sorted[j]]) {
+				return sorted[i] < sorted[j]
+			}
+			return times[sorted[i]].Before(times[sorted[j]])
+		})
+		return sorted, nil
+	default:
+		return nil, fmt.Errorf("unknown versionscheme %s", scheme)
+	}
+}
+
+// sortCalvers orders versions by the numbers they contain, so 2024.9 comes before 2024.10
+func sortCalvers(versions []string) ([]string, error) {
+
+	parts := make(map[string][]int64, len(versions))
+
+	for _, v := range versions {
+		matches := calverRegex.FindAllString(v, -1)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s is not a calver version", v)
+		}
+		for _, m := range matches {
+			n, err := strconv.ParseInt(m, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a calver version - %w", v, err)
+			}
+			parts[v] = append(parts[v], n)
+		}
+	}
+
+	sorted := slices.Clone(versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := slices.Compare(parts[sorted[i]], parts[sorted[j]]); c != 0 {
+			return c < 0
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted, nil
+}
+
+// sortVersions orders versions of the release oldest first using its versionscheme
+func (r *BinmanRelease) sortVersions(versions []string) ([]string, error) {
+	return sortVersions(r.VersionScheme, versions, r.storedCreated)
+}
+
+// schemeOrdered returns true when the tags a source lists must be ordered by the release versionscheme. Sources order semver tags themselves
+func (r *BinmanRelease) schemeOrdered() bool {
+	return r.VersionScheme != "" && r.VersionScheme != VersionSemver
+}
+
+// latestTag returns the newest of the tags a source lists for the release using its versionscheme. created returns the time the
+// source published a tag, it is nil for sources that do not provide one. Tags that are not versions of a calver release are ignored
+func (r *BinmanRelease) latestTag(tags []string, created func(version string) time.Time) (string, error) {
+
+	// Tags that have not been synced yet have no stored createdAt, so the db can't be used to order upstream tags
+	if r.VersionScheme == VersionCreated && created == nil {
+		return "", fmt.Errorf("%s - versionscheme %s needs the publish time of each tag, which %s sources do not provide", r.Repo, VersionCreated, r.source.Apitype)
+	}
+
+	if r.VersionScheme == VersionCalver {
+		tags = slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return !calverRegex.MatchString(t) })
+	}
+
+	if len(tags) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoVersionsFound, r.Repo)
+	}
+
+	sorted, err := sortVersions(r.VersionScheme, tags, created)
+	if err != nil {
+		return "", err
+	}
+
+	return sorted[len(sorted)-1], nil
+}
+
+// storedCreated returns the createdAt stored in the db for version. Versions added by a scan have no createdAt and return the zero time
+func (r *BinmanRelease) storedCreated(version string) time.Time {
+
+	if r.dbChan == nil || r.dwg == nil {
+		return time.Time{}
+	}
+
+	r.dwg.Add(1)
+
+	var rwg sync.WaitGroup
+
+	dbMsg := db.DbMsg{
+		Operation:  "read",
+		Key:        fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, version),
+		ReturnChan: make(chan db.DBResponse, 1),
+		ReturnWg:   &rwg,
+	}
+
+	d := dbMsg.Send(r.dbChan)
+	if d.Err != nil {
+		return time.Time{}
+	}
+
+	data, err := decodeData(d.Data)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return dataCreated(data)
+}
+
+// dataCreated returns the createdAt of version data read from the db
+func dataCreated(data map[string]any) time.Time {
+	if createdAt, ok := data["createdAt"].(int64); ok && createdAt != 0 {
+		return time.Unix(createdAt, 0)
+	}
+	return time.Time{}
+}
+
+// createdIn returns a lookup of the createdAt stored in bdb for each version of the release
+func (r *BinmanRelease) createdIn(bdb *bolt.DB) func(version string) time.Time {
+	return func(version string) time.Time {
+		byteData, err := db.GetData(fmt.Sprintf("%s/%s/%s/data", r.SourceIdentifier, r.Repo, version), bdb)
+		if err != nil {
+			return time.Time{}
+		}
+		data, err := decodeData(byteData)
+		if err != nil {
+			return time.Time{}
+		}
+		return dataCreated(data)
+	}
+}
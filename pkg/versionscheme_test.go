@@ -0,0 +1,118 @@
+package binman
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortVersions(t *testing.T) {
+
+	created := map[string]time.Time{
+		"nightly":  time.Unix(300, 0),
+		"stable":   time.Unix(100, 0),
+		"snapshot": time.Unix(200, 0),
+	}
+
+	var tests = []struct {
+		scheme   string
+		versions []string
+		expected []string
+		err      bool
+	}{
+		{scheme: "", versions: []string{"v1.10.0", "v1.2.0", "v1.9.1"}, expected: []string{"v1.2.0", "v1.9.1", "v1.10.0"}},
+		{scheme: VersionSemver, versions: []string{"nightly", "v1.0.0"}, err: true},
+		{scheme: VersionCalver, versions: []string{"2024.10", "2024.9", "2023.12.31"}, expected: []string{"2023.12.31", "2024.9", "2024.10"}},
+		{scheme: VersionCalver, versions: []string{"release-2024.01", "release-2023.11", "release-2024.01.1"}, expected: []string{"release-2023.11", "release-2024.01", "release-2024.01.1"}},
+		{scheme: VersionCalver, versions: []string{"2024.01", "nightly"}, err: true},
+		{scheme: VersionLexical, versions: []string{"b", "nightly", "a"}, expected: []string{"a", "b", "nightly"}},
+		{scheme: VersionCreated, versions: []string{"nightly", "stable", "snapshot"}, expected: []string{"stable", "snapshot", "nightly"}},
+		{scheme: "dates", versions: []string{"a"}, err: true},
+	}
+
+	for _, test := range tests {
+		got, err := sortVersions(test.scheme, test.versions, func(v string) time.Time { return created[v] })
+		if (err != nil) != test.err {
+			t.Fatalf("%s %v: expected error %t got %v", test.scheme, test.versions, test.err, err)
+		}
+		if !test.err && !slices.Equal(got, test.expected) {
+			t.Fatalf("%s: expected %v got %v", test.scheme, test.expected, got)
+		}
+	}
+}
+
+func TestValidVersionScheme(t *testing.T) {
+	for _, scheme := range []string{"", VersionSemver, VersionCalver, VersionLexical, VersionCreated} {
+		if !validVersionScheme(scheme) {
+			t.Fatalf("Expected %q to be valid", scheme)
+		}
+	}
+	if validVersionScheme("dates") {
+		t.Fatalf("Expected dates to be invalid")
+	}
+}
+
+func TestLatestTagFileSource(t *testing.T) {
+
+	root := t.TempDir()
+
+	// Date tags are not semver, so they are only found with a versionscheme
+	for _, version := range []string{"release-2024.9", "release-2024.10", "latest"} {
+		dir := filepath.Join(root, "org", "tool", version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Unable to create %s", dir)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "tool_linux_amd64"), []byte(version), 0644); err != nil {
+			t.Fatalf("Unable to write asset")
+		}
+	}
+
+	usb := &Source{Name: "usb", Apitype: "file", URL: root}
+
+	rel := BinmanRelease{Repo: "org/tool", QueryType: "release", source: usb}
+	if err := rel.AddGetFileReleaseAction().execute(); err == nil {
+		t.Fatalf("Expected no semver versions to be found got %s", rel.Version)
+	}
+
+	rel = BinmanRelease{Repo: "org/tool", QueryType: "release", source: usb, VersionScheme: VersionCalver}
+	if err := rel.AddGetFileReleaseAction().execute(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if rel.Version != "release-2024.10" {
+		t.Fatalf("Expected release-2024.10 got %s", rel.Version)
+	}
+
+	// created orders by the modification time of each version directory. latest is newer than anything synced
+	now := time.Now()
+	for version, age := range map[string]time.Duration{"release-2024.9": 48 * time.Hour, "release-2024.10": 24 * time.Hour, "latest": 0} {
+		if err := os.Chtimes(filepath.Join(root, "org", "tool", version), now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Unable to age %s", version)
+		}
+	}
+
+	rel = BinmanRelease{Repo: "org/tool", QueryType: "release", source: usb, VersionScheme: VersionCreated}
+	if err := rel.AddGetFileReleaseAction().execute(); err != nil {
+		t.Fatalf("Unexpected error %s", err)
+	}
+	if rel.Version != "latest" {
+		t.Fatalf("Expected latest got %s", rel.Version)
+	}
+}
+
+func TestLatestTagCreated(t *testing.T) {
+
+	rel := BinmanRelease{Repo: "org/tool", VersionScheme: VersionCreated, source: &Source{Name: "registry", Apitype: "oci"}}
+
+	// v2.0.0 has not been synced, it must still be picked when the source says it is newer
+	published := map[string]time.Time{"v1.0.0": time.Unix(100, 0), "v2.0.0": time.Unix(200, 0)}
+	if tag, err := rel.latestTag([]string{"v1.0.0", "v2.0.0"}, func(v string) time.Time { return published[v] }); err != nil || tag != "v2.0.0" {
+		t.Fatalf("Expected v2.0.0 got %s %v", tag, err)
+	}
+
+	// Sources without a publish time can't order tags by created
+	if tag, err := rel.latestTag([]string{"v1.0.0", "v2.0.0"}, nil); err == nil {
+		t.Fatalf("Expected created to be rejected without publish times got %s", tag)
+	}
+}
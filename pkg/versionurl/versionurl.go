@@ -0,0 +1,174 @@
+package versionurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBody is the largest version document read. Version documents are small, anything larger is not what we are looking for
+const maxBody = 1 << 20
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// GetVersion fetches url and extracts a version from it. See Extract
+func GetVersion(ctx context.Context, url string, path string, rx string) (string, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return "", err
+	}
+
+	v, err := Extract(body, path, rx)
+	if err != nil {
+		return "", fmt.Errorf("unable to find version in %s - %w", url, err)
+	}
+
+	return v, nil
+}
+
+// Extract returns the version found in body. If path is set body is decoded as json and path is evaluated against it.
+// If rx is set it is matched against the result, the first capture group is used if present otherwise the whole match.
+// With neither set the first line of body is returned
+func Extract(body []byte, path string, rx string) (string, error) {
+
+	v := strings.TrimSpace(string(body))
+
+	if path != "" {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return "", fmt.Errorf("unable to decode json - %w", err)
+		}
+
+		result, err := JSONPath(doc, path)
+		if err != nil {
+			return "", err
+		}
+
+		switch r := result.(type) {
+		case string:
+			v = r
+		case float64, bool:
+			v = fmt.Sprint(r)
+		default:
+			return "", fmt.Errorf("%s does not point to a string", path)
+		}
+	}
+
+	if rx != "" {
+		re, err := regexp.Compile(rx)
+		if err != nil {
+			return "", err
+		}
+
+		m := re.FindStringSubmatch(v)
+		switch {
+		case m == nil:
+			return "", fmt.Errorf("%s did not match", rx)
+		case len(m) > 1:
+			v = m[1]
+		default:
+			v = m[0]
+		}
+	} else if line, _, found := strings.Cut(v, "\n"); found {
+		v = line
+	}
+
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return "", fmt.Errorf("version is empty")
+	}
+
+	return v, nil
+}
+
+// JSONPath evaluates a simple jsonpath against a decoded json document. Keys with dot or bracket notation and
+// array indexes are supported. Negative indexes count from the end. e.g $.versions[0].name, $['current'].tag or releases[-1]
+func JSONPath(doc any, path string) (any, error) {
+
+	p := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	cur := doc
+
+	for p != "" {
+		var key string
+		var index *int
+
+		switch {
+		case strings.HasPrefix(p, "."):
+			p = p[1:]
+			end := strings.IndexAny(p, ".[")
+			if end == -1 {
+				end = len(p)
+			}
+			key, p = p[:end], p[end:]
+		case strings.HasPrefix(p, "['"), strings.HasPrefix(p, `["`):
+			quote := p[1:2]
+			end := strings.Index(p[2:], quote+"]")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated key in %s", path)
+			}
+			key, p = p[2:2+end], p[2+end+2:]
+		case strings.HasPrefix(p, "["):
+			end := strings.Index(p, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated index in %s", path)
+			}
+			i, err := strconv.Atoi(p[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %s in %s", p[1:end], path)
+			}
+			index, p = &i, p[end+1:]
+		default:
+			// Allow the leading dot to be omitted. e.g tag_name
+			p = "." + p
+			continue
+		}
+
+		if index != nil {
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array at index %d", path, *index)
+			}
+			i := *index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("index %d out of range in %s", *index, path)
+			}
+			cur = arr[i]
+			continue
+		}
+
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object at key %s", path, key)
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("key %s not found in %s", key, path)
+		}
+	}
+
+	return cur, nil
+}
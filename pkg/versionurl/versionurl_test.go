@@ -0,0 +1,77 @@
+package versionurl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testDoc = `{"current_version": "1.7.2", "latest": {"tag_name": "v2.0.0"}, "versions": [{"name": "go1.22.1"}, {"name": "go1.21.8"}], "build": 42}`
+
+func TestExtract(t *testing.T) {
+
+	var tests = []struct {
+		caseName string
+		body     string
+		path     string
+		rx       string
+		expected string
+		err      bool
+	}{
+		{"plain", "v1.29.2\n", "", "", "v1.29.2", false},
+		{"firstline", "v1.29.2\nsomething else", "", "", "v1.29.2", false},
+		{"regex", "Latest release: 3.4.5 (stable)", "", `(\d+\.\d+\.\d+)`, "3.4.5", false},
+		{"regexnogroup", "tool-v0.9.1.tar.gz", "", `v\d+\.\d+\.\d+`, "v0.9.1", false},
+		{"jsonkey", testDoc, "current_version", "", "1.7.2", false},
+		{"jsonnested", testDoc, "$.latest.tag_name", "", "v2.0.0", false},
+		{"jsonbracket", testDoc, "$['latest']['tag_name']", "", "v2.0.0", false},
+		{"jsonindex", testDoc, "$.versions[0].name", `go(.*)`, "1.22.1", false},
+		{"jsonnegative", testDoc, "$.versions[-1].name", "", "go1.21.8", false},
+		{"jsonnumber", testDoc, "$.build", "", "42", false},
+		{"jsonmissing", testDoc, "$.nope", "", "", true},
+		{"jsonoutofrange", testDoc, "$.versions[2].name", "", "", true},
+		{"jsonobject", testDoc, "$.latest", "", "", true},
+		{"notjson", "v1.0.0", "$.version", "", "", true},
+		{"nomatch", "v1.0.0", "", `\d+-\d+`, "", true},
+		{"empty", "  \n", "", "", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := Extract([]byte(test.body), test.path, test.rx)
+		if (err != nil) != test.err {
+			t.Fatalf("%s: unexpected error state %v", test.caseName, err)
+		}
+		if got != test.expected {
+			t.Fatalf("%s: expected %s got %s", test.caseName, test.expected, got)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stable.txt":
+			fmt.Fprint(w, "v1.29.2")
+		case "/latest.json":
+			fmt.Fprint(w, testDoc)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	if v, err := GetVersion(context.Background(), srv.URL+"/stable.txt", "", ""); err != nil || v != "v1.29.2" {
+		t.Fatalf("Expected v1.29.2 got %s, %v", v, err)
+	}
+
+	if v, err := GetVersion(context.Background(), srv.URL+"/latest.json", "$.latest.tag_name", ""); err != nil || v != "v2.0.0" {
+		t.Fatalf("Expected v2.0.0 got %s, %v", v, err)
+	}
+
+	if _, err := GetVersion(context.Background(), srv.URL+"/missing", "", ""); err == nil {
+		t.Fatalf("Expected error for missing document")
+	}
+}
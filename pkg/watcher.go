@@ -18,6 +18,7 @@ var (
 	ErrNotFound   = errors.New("Release not found")
 	ErrBadRequest = errors.New("Bad Request")
 	ErrUnknown    = errors.New("Unknown Failure")
+	ErrServer     = errors.New("Server Error")
 )
 
 const (
@@ -37,6 +38,11 @@ type BinmanQuery struct {
 }
 
 func (q *BinmanQuery) SendQuery(bmurl string) (BinmanQueryResponse, error) {
+	return q.SendQueryWithTransport(bmurl, nil)
+}
+
+// SendQueryWithTransport sends the query through rt. If rt is nil the default transport is used
+func (q *BinmanQuery) SendQueryWithTransport(bmurl string, rt http.RoundTripper) (BinmanQueryResponse, error) {
 
 	resp := BinmanQueryResponse{}
 
@@ -45,7 +51,7 @@ func (q *BinmanQuery) SendQuery(bmurl string) (BinmanQueryResponse, error) {
 		return resp, err
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: rt}
 
 	req, err := http.NewRequest("POST", fmt.Sprintf("%s/%s", bmurl, v1QueryEndpoint), bytes.NewReader(j))
 	if err != nil {
@@ -66,6 +72,9 @@ func (q *BinmanQuery) SendQuery(bmurl string) (BinmanQueryResponse, error) {
 	case http.StatusNotFound:
 		return resp, ErrNotFound
 	default:
+		if r.StatusCode >= http.StatusInternalServerError {
+			return resp, ErrServer
+		}
 		return resp, ErrUnknown
 	}
 